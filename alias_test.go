@@ -0,0 +1,27 @@
+//go:build !tinygo && !purego
+
+package gensenc_test
+
+import (
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// TestDecodeAliasShares checks that strings decoded by DecodeAlias point
+// into the input, which builds without raw memory access copy instead.
+func TestDecodeAliasShares(t *testing.T) {
+	b, err := gensenc.Encode([]string{"abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	err = gensenc.DecodeAlias(b, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1] = 'x'
+	if s[0] != "abx" {
+		t.Errorf("got %q after changing the input, want it to follow", s[0])
+	}
+}
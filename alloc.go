@@ -0,0 +1,30 @@
+package gensenc
+
+import "reflect"
+
+// An Allocator provides the memory for decoded strings and slices, for
+// decoding into pools, arenas or memory managed outside of Go. Arena is an
+// Allocator.
+//
+// Decoding asks it for every string and for every slice that needs to
+// grow, whatever its elements; an Allocator may hand the latter back to
+// reflect.MakeSlice, as Arena does for elements with pointers. Maps, the
+// values pointers point to, and the slices of fields tagged rle and of
+// types with a codec of their own, such as Raw and net.IP, are allocated
+// as usual: reflect can't place maps in memory of its caller, and the
+// others grow piecemeal or are built by the codec.
+type Allocator interface {
+	// Alloc returns a slice of size bytes. Decoded strings share its
+	// memory, which must stay unmodified as long as they are in use.
+	Alloc(size int) []byte
+	// MakeSlice returns a slice of type t with a capacity of at least n.
+	// Its elements are overwritten.
+	MakeSlice(t reflect.Type, n int) reflect.Value
+}
+
+// WithAllocator makes decoding take the memory of strings and of slices
+// that need to grow from a. A Codec used by multiple goroutines at once
+// needs an Allocator safe for concurrent use, which Arena is not.
+func WithAllocator(a Allocator) Option {
+	return func(o *options) { o.allocator = a }
+}
@@ -0,0 +1,73 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// countingAllocator records what decoding asks it for.
+type countingAllocator struct {
+	sizes  []int
+	slices []reflect.Type
+}
+
+func (a *countingAllocator) Alloc(size int) []byte {
+	a.sizes = append(a.sizes, size)
+	return make([]byte, size)
+}
+
+func (a *countingAllocator) MakeSlice(t reflect.Type, n int) reflect.Value {
+	a.slices = append(a.slices, t)
+	return reflect.MakeSlice(t, n, n)
+}
+
+func TestWithAllocator(t *testing.T) {
+	v := order{ID: 1, Items: []string{"ab", "cde"}, Tags: map[string]int{"k": 1}}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &countingAllocator{}
+	c := gensenc.New(gensenc.WithAllocator(a))
+	var got order
+	if err := c.Decode(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	if want := []int{2, 3, 1}; !reflect.DeepEqual(a.sizes, want) {
+		t.Errorf("allocated strings of %v bytes, want %v", a.sizes, want)
+	}
+	if want := []reflect.Type{reflect.TypeFor[[]string]()}; !reflect.DeepEqual(a.slices, want) {
+		t.Errorf("made slices %v, want %v", a.slices, want)
+	}
+
+	// Slices large enough already aren't made anew.
+	a.slices = nil
+	got.Items = make([]string, 0, 4)
+	if err := c.Decode(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.slices) != 0 {
+		t.Errorf("made slices %v for a slice with room", a.slices)
+	}
+}
+
+func TestWithAllocatorInvalidLength(t *testing.T) {
+	// A string longer than the input fails before anything is allocated.
+	b := binary.LittleEndian.AppendUint64(nil, 1<<40)
+	a := &countingAllocator{}
+	var s string
+	err := gensenc.New(gensenc.WithAllocator(a)).Decode(b, &s)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("got %v, want ErrInvalidLength", err)
+	}
+	if len(a.sizes) != 0 {
+		t.Errorf("allocated %v bytes for invalid input", a.sizes)
+	}
+}
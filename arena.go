@@ -0,0 +1,88 @@
+package gensenc
+
+import "reflect"
+
+const defaultArenaChunk = 64 << 10
+
+// An Arena hands out memory for decoded strings and for slices whose
+// elements contain no pointers from a few large blocks instead of one
+// allocation per value. Reset releases all blocks at once; values decoded
+// before a Reset stay valid and keep their block alive until they become
+// unreachable themselves.
+//
+// Maps, values that pointers point to and slices whose elements contain
+// pointers are allocated as usual: the garbage collector doesn't scan
+// arena blocks, so pointers stored in them would not keep what they point
+// to alive.
+//
+// An Arena must not be used by multiple goroutines at once.
+type Arena struct {
+	cur       []byte
+	chunkSize int
+}
+
+// NewArena returns an arena allocating blocks of chunkSize bytes, or a
+// default size if chunkSize is not positive.
+func NewArena(chunkSize int) *Arena {
+	if chunkSize <= 0 {
+		chunkSize = defaultArenaChunk
+	}
+	return &Arena{chunkSize: chunkSize}
+}
+
+func (a *Arena) alloc(size, align int) []byte {
+	if size == 0 {
+		return nil
+	}
+	if size+align > a.chunkSize {
+		b := make([]byte, size+align)
+		pad := alignPad(b, align)
+		return b[pad : pad+size : pad+size]
+	}
+	pad := 0
+	if len(a.cur) > 0 {
+		pad = alignPad(a.cur, align)
+	}
+	if pad+size > len(a.cur) {
+		a.cur = make([]byte, a.chunkSize)
+		pad = alignPad(a.cur, align)
+	}
+	b := a.cur[pad : pad+size : pad+size]
+	a.cur = a.cur[pad+size:]
+	return b
+}
+
+// Alloc returns size bytes from the current block.
+func (a *Arena) Alloc(size int) []byte {
+	return a.alloc(size, 1)
+}
+
+// MakeSlice returns a slice of type t and length n, taken from the current
+// block if its elements contain no pointers and the build allows unsafe
+// memory access.
+func (a *Arena) MakeSlice(t reflect.Type, n int) reflect.Value {
+	if !rawMemory || hasPointers(t.Elem()) || n == 0 {
+		return reflect.MakeSlice(t, n, n)
+	}
+	b := a.alloc(n*int(t.Elem().Size()), t.Elem().Align())
+	if b == nil {
+		return reflect.MakeSlice(t, n, n)
+	}
+	return sliceAt(t, b, n)
+}
+
+// Reset drops the arena's current block so that subsequent decodes start
+// a new one.
+func (a *Arena) Reset() {
+	a.cur = nil
+}
+
+// DecodeArena is like Decode but allocates strings and pointer-free slices
+// from arena, and everything else as Decode does.
+func DecodeArena(b []byte, a any, arena *Arena) error {
+	d := defaultCodec.newDecodeState(b, nil)
+	if arena != nil {
+		d.allocator = arena
+	}
+	return defaultCodec.decode(d, reflect.ValueOf(a))
+}
@@ -0,0 +1,74 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestDecodeArena(t *testing.T) {
+	v := order{ID: 1, Items: []string{"ab", "cd", "ef"}, Tags: map[string]int{"k": 1}}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arena := gensenc.NewArena(0)
+	var got order
+	err = gensenc.DecodeArena(b, &got, arena)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	// The strings are taken from one block back to back.
+	first := unsafe.StringData(got.Items[0])
+	for i, s := range got.Items[1:] {
+		if unsafe.StringData(s) != (*byte)(unsafe.Add(unsafe.Pointer(first), 2*(i+1))) {
+			t.Errorf("string %d is not next to the one before it", i+1)
+		}
+	}
+	got = order{}
+	if err := gensenc.DecodeArena(b, &got, nil); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("decoding without an arena gave %+v, %v", got, err)
+	}
+	err = gensenc.DecodeArena(b[:len(b)-1], &got, arena)
+	if !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated input gave %v, want ErrTruncated", err)
+	}
+}
+
+func TestArenaAlloc(t *testing.T) {
+	arena := gensenc.NewArena(16)
+	if b := arena.Alloc(0); b != nil {
+		t.Errorf("allocated %d bytes for 0", len(b))
+	}
+	a, b := arena.Alloc(4), arena.Alloc(4)
+	if cap(a) != 4 {
+		t.Errorf("allocation of 4 bytes has a capacity of %d, reaching into the next", cap(a))
+	}
+	if &b[0] != (*byte)(unsafe.Add(unsafe.Pointer(&a[0]), 4)) {
+		t.Error("allocations from one block are not consecutive")
+	}
+	// Allocations larger than a block get memory of their own, and those
+	// not fitting the rest of the block a new block.
+	if big := arena.Alloc(100); len(big) != 100 {
+		t.Errorf("allocated %d bytes for 100", len(big))
+	}
+	c := arena.Alloc(12)
+	if &c[0] == (*byte)(unsafe.Add(unsafe.Pointer(&b[0]), 4)) {
+		t.Error("12 bytes were taken from a block with 8 left")
+	}
+	arena.Reset()
+	d := arena.Alloc(1)
+	if &d[0] == (*byte)(unsafe.Add(unsafe.Pointer(&c[0]), 12)) {
+		t.Error("Reset kept the current block")
+	}
+	s := arena.MakeSlice(reflect.TypeFor[[]int64](), 3)
+	if s.Len() != 3 || s.Index(2).Int() != 0 {
+		t.Errorf("MakeSlice returned %v", s)
+	}
+}
@@ -0,0 +1,130 @@
+package gensenc
+
+import "reflect"
+
+// arrayLenCodec encodes array fields tagged with arraylen. The array is
+// preceded by its length like a slice, and decoding fails with
+// ErrArrayLength unless the length matches the destination array, instead
+// of misreading everything after an array whose size changed.
+type arrayLenCodec struct {
+	t reflect.Type
+}
+
+func newArrayLenCodec(t reflect.Type) wireCodec {
+	if t.Kind() != reflect.Array {
+		return invalidTag{}
+	}
+	return arrayLenCodec{t}
+}
+
+func (c arrayLenCodec) encode(e *encodeState, v reflect.Value) error {
+	e.writeUint64(uint64(c.t.Len()))
+	for i := range v.Len() {
+		err := e.encode(v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c arrayLenCodec) readLen(d *decodeState) error {
+	n, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	if n != uint64(c.t.Len()) {
+		return ErrArrayLength
+	}
+	return nil
+}
+
+func (c arrayLenCodec) decode(d *decodeState, v reflect.Value) error {
+	err := c.readLen(d)
+	if err != nil {
+		return err
+	}
+	for i := range v.Len() {
+		err = d.decode(v.Index(i))
+		if err != nil {
+			return d.at(err, index(i))
+		}
+	}
+	return nil
+}
+
+func (c arrayLenCodec) skip(d *decodeState) error {
+	err := c.readLen(d)
+	if err != nil {
+		return err
+	}
+	for i := range c.t.Len() {
+		err = d.skip(c.t.Elem())
+		if err != nil {
+			return d.at(err, index(i))
+		}
+	}
+	return nil
+}
+
+func (c arrayLenCodec) wireSize(visiting map[reflect.Type]bool) int {
+	s := computeWireSize(c.t, visiting)
+	if s < 0 {
+		return -1
+	}
+	return 8 + s
+}
+
+// decodeArrayLen decodes an array preceded by its length. Surplus elements
+// are skipped and missing ones left zero, so arrays can change size
+// between versions.
+func (d *decodeState) decodeArrayLen(v reflect.Value) error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	elem := v.Type().Elem()
+	err = d.checkLength(length, d.minSize(elem))
+	if err != nil {
+		return err
+	}
+	for i := range int(length) {
+		err = d.tick()
+		if err != nil {
+			return err
+		}
+		if i < v.Len() {
+			err = d.decode(v.Index(i))
+		} else {
+			err = d.skip(elem)
+		}
+		if err != nil {
+			return d.at(err, index(i))
+		}
+	}
+	for i := int(length); i < v.Len(); i++ {
+		if !v.Index(i).CanSet() {
+			return ErrCantSet
+		}
+		v.Index(i).SetZero()
+	}
+	return nil
+}
+
+func (d *decodeState) skipArrayLen(t reflect.Type) error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	err = d.checkLength(length, d.minSize(t.Elem()))
+	if err != nil {
+		return err
+	}
+	for i := range length {
+		err = d.skip(t.Elem())
+		if err != nil {
+			return d.at(err, index(int(i)))
+		}
+	}
+	return nil
+}
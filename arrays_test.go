@@ -0,0 +1,109 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type hops3 struct {
+	Hops [3]uint16 `gensenc:"arraylen"`
+	Name string
+}
+
+type hops2 struct {
+	Hops [2]uint16 `gensenc:"arraylen"`
+	Name string
+}
+
+func TestArrayLenTag(t *testing.T) {
+	v := hops3{Hops: [3]uint16{1, 2, 3}, Name: "r"}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The array is preceded by its length.
+	if n := binary.LittleEndian.Uint64(b); n != 3 {
+		t.Errorf("encoded length %d, want 3", n)
+	}
+	if got := roundTrip(t, gensenc.New(), v); got != v {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	var short hops2
+	err = gensenc.Decode(b, &short)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrArrayLength) || de.Path != "Hops" {
+		t.Errorf("decoding into a shorter array gave %v, want ErrArrayLength at Hops", err)
+	}
+	// Skipping checks the length too.
+	dec := gensenc.NewDecoder(bytes.NewReader(b))
+	if err := dec.Skip(reflect.TypeFor[hops2]()); !errors.Is(err, gensenc.ErrArrayLength) {
+		t.Errorf("skipping as a shorter array gave %v, want ErrArrayLength", err)
+	}
+	_, err = gensenc.Encode(struct {
+		S []int `gensenc:"arraylen"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("tagging a slice gave %v, want ErrInvalidTag", err)
+	}
+}
+
+type route3 struct {
+	Hops [3]uint16
+	Name string
+}
+
+type route2 struct {
+	Hops [2]uint16
+	Name string
+}
+
+type route4 struct {
+	Hops [4]uint16
+	Name string
+}
+
+func TestWithArrayLengths(t *testing.T) {
+	c := gensenc.New(gensenc.WithArrayLengths())
+	b, err := c.Encode(route3{Hops: [3]uint16{1, 2, 3}, Name: "r"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var short route2
+	err = c.Decode(b, &short)
+	if err != nil || short != (route2{Hops: [2]uint16{1, 2}, Name: "r"}) {
+		t.Errorf("decoding into a shorter array gave %+v, %v", short, err)
+	}
+	// Elements missing from the input are zeroed, not left as they were.
+	long := route4{Hops: [4]uint16{9, 9, 9, 9}}
+	err = c.Decode(b, &long)
+	if err != nil || long != (route4{Hops: [4]uint16{1, 2, 3, 0}, Name: "r"}) {
+		t.Errorf("decoding into a longer array gave %+v, %v", long, err)
+	}
+	dec := gensenc.NewDecoder(bytes.NewReader(b))
+	dec.ArrayLengths()
+	short = route2{}
+	err = dec.Decode(&short)
+	if err != nil || short != (route2{Hops: [2]uint16{1, 2}, Name: "r"}) {
+		t.Errorf("decoding from a stream gave %+v, %v", short, err)
+	}
+	skipping := gensenc.NewDecoder(bytes.NewReader(append(b, b...)))
+	skipping.ArrayLengths()
+	if err := skipping.Skip(reflect.TypeFor[route2]()); err != nil {
+		t.Fatal(err)
+	}
+	var again route3
+	if err := skipping.Decode(&again); err != nil || again.Name != "r" {
+		t.Errorf("decoding after a skip gave %+v, %v", again, err)
+	}
+
+	huge := binary.LittleEndian.AppendUint64(nil, 1<<40)
+	err = c.Decode(huge, &short)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a huge array length gave %v, want ErrInvalidLength", err)
+	}
+}
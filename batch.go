@@ -0,0 +1,135 @@
+package gensenc
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// EncodeBatch encodes s as a batch: the Fingerprint of T followed by s
+// encoded as a slice, written into one buffer with the encoding plan of T
+// looked up once. Elements laid out in memory like on the wire are copied
+// in one piece.
+func EncodeBatch[T any](s []T) ([]byte, error) {
+	e := defaultCodec.getState()
+	defer defaultCodec.putState(e)
+	e.writeUint64(Fingerprint(reflect.TypeFor[T]()))
+	err := defaultCodec.encode(e, reflect.ValueOf(s))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(e.buf.Bytes()), nil
+}
+
+// DecodeBatch decodes a batch written by EncodeBatch, failing with
+// ErrFingerprintMismatch if it holds values of another type than T.
+func DecodeBatch[T any](b []byte) ([]T, error) {
+	var s []T
+	d := defaultCodec.newDecodeState(b, nil)
+	err := d.guard(func() error {
+		fp, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if fp != Fingerprint(reflect.TypeFor[T]()) {
+			return ErrFingerprintMismatch
+		}
+		return d.decode(reflect.ValueOf(&s).Elem())
+	})
+	if err == nil && d.off != len(b) {
+		err = &DecodeError{Err: ErrTrailingBytes, Offset: int64(d.off)}
+	}
+	defaultCodec.metrics.decode(int64(d.off), err)
+	return s, err
+}
+
+// DecodeBatchParallel is like DecodeBatch but decodes into dst, reusing
+// its memory if it has the capacity, and for large batches decodes
+// contiguous chunks of the elements on up to workers goroutines. Unless
+// the elements have a fixed size, they are skipped over once first to find
+// where the chunks start. If workers is not positive, GOMAXPROCS is used.
+func DecodeBatchParallel[T any](b []byte, dst []T, workers int) ([]T, error) {
+	t := reflect.TypeFor[T]()
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	var n int
+	var starts []int
+	err := d.guard(func() error {
+		fp, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if fp != Fingerprint(t) {
+			return ErrFingerprintMismatch
+		}
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		err = d.checkLength(length, d.minSize(t))
+		if err != nil {
+			return err
+		}
+		n = int(length)
+		workers = max(1, min(workers, n/minParallelChunk))
+		size := (n + workers - 1) / workers
+		starts = make([]int, workers+1)
+		s := d.size(infoOf(t))
+		for w := range workers {
+			starts[w] = d.off
+			if s >= 0 {
+				d.off += min(size, n-w*size) * s
+				continue
+			}
+			for i := w * size; i < min((w+1)*size, n); i++ {
+				err = d.skip(t)
+				if err != nil {
+					return d.at(err, index(i))
+				}
+			}
+		}
+		starts[workers] = d.off
+		return nil
+	})
+	if err == nil && d.off != len(b) {
+		err = &DecodeError{Err: ErrTrailingBytes, Offset: int64(d.off)}
+	}
+	if err != nil {
+		defaultCodec.metrics.decode(0, err)
+		return dst[:0], err
+	}
+	dst = slices.Grow(dst[:0], n)[:n]
+	errs := make([]error, workers)
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := defaultCodec.newDecodeState(b[:starts[w+1]], nil)
+			d.off = starts[w]
+			errs[w] = d.guard(func() error {
+				for i := w * size; i < min((w+1)*size, n); i++ {
+					err := d.decode(reflect.ValueOf(&dst[i]).Elem())
+					if err != nil {
+						return d.at(err, index(i))
+					}
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			defaultCodec.metrics.decode(0, err)
+			return dst[:0], err
+		}
+	}
+	defaultCodec.metrics.decode(int64(len(b)), nil)
+	return dst, nil
+}
@@ -0,0 +1,151 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestBatch(t *testing.T) {
+	orders := []order{
+		{ID: 1, Items: []string{"a"}, Tags: map[string]int{"x": 1}},
+		{ID: 2, Tags: map[string]int{}},
+	}
+	b, err := gensenc.EncodeBatch(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := gensenc.Fingerprint(reflect.TypeFor[order]())
+	if binary.LittleEndian.Uint64(b) != fp {
+		t.Errorf("batch starts with %x, want the fingerprint %x", b[:8], fp)
+	}
+	if s, _ := gensenc.Encode(orders); string(b[8:]) != string(s) {
+		t.Errorf("batch holds %x, want the slice %x", b[8:], s)
+	}
+	got, err := gensenc.DecodeBatch[order](b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, orders) {
+		t.Errorf("got %+v, want %+v", got, orders)
+	}
+
+	points := []point{{1, 2}, {-3, 4}}
+	b, err = gensenc.EncodeBatch(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gensenc.DecodeBatch[point](b); err != nil || !reflect.DeepEqual(got, points) {
+		t.Errorf("got %v, %v, want %v", got, err, points)
+	}
+	b, err = gensenc.EncodeBatch[point](nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gensenc.DecodeBatch[point](b); err != nil || len(got) != 0 {
+		t.Errorf("decoding an empty batch gave %v, %v", got, err)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	b, err := gensenc.EncodeBatch([]point{{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gensenc.DecodeBatch[order](b); !errors.Is(err, gensenc.ErrFingerprintMismatch) {
+		t.Errorf("decoding points as orders gave %v, want ErrFingerprintMismatch", err)
+	}
+	if _, err := gensenc.DecodeBatch[point](append(b, 0)); !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("decoding a byte too many gave %v, want ErrTrailingBytes", err)
+	}
+	for _, n := range []int{0, 4, 12, len(b) - 1} {
+		if _, err := gensenc.DecodeBatch[point](b[:n]); err == nil {
+			t.Errorf("decoding %d of %d bytes succeeded", n, len(b))
+		}
+	}
+	if _, err := gensenc.EncodeBatch([]chan int{nil}); !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("encoding channels gave %v, want ErrUnsupportedKind", err)
+	}
+}
+
+func TestBatchParallel(t *testing.T) {
+	const n = 20000
+	orders := make([]order, n)
+	points := make([]point, n)
+	for i := range n {
+		orders[i] = order{ID: uint64(i), Items: make([]string, i%3), Tags: map[string]int{}}
+		for j := range orders[i].Items {
+			orders[i].Items[j] = string(rune('a' + j))
+		}
+		if len(orders[i].Items) == 0 {
+			orders[i].Items = nil
+		}
+		points[i] = point{int32(i), int32(-i)}
+	}
+	b, err := gensenc.EncodeBatch(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gensenc.DecodeBatchParallel[order](b, nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, orders) {
+		t.Error("orders decoded in parallel differ")
+	}
+
+	// Fixed-size elements are decoded into the memory given.
+	b, err = gensenc.EncodeBatch(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]point, 3, n)
+	gotPoints, err := gensenc.DecodeBatchParallel(b, dst, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotPoints, points) || &gotPoints[0] != &dst[0] {
+		t.Error("points decoded in parallel differ or weren't decoded into dst")
+	}
+}
+
+func TestBatchParallelErrors(t *testing.T) {
+	const n = 20000
+	b := binary.LittleEndian.AppendUint64(nil, gensenc.Fingerprint(reflect.TypeFor[uint8]()))
+	b = binary.LittleEndian.AppendUint64(b, n)
+	for i := range n {
+		v := uint64(i % 256)
+		if i == 15000 {
+			v = 300
+		}
+		b = binary.LittleEndian.AppendUint64(b, v)
+	}
+	_, err := gensenc.DecodeBatchParallel[uint8](b, nil, 4)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) || de.Path != "[15000]" {
+		t.Errorf("got %v, want ErrOverflow at [15000]", err)
+	}
+	if _, err := gensenc.DecodeBatchParallel[int8](b, nil, 4); !errors.Is(err, gensenc.ErrFingerprintMismatch) {
+		t.Errorf("decoding as int8 gave %v, want ErrFingerprintMismatch", err)
+	}
+	if _, err := gensenc.DecodeBatchParallel[uint8](append(b, 0), nil, 4); !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("decoding a byte too many gave %v, want ErrTrailingBytes", err)
+	}
+	if _, err := gensenc.DecodeBatchParallel[uint8](b[:len(b)-1], nil, 4); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a truncated batch gave %v, want ErrInvalidLength", err)
+	}
+
+	// Truncated elements of variable size are found while skipping.
+	orders := make([]order, n)
+	orders[n-1].Items = []string{"abcdef"}
+	vb, err := gensenc.EncodeBatch(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gensenc.DecodeBatchParallel[order](vb[:len(vb)-1], nil, 4); !errors.As(err, &de) || de.Path != "[19999].Note" {
+		t.Errorf("decoding truncated orders gave %v, want an error at [19999].Note", err)
+	}
+}
@@ -0,0 +1,241 @@
+package gensenc
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// bitsCodec packs bool and small integer fields into as few bits as they
+// need. Fields tagged with bits hold bools, or slices or arrays of them,
+// which become bitmaps. Fields tagged with bits=N, for N between 1 and 64,
+// hold integers, or slices or arrays of them, which are packed with N bits
+// per value, the least significant bits first; signed values are stored
+// in two's complement. Slices are preceded by their length as usual, and a
+// field takes as many bytes as its bits fill.
+type bitsCodec struct {
+	t reflect.Type
+	// bits is the number of bits per value.
+	bits int
+}
+
+func newBitsCodec(t reflect.Type, value string) wireCodec {
+	elem := t
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		elem = t.Elem()
+	}
+	if value == "" {
+		if elem.Kind() != reflect.Bool {
+			return invalidTag{}
+		}
+		return bitsCodec{t, 1}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 64 {
+		return invalidTag{}
+	}
+	switch elem.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return bitsCodec{t, n}
+	}
+	return invalidTag{}
+}
+
+// bytesFor returns the number of bytes holding n packed values.
+func (c bitsCodec) bytesFor(n int) int {
+	return (n*c.bits + 7) / 8
+}
+
+func (c bitsCodec) encode(e *encodeState, v reflect.Value) error {
+	if c.t.Kind() == reflect.Slice {
+		e.writeUint64(uint64(v.Len()))
+	}
+	values := c.values(v)
+	b := make([]byte, c.bytesFor(len(values)))
+	for i, x := range values {
+		bits, err := c.valueBits(x)
+		if err != nil {
+			return err
+		}
+		putBits(b, i*c.bits, c.bits, bits)
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+// valueBits returns the low bits of v, failing with ErrOverflow if v
+// doesn't fit them.
+func (c bitsCodec) valueBits(v reflect.Value) (uint64, error) {
+	if v.Kind() == reflect.Bool {
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if v.CanInt() {
+		x := v.Int()
+		if c.bits < 64 && (x >= 1<<(c.bits-1) || x < -1<<(c.bits-1)) {
+			return 0, ErrOverflow
+		}
+		return uint64(x) & mask(c.bits), nil
+	}
+	x := v.Uint()
+	if x&^mask(c.bits) != 0 {
+		return 0, ErrOverflow
+	}
+	return x, nil
+}
+
+func mask(bits int) uint64 {
+	return math.MaxUint64 >> (64 - bits)
+}
+
+func putBits(b []byte, pos, n int, x uint64) {
+	for n > 0 {
+		i, o := pos/8, pos%8
+		k := min(8-o, n)
+		b[i] |= byte(x&(1<<k-1)) << o
+		x >>= k
+		pos += k
+		n -= k
+	}
+}
+
+func getBits(b []byte, pos, n int) uint64 {
+	var x uint64
+	for shift := 0; shift < n; {
+		i, o := pos/8, pos%8
+		k := min(8-o, n-shift)
+		x |= uint64(b[i]>>o&(1<<k-1)) << shift
+		pos += k
+		shift += k
+	}
+	return x
+}
+
+func (c bitsCodec) set(d *decodeState, v reflect.Value, bits uint64) error {
+	if v.Kind() == reflect.Bool {
+		v.SetBool(bits != 0)
+		return nil
+	}
+	if v.CanInt() && c.bits < 64 && bits>>(c.bits-1) != 0 {
+		bits |= ^mask(c.bits)
+	}
+	return d.setInteger(v, bits)
+}
+
+// length reads the number of values of the field and the bytes packing
+// them.
+func (c bitsCodec) length(d *decodeState) (int, []byte, error) {
+	n := 1
+	switch c.t.Kind() {
+	case reflect.Slice:
+		length, err := d.readUint64()
+		if err != nil {
+			return 0, nil, err
+		}
+		if length > math.MaxInt/64 {
+			return 0, nil, ErrInvalidLength
+		}
+		n = int(length)
+		err = d.checkLength(uint64(c.bytesFor(n)), 1)
+		if err != nil {
+			return 0, nil, err
+		}
+	case reflect.Array:
+		n = c.t.Len()
+	}
+	b, err := d.take(uint64(c.bytesFor(n)))
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, b, nil
+}
+
+func (c bitsCodec) decode(d *decodeState, v reflect.Value) error {
+	n, b, err := c.length(d)
+	if err != nil {
+		return err
+	}
+	switch c.t.Kind() {
+	case reflect.Slice:
+		return d.decodeElems(v, n, func(i int, elem reflect.Value) error {
+			return c.set(d, elem, getBits(b, i*c.bits, c.bits))
+		})
+	case reflect.Array:
+		for i := range n {
+			if !v.Index(i).CanSet() {
+				return ErrCantSet
+			}
+			err = c.set(d, v.Index(i), getBits(b, i*c.bits, c.bits))
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+		return nil
+	}
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	return c.set(d, v, getBits(b, 0, c.bits))
+}
+
+func (c bitsCodec) skip(d *decodeState) error {
+	_, _, err := c.length(d)
+	return err
+}
+
+func (c bitsCodec) wireSize(map[reflect.Type]bool) int {
+	switch c.t.Kind() {
+	case reflect.Slice:
+		return -1
+	case reflect.Array:
+		return c.bytesFor(c.t.Len())
+	}
+	return c.bytesFor(1)
+}
+
+// values returns the values v holds, one for a single bool or integer and
+// its elements for slices and arrays.
+func (c bitsCodec) values(v reflect.Value) []reflect.Value {
+	switch c.t.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]reflect.Value, v.Len())
+		for i := range values {
+			values[i] = v.Index(i)
+		}
+		return values
+	}
+	return []reflect.Value{v}
+}
+
+// check fails with ErrOverflow if any of the values of v doesn't fit its
+// bits.
+func (c bitsCodec) check(v reflect.Value) error {
+	for _, x := range c.values(v) {
+		_, err := c.valueBits(x)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFormat writes the values as their kind, failing like encode for
+// those that don't fit their bits.
+func (c bitsCodec) writeFormat(e *encodeState, w FormatWriter, v reflect.Value) error {
+	err := c.check(v)
+	if err != nil {
+		return err
+	}
+	return e.encodeFormat(w, v)
+}
+
+func (c bitsCodec) readFormat(d *decodeState, r FormatReader, v reflect.Value) error {
+	err := d.decodeFormat(r, v)
+	if err != nil {
+		return err
+	}
+	return c.check(v)
+}
@@ -0,0 +1,97 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type packed struct {
+	Flags  [10]bool `gensenc:"bits"`
+	Nibs   []uint8  `gensenc:"bits=4"`
+	Signed int8     `gensenc:"bits=4"`
+	Odd    [3]int16 `gensenc:"bits=11"`
+	Full   uint64   `gensenc:"bits=64"`
+	Mask   []bool   `gensenc:"bits"`
+}
+
+func TestBits(t *testing.T) {
+	v := packed{
+		Flags:  [10]bool{0: true, 9: true},
+		Nibs:   []uint8{1, 15, 2},
+		Signed: -8,
+		Odd:    [3]int16{-1024, 1023, -1},
+		Full:   math.MaxUint64,
+		Mask:   []bool{false, true},
+	}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x01, 0x02, // bits 0 and 9
+		3, 0, 0, 0, 0, 0, 0, 0, 0xf1, 0x02, // the least significant bits first
+		0x08,
+		0x00, 0xfc, 0xdf, 0xff, 0x01, // 33 bits: 0x400, 0x3ff and 0x7ff
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		2, 0, 0, 0, 0, 0, 0, 0, 0x02,
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	if got := roundTrip(t, gensenc.New(), v); !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+}
+
+func TestBitsErrors(t *testing.T) {
+	for _, v := range []any{
+		packed{Signed: 8},
+		packed{Signed: -9},
+		packed{Nibs: []uint8{16}},
+		packed{Odd: [3]int16{1024}},
+	} {
+		_, err := gensenc.Encode(v)
+		if !errors.Is(err, gensenc.ErrOverflow) {
+			t.Errorf("encoding %+v gave %v, want ErrOverflow", v, err)
+		}
+	}
+	// Sizes too large for the destination are caught on decode.
+	type narrow struct {
+		N uint8 `gensenc:"bits=12"`
+	}
+	b, err := gensenc.Encode(struct {
+		N uint16 `gensenc:"bits=12"`
+	}{N: 4095})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n narrow
+	if err := gensenc.Decode(b, &n); !errors.Is(err, gensenc.ErrOverflow) {
+		t.Errorf("decoding 4095 into a uint8 gave %v, want ErrOverflow", err)
+	}
+	var p packed
+	huge := append(make([]byte, 2), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x0f)
+	if err := gensenc.Decode(huge, &p); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a huge slice length gave %v, want ErrInvalidLength", err)
+	}
+	for _, v := range []any{
+		struct {
+			N int `gensenc:"bits"`
+		}{},
+		struct {
+			B bool `gensenc:"bits=65"`
+		}{},
+		struct {
+			F float64 `gensenc:"bits=8"`
+		}{},
+	} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package cache stores values encoded with gensenc in key-value stores such
+// as Redis or memcached, behind a small KVStore interface:
+//
+//	c := &cache.Cache{Store: store}
+//	err := c.Set(ctx, "user:1", user, time.Hour)
+//	...
+//	err = c.Get(ctx, "user:1", &user)
+//	if errors.Is(err, cache.ErrMiss) {
+//		// load it
+//	}
+//
+// Entries carry the gensenc.Fingerprint of their type, so that entries
+// written before the type changed are treated as missing instead of
+// decoding wrongly.
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var ErrMiss error = errors.New("cache miss")
+
+// A KVStore stores byte values under string keys.
+type KVStore interface {
+	// Get returns the value stored under key, with ok false if there is
+	// none.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key, to expire after ttl, or never if ttl is
+	// zero.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// A Cache stores values in a KVStore.
+type Cache struct {
+	Store KVStore
+	// Codec, if not nil, encodes and decodes the values. Readers and
+	// writers of the same keys need the same configuration.
+	Codec *gensenc.Codec
+}
+
+func (c *Cache) codec() *gensenc.Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return gensenc.New()
+}
+
+func fingerprint(t reflect.Type) uint64 {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return gensenc.Fingerprint(t)
+}
+
+// Set stores v under key for ttl.
+func (c *Cache) Set(ctx context.Context, key string, v any, ttl time.Duration) error {
+	if v == nil {
+		return gensenc.ErrNilPointer
+	}
+	b, err := c.codec().Encode(v)
+	if err != nil {
+		return err
+	}
+	entry := binary.LittleEndian.AppendUint64(make([]byte, 0, 8+len(b)), fingerprint(reflect.TypeOf(v)))
+	return c.Store.Set(ctx, key, append(entry, b...), ttl)
+}
+
+// Get decodes the value stored under key into the value v points to,
+// failing with ErrMiss if there is none or it was stored as another type.
+func (c *Cache) Get(ctx context.Context, key string, v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return gensenc.ErrNilPointer
+	}
+	entry, ok, err := c.Store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !ok || len(entry) < 8 || binary.LittleEndian.Uint64(entry) != fingerprint(t) {
+		return ErrMiss
+	}
+	return c.codec().Decode(entry[8:], v)
+}
@@ -0,0 +1,131 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/cache"
+)
+
+// mapStore is a KVStore in memory, failing with err if set.
+type mapStore struct {
+	m    map[string][]byte
+	ttls map[string]time.Duration
+	err  error
+}
+
+func newStore() *mapStore {
+	return &mapStore{m: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (s *mapStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	v, ok := s.m[key]
+	return v, ok, nil
+}
+
+func (s *mapStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.m[key], s.ttls[key] = value, ttl
+	return nil
+}
+
+type user struct {
+	ID   uint64
+	Name string
+	Tags []string
+}
+
+type userV2 struct {
+	ID    uint64
+	Name  string
+	Tags  []string
+	Email string
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	store := newStore()
+	c := &cache.Cache{Store: store, Codec: gensenc.New(gensenc.WithVarints())}
+	u := user{ID: 1, Name: "ann", Tags: []string{"a"}}
+	if err := c.Set(ctx, "user:1", &u, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if store.ttls["user:1"] != time.Hour {
+		t.Errorf("stored for %v, want an hour", store.ttls["user:1"])
+	}
+	var got user
+	if err := c.Get(ctx, "user:1", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, u) {
+		t.Errorf("got %+v, want %+v", got, u)
+	}
+	// Values and pointers to them are stored alike.
+	if err := c.Set(ctx, "user:2", u, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, "user:2", &got); err != nil || !reflect.DeepEqual(got, u) {
+		t.Errorf("got %+v, %v, want %+v", got, err, u)
+	}
+}
+
+func TestCacheMiss(t *testing.T) {
+	ctx := context.Background()
+	store := newStore()
+	c := &cache.Cache{Store: store}
+	if err := c.Set(ctx, "user:1", user{ID: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := c.Get(ctx, "none", &u); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("getting a missing key gave %v, want ErrMiss", err)
+	}
+	// Entries of a type since changed are missing.
+	var v2 userV2
+	if err := c.Get(ctx, "user:1", &v2); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("getting a changed type gave %v, want ErrMiss", err)
+	}
+	store.m["short"] = []byte{1, 2, 3}
+	if err := c.Get(ctx, "short", &u); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("getting a short entry gave %v, want ErrMiss", err)
+	}
+
+	// A damaged entry of the right type fails to decode.
+	b := store.m["user:1"]
+	store.m["user:1"] = b[:len(b)-1]
+	if err := c.Get(ctx, "user:1", &u); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("getting a truncated entry gave %v, want ErrTruncated", err)
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	store := newStore()
+	c := &cache.Cache{Store: store}
+	if err := c.Set(ctx, "k", nil, 0); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("setting nil gave %v, want ErrNilPointer", err)
+	}
+	if err := c.Get(ctx, "k", user{}); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("getting into a non-pointer gave %v, want ErrNilPointer", err)
+	}
+	if err := c.Set(ctx, "k", make(chan int), 0); !errors.Is(err, gensenc.ErrUnsupportedKind) || len(store.m) != 0 {
+		t.Errorf("setting a channel gave %v, want ErrUnsupportedKind and nothing stored", err)
+	}
+	store.err = errors.New("connection refused")
+	var u user
+	if err := c.Get(ctx, "k", &u); !errors.Is(err, store.err) {
+		t.Errorf("got %v, want the store's error", err)
+	}
+	if err := c.Set(ctx, "k", u, 0); !errors.Is(err, store.err) {
+		t.Errorf("got %v, want the store's error", err)
+	}
+}
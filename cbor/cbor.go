@@ -0,0 +1,469 @@
+// Package cbor implements CBOR (RFC 8949) as a gensenc.Format.
+//
+// Values are written in the preferred serialization: every head uses its
+// shortest form and no indefinite-length items are produced. When
+// reading, semantic tags are ignored and indefinite-length items are
+// rejected.
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var (
+	ErrTypeMismatch error = errors.New("cbor: unexpected type")
+	ErrOverflow     error = errors.New("cbor: integer overflow")
+	ErrIndefinite   error = errors.New("cbor: indefinite-length items are not supported")
+)
+
+// Format is the CBOR format.
+var Format gensenc.Format = format{}
+
+type format struct{}
+
+func (format) NewWriter(w io.Writer) gensenc.FormatWriter {
+	return &writer{w: w}
+}
+
+func (format) NewReader(r io.Reader) gensenc.FormatReader {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &reader{r: br}
+}
+
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	simpleFloat16   = 25
+	simpleFloat32   = 26
+	simpleFloat64   = 27
+	indefinite      = 31
+)
+
+type writer struct {
+	w io.Writer
+	b [9]byte
+}
+
+func (w *writer) head(major byte, n uint64) error {
+	var size int
+	switch {
+	case n < 24:
+		w.b[0] = major<<5 | byte(n)
+	case n <= math.MaxUint8:
+		w.b[0] = major<<5 | 24
+		w.b[1] = byte(n)
+		size = 1
+	case n <= math.MaxUint16:
+		w.b[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(w.b[1:], uint16(n))
+		size = 2
+	case n <= math.MaxUint32:
+		w.b[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(w.b[1:], uint32(n))
+		size = 4
+	default:
+		w.b[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(w.b[1:], n)
+		size = 8
+	}
+	_, err := w.w.Write(w.b[:1+size])
+	return err
+}
+
+func (w *writer) WriteNil() error {
+	return w.head(majorSimple, simpleNull)
+}
+
+func (w *writer) WriteBool(b bool) error {
+	if b {
+		return w.head(majorSimple, simpleTrue)
+	}
+	return w.head(majorSimple, simpleFalse)
+}
+
+func (w *writer) WriteInt(i int64) error {
+	if i < 0 {
+		return w.head(majorNegint, uint64(^i))
+	}
+	return w.head(majorUint, uint64(i))
+}
+
+func (w *writer) WriteUint(u uint64) error {
+	return w.head(majorUint, u)
+}
+
+func (w *writer) WriteFloat32(f float32) error {
+	w.b[0] = majorSimple<<5 | simpleFloat32
+	binary.BigEndian.PutUint32(w.b[1:], math.Float32bits(f))
+	_, err := w.w.Write(w.b[:5])
+	return err
+}
+
+func (w *writer) WriteFloat64(f float64) error {
+	w.b[0] = majorSimple<<5 | simpleFloat64
+	binary.BigEndian.PutUint64(w.b[1:], math.Float64bits(f))
+	_, err := w.w.Write(w.b[:9])
+	return err
+}
+
+func (w *writer) WriteString(s string) error {
+	err := w.head(majorText, uint64(len(s)))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.w, s)
+	return err
+}
+
+func (w *writer) WriteBytes(b []byte) error {
+	err := w.head(majorBytes, uint64(len(b)))
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+func (w *writer) WriteArrayHeader(n int) error {
+	return w.head(majorArray, uint64(n))
+}
+
+func (w *writer) WriteMapHeader(n int) error {
+	return w.head(majorMap, uint64(n))
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteScanner
+}
+
+type reader struct {
+	r byteReader
+	b [8]byte
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// head reads the head of the next data item, skipping any tags, and
+// returns its major type, additional information and argument.
+func (r *reader) head() (byte, byte, uint64, error) {
+	for {
+		c, err := r.r.ReadByte()
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		major, info := c>>5, c&0x1f
+		var n uint64
+		switch {
+		case info < 24:
+			n = uint64(info)
+		case info <= 27:
+			size := 1 << (info - 24)
+			_, err = io.ReadFull(r.r, r.b[:size])
+			if err != nil {
+				return 0, 0, 0, unexpected(err)
+			}
+			switch size {
+			case 1:
+				n = uint64(r.b[0])
+			case 2:
+				n = uint64(binary.BigEndian.Uint16(r.b[:]))
+			case 4:
+				n = uint64(binary.BigEndian.Uint32(r.b[:]))
+			default:
+				n = binary.BigEndian.Uint64(r.b[:])
+			}
+		case info == indefinite:
+			return 0, 0, 0, ErrIndefinite
+		default:
+			return 0, 0, 0, ErrTypeMismatch
+		}
+		if major != majorTag {
+			return major, info, n, nil
+		}
+	}
+}
+
+// bytes reads n bytes without trusting n for the initial allocation.
+func (r *reader) bytes(n uint64) ([]byte, error) {
+	if n <= 64<<10 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r.r, b)
+		return b, unexpected(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	m, err := io.CopyN(buf, r.r, int64(n))
+	if uint64(m) < n {
+		return nil, unexpected(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *reader) ReadNil() (bool, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if c == majorSimple<<5|simpleNull || c == majorSimple<<5|simpleUndefined {
+		return true, nil
+	}
+	return false, r.r.UnreadByte()
+}
+
+func (r *reader) ReadBool() (bool, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return false, err
+	}
+	if major == majorSimple {
+		switch n {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		}
+	}
+	return false, ErrTypeMismatch
+}
+
+func (r *reader) ReadInt() (int64, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	switch major {
+	case majorUint, majorNegint:
+		if n > math.MaxInt64 {
+			return 0, ErrOverflow
+		}
+		if major == majorNegint {
+			return ^int64(n), nil
+		}
+		return int64(n), nil
+	}
+	return 0, ErrTypeMismatch
+}
+
+func (r *reader) ReadUint() (uint64, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	switch major {
+	case majorUint:
+		return n, nil
+	case majorNegint:
+		return 0, ErrOverflow
+	}
+	return 0, ErrTypeMismatch
+}
+
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+func (r *reader) ReadFloat64() (float64, error) {
+	major, info, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	switch major {
+	case majorSimple:
+		switch info {
+		case simpleFloat16:
+			return float16(uint16(n)), nil
+		case simpleFloat32:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case simpleFloat64:
+			return math.Float64frombits(n), nil
+		}
+	case majorUint:
+		return float64(n), nil
+	case majorNegint:
+		return -1 - float64(n), nil
+	}
+	return 0, ErrTypeMismatch
+}
+
+func (r *reader) ReadString() (string, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return "", err
+	}
+	if major != majorText && major != majorBytes {
+		return "", ErrTypeMismatch
+	}
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+func (r *reader) ReadBytes() ([]byte, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorText && major != majorBytes {
+		return nil, ErrTypeMismatch
+	}
+	return r.bytes(n)
+}
+
+func (r *reader) container(want byte) (int, error) {
+	major, _, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	if major != want {
+		return 0, ErrTypeMismatch
+	}
+	if n > math.MaxInt32 {
+		return 0, ErrOverflow
+	}
+	return int(n), nil
+}
+
+func (r *reader) ReadArrayHeader() (int, error) {
+	return r.container(majorArray)
+}
+
+func (r *reader) ReadMapHeader() (int, error) {
+	return r.container(majorMap)
+}
+
+func (r *reader) ReadAny() (any, error) {
+	major, info, n, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegint:
+		if n > math.MaxInt64 {
+			return nil, ErrOverflow
+		}
+		return ^int64(n), nil
+	case majorBytes:
+		return r.bytes(n)
+	case majorText:
+		b, err := r.bytes(n)
+		return string(b), err
+	case majorArray, majorMap:
+		if n > math.MaxInt32 {
+			return nil, ErrOverflow
+		}
+		if major == majorArray {
+			return gensenc.ArrayHeader(n), nil
+		}
+		return gensenc.MapHeader(n), nil
+	case majorSimple:
+		switch info {
+		case simpleFloat16:
+			return float16(uint16(n)), nil
+		case simpleFloat32:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case simpleFloat64:
+			return math.Float64frombits(n), nil
+		}
+		switch n {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull, simpleUndefined:
+			return nil, nil
+		}
+	}
+	return nil, ErrTypeMismatch
+}
+
+// Skip skips the next data item. The items of arrays and maps are counted
+// rather than skipped recursively, so that deeply nested input can't
+// overflow the stack.
+func (r *reader) Skip() error {
+	for left, top := uint64(1), true; left > 0; left, top = left-1, false {
+		items, err := r.skipItem()
+		if err != nil {
+			if top {
+				return err
+			}
+			return unexpected(err)
+		}
+		left += items
+	}
+	return nil
+}
+
+// skipItem skips the next data item but for the items of arrays and maps,
+// the number of which it returns.
+func (r *reader) skipItem() (uint64, error) {
+	major, info, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	switch major {
+	case majorBytes, majorText:
+		m, err := io.CopyN(io.Discard, r.r, int64(n))
+		if uint64(m) < n {
+			return 0, unexpected(err)
+		}
+	case majorArray:
+		return n, nil
+	case majorMap:
+		return 2 * n, nil
+	case majorSimple:
+		if info == 24 && n < 32 {
+			return 0, ErrTypeMismatch
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,180 @@
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/cbor"
+)
+
+// TestWriter checks the writer against the examples of RFC 8949, appendix A,
+// but for floats, which are always written at the precision given.
+func TestWriter(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		write func(gensenc.FormatWriter) error
+		want  string
+	}{
+		{"null", func(w gensenc.FormatWriter) error { return w.WriteNil() }, "f6"},
+		{"false", func(w gensenc.FormatWriter) error { return w.WriteBool(false) }, "f4"},
+		{"true", func(w gensenc.FormatWriter) error { return w.WriteBool(true) }, "f5"},
+		{"0", func(w gensenc.FormatWriter) error { return w.WriteInt(0) }, "00"},
+		{"23", func(w gensenc.FormatWriter) error { return w.WriteInt(23) }, "17"},
+		{"24", func(w gensenc.FormatWriter) error { return w.WriteInt(24) }, "1818"},
+		{"1000", func(w gensenc.FormatWriter) error { return w.WriteInt(1000) }, "1903e8"},
+		{"1000000", func(w gensenc.FormatWriter) error { return w.WriteInt(1000000) }, "1a000f4240"},
+		{"max uint64", func(w gensenc.FormatWriter) error { return w.WriteUint(math.MaxUint64) }, "1bffffffffffffffff"},
+		{"-1", func(w gensenc.FormatWriter) error { return w.WriteInt(-1) }, "20"},
+		{"-1000", func(w gensenc.FormatWriter) error { return w.WriteInt(-1000) }, "3903e7"},
+		{"min int64", func(w gensenc.FormatWriter) error { return w.WriteInt(math.MinInt64) }, "3b7fffffffffffffff"},
+		{"float32", func(w gensenc.FormatWriter) error { return w.WriteFloat32(100000) }, "fa47c35000"},
+		{"float64", func(w gensenc.FormatWriter) error { return w.WriteFloat64(1.1) }, "fb3ff199999999999a"},
+		{"text", func(w gensenc.FormatWriter) error { return w.WriteString("IETF") }, "6449455446"},
+		{"bytes", func(w gensenc.FormatWriter) error { return w.WriteBytes([]byte{1, 2, 3, 4}) }, "4401020304"},
+		{"array", func(w gensenc.FormatWriter) error { return w.WriteArrayHeader(25) }, "9819"},
+		{"map", func(w gensenc.FormatWriter) error { return w.WriteMapHeader(2) }, "a2"},
+	} {
+		var buf bytes.Buffer
+		if err := tt.write(cbor.Format.NewWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+			t.Errorf("%s: wrote %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestReadAny reads examples of RFC 8949, appendix A, among them half
+// precision floats, undefined and tags, which are never written.
+func TestReadAny(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want any
+	}{
+		{"f6", nil},
+		{"f7", nil},
+		{"f5", true},
+		{"1903e8", int64(1000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"3903e7", int64(-1000)},
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"f9c400", -4.0},
+		{"f90001", 5.960464477539063e-8},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"6449455446", "IETF"},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+		{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", "http://www.example.com"},
+		{"83", gensenc.ArrayHeader(3)},
+		{"b90100", gensenc.MapHeader(256)},
+	} {
+		b, _ := hex.DecodeString(tt.in)
+		got, err := cbor.Format.NewReader(bytes.NewReader(b)).ReadAny()
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: read %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+	b, _ := hex.DecodeString("f97e00")
+	f, err := cbor.Format.NewReader(bytes.NewReader(b)).ReadFloat64()
+	if err != nil || !math.IsNaN(f) {
+		t.Errorf("read %v, %v from a half precision NaN", f, err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	reader := func(in string) gensenc.FormatReader {
+		b, _ := hex.DecodeString(in)
+		return cbor.Format.NewReader(bytes.NewReader(b))
+	}
+	if _, err := reader("6449").ReadString(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading truncated text gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := reader("19").ReadInt(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated head gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := reader("").ReadInt(); err != io.EOF {
+		t.Errorf("reading no input gave %v, want io.EOF", err)
+	}
+	if _, err := reader("6449455446").ReadInt(); !errors.Is(err, cbor.ErrTypeMismatch) {
+		t.Errorf("reading text as an integer gave %v, want ErrTypeMismatch", err)
+	}
+	if _, err := reader("1c").ReadAny(); !errors.Is(err, cbor.ErrTypeMismatch) {
+		t.Errorf("reading the reserved info 28 gave %v, want ErrTypeMismatch", err)
+	}
+	if _, err := reader("20").ReadUint(); !errors.Is(err, cbor.ErrOverflow) {
+		t.Errorf("reading -1 as unsigned gave %v, want ErrOverflow", err)
+	}
+	if _, err := reader("1bffffffffffffffff").ReadInt(); !errors.Is(err, cbor.ErrOverflow) {
+		t.Errorf("reading MaxUint64 as signed gave %v, want ErrOverflow", err)
+	}
+	if _, err := reader("3bffffffffffffffff").ReadAny(); !errors.Is(err, cbor.ErrOverflow) {
+		t.Errorf("reading -2^64 gave %v, want ErrOverflow", err)
+	}
+	if _, err := reader("9f01ff").ReadArrayHeader(); !errors.Is(err, cbor.ErrIndefinite) {
+		t.Errorf("reading an indefinite-length array gave %v, want ErrIndefinite", err)
+	}
+	if err := reader("8201").Skip(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("skipping a truncated array gave %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// {"a": [1, [2, 3]], "b": tag 1(1.5)}, then 1.
+	b, _ := hex.DecodeString("a2" + "6161" + "8201820203" + "6162" + "c1f93e00" + "01")
+	r := cbor.Format.NewReader(bytes.NewReader(b))
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.ReadInt(); err != nil || n != 1 {
+		t.Errorf("read %d, %v after the skipped item, want 1", n, err)
+	}
+	deep := append(bytes.Repeat([]byte{0x81}, 1_000_000), 0xf6)
+	if err := cbor.Format.NewReader(bytes.NewReader(deep)).Skip(); err != nil {
+		t.Errorf("skipping deeply nested arrays: %v", err)
+	}
+}
+
+type record struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Attrs map[string]float64
+	Data  []byte
+	Next  *record
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := record{
+		ID: -1, Name: "a", Tags: []string{"x"}, Attrs: map[string]float64{"k": 0.5}, Data: []byte{1},
+		Next: &record{ID: 2, Tags: []string{"y", ""}, Attrs: map[string]float64{}, Data: []byte{2}},
+	}
+	b, err := gensenc.EncodeFormat(cbor.Format, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Structs are maps from field names.
+	if !bytes.HasPrefix(b, []byte("\xa6\x62ID\x20")) {
+		t.Errorf("encoded as %x", b)
+	}
+	var got record
+	err = gensenc.DecodeFormat(cbor.Format, b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+}
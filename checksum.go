@@ -0,0 +1,78 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/crc64"
+)
+
+var ErrChecksumMismatch error = errors.New("checksum mismatch")
+
+type Checksum int
+
+const (
+	CRC32 Checksum = iota
+	CRC64
+)
+
+var (
+	crc32Table = crc32.MakeTable(crc32.Castagnoli)
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+)
+
+func (c Checksum) size() int {
+	if c == CRC64 {
+		return 8
+	}
+	return 4
+}
+
+func (c Checksum) sum(b []byte) uint64 {
+	if c == CRC64 {
+		return crc64.Checksum(b, crc64Table)
+	}
+	return uint64(crc32.Checksum(b, crc32Table))
+}
+
+func (c Checksum) append(b []byte) []byte {
+	if c == CRC64 {
+		return binary.LittleEndian.AppendUint64(b, c.sum(b))
+	}
+	return binary.LittleEndian.AppendUint32(b, uint32(c.sum(b)))
+}
+
+func (c Checksum) verify(b []byte) ([]byte, error) {
+	if len(b) < c.size() {
+		return nil, ErrChecksumMismatch
+	}
+	payload, tail := b[:len(b)-c.size()], b[len(b)-c.size():]
+	var want uint64
+	if c == CRC64 {
+		want = binary.LittleEndian.Uint64(tail)
+	} else {
+		want = uint64(binary.LittleEndian.Uint32(tail))
+	}
+	if c.sum(payload) != want {
+		return nil, ErrChecksumMismatch
+	}
+	return payload, nil
+}
+
+// EncodeChecked encodes a and appends a little-endian checksum of the
+// encoded bytes.
+func EncodeChecked(a any, c Checksum) ([]byte, error) {
+	b, err := Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	return c.append(b), nil
+}
+
+func DecodeChecked(b []byte, a any, c Checksum) error {
+	payload, err := c.verify(b)
+	if err != nil {
+		return err
+	}
+	return Decode(payload, a)
+}
@@ -0,0 +1,103 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/crc64"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var checksums = []struct {
+	name     string
+	checksum gensenc.Checksum
+	size     int
+}{
+	{"crc32", gensenc.CRC32, 4},
+	{"crc64", gensenc.CRC64, 8},
+}
+
+func TestChecked(t *testing.T) {
+	v := order{ID: 7, Items: []string{"a", "b"}, Tags: map[string]int{"x": 1}}
+	plain, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range checksums {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := gensenc.EncodeChecked(v, c.checksum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) != len(plain)+c.size {
+				t.Fatalf("encoded %d bytes, want %d", len(b), len(plain)+c.size)
+			}
+			var want uint64
+			if c.checksum == gensenc.CRC64 {
+				want = crc64.Checksum(plain, crc64.MakeTable(crc64.ECMA))
+				if got := binary.LittleEndian.Uint64(b[len(plain):]); got != want {
+					t.Errorf("checksum %x, want %x", got, want)
+				}
+			} else {
+				want = uint64(crc32.Checksum(plain, crc32.MakeTable(crc32.Castagnoli)))
+				if got := binary.LittleEndian.Uint32(b[len(plain):]); uint64(got) != want {
+					t.Errorf("checksum %x, want %x", got, want)
+				}
+			}
+			var got order
+			err = gensenc.DecodeChecked(b, &got, c.checksum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("got %+v, want %+v", got, v)
+			}
+		})
+	}
+}
+
+// TestCheckedTampered changes every byte of checked encodings in turn,
+// payload and checksum alike, which must be detected.
+func TestCheckedTampered(t *testing.T) {
+	for _, c := range checksums {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := gensenc.EncodeChecked(order{ID: 1, Items: []string{"item"}}, c.checksum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range b {
+				tampered := append([]byte(nil), b...)
+				tampered[i] ^= 0x40
+				var got order
+				err := gensenc.DecodeChecked(tampered, &got, c.checksum)
+				if !errors.Is(err, gensenc.ErrChecksumMismatch) {
+					t.Fatalf("changing byte %d gave %v, want ErrChecksumMismatch", i, err)
+				}
+			}
+			for _, short := range [][]byte{nil, b[:c.size-1], b[:len(b)-1]} {
+				var got order
+				err := gensenc.DecodeChecked(short, &got, c.checksum)
+				if !errors.Is(err, gensenc.ErrChecksumMismatch) {
+					t.Errorf("decoding %d of %d bytes gave %v, want ErrChecksumMismatch", len(short), len(b), err)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckedWrongChecksum decodes with another checksum than the value
+// was encoded with.
+func TestCheckedWrongChecksum(t *testing.T) {
+	b, err := gensenc.EncodeChecked(order{ID: 1}, gensenc.CRC64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	err = gensenc.DecodeChecked(b, &got, gensenc.CRC32)
+	if !errors.Is(err, gensenc.ErrChecksumMismatch) {
+		t.Errorf("decoding CRC64 as CRC32 gave %v, want ErrChecksumMismatch", err)
+	}
+}
@@ -0,0 +1,115 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+)
+
+// A ChunkWriter writes a slice whose length is not known up front as a
+// series of segments, each holding an element count followed by that many
+// elements, terminated by an empty segment. Elements are written like those
+// of a slice, so nil pointers among them decode as nil. Use DecodeChunked to
+// read it back.
+type ChunkWriter struct {
+	w     io.Writer
+	e     encodeState
+	n     int
+	limit int
+}
+
+// NewChunkWriter returns a ChunkWriter that buffers at most chunkSize
+// elements before writing a segment to w.
+func NewChunkWriter(w io.Writer, chunkSize int) *ChunkWriter {
+	return &ChunkWriter{w: w, e: *defaultCodec.newEncodeState(), limit: max(chunkSize, 1)}
+}
+
+func (c *ChunkWriter) Append(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		rv = addressable(rv)
+	}
+	err := c.e.encodeElem(rv)
+	if err != nil {
+		return err
+	}
+	c.n++
+	if c.n >= c.limit {
+		return c.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered elements as a segment.
+func (c *ChunkWriter) Flush() error {
+	if c.n == 0 {
+		return nil
+	}
+	var l [8]byte
+	binary.LittleEndian.PutUint64(l[:], uint64(c.n))
+	_, err := c.w.Write(l[:])
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(c.e.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	c.e.buf.Reset()
+	c.n = 0
+	return nil
+}
+
+// Close flushes the buffered elements and writes the terminating segment.
+// It does not close the underlying writer.
+func (c *ChunkWriter) Close() error {
+	err := c.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(make([]byte, 8))
+	return err
+}
+
+// DecodeChunked reads segments written by a ChunkWriter and appends their
+// elements to the slice a points to.
+func DecodeChunked(r io.Reader, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Slice {
+		return ErrCantSet
+	}
+	v = v.Elem()
+	d := defaultCodec.newDecodeState(nil, r)
+	size := v.Type().Elem().Size()
+	step := max(1, growStep/max(1, int(size)))
+	return d.guard(func() error {
+		for {
+			length, err := d.readUint64()
+			if err == nil {
+				err = d.checkLength(length, 1)
+			}
+			if err == nil {
+				err = d.charge(length, size)
+			}
+			if err != nil {
+				return err
+			}
+			if length == 0 {
+				return nil
+			}
+			// The count is not trusted, so v grows in bounded steps as
+			// elements arrive.
+			for range length {
+				n := v.Len()
+				if n == v.Cap() {
+					v.Grow(step)
+				}
+				v.SetLen(n + 1)
+				err = d.decode(v.Index(n))
+				if err != nil {
+					return d.at(err, index(n))
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,19 @@
+// Command gensenc-vectors writes the gensenc conformance corpus to a
+// directory. See package testvectors for the layout.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/CodeSpoof/gogenericencoder/testvectors"
+)
+
+func main() {
+	out := flag.String("out", "testdata/vectors", "output directory")
+	flag.Parse()
+	err := testvectors.Write(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
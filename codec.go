@@ -0,0 +1,323 @@
+package gensenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+var ErrLimitExceeded error = errors.New("length exceeds limit")
+
+// options holds the configuration of a Codec, which the states encoding
+// and decoding with it embed.
+type options struct {
+	// arrayLens precedes arrays with their length.
+	arrayLens bool
+	// truncate stores integers that don't fit their destination truncated
+	// instead of failing with ErrOverflow.
+	truncate bool
+	// intern enables string references.
+	intern    bool
+	bigEndian bool
+	// varint writes integers as varints.
+	varint    bool
+	canonical bool
+	strict    bool
+	// mergeMaps decodes map entries into the existing map.
+	mergeMaps bool
+	metrics   bool
+	unknown   UnknownPolicy
+	// named writes struct fields with their names, or hashes of them if
+	// hashNames is set.
+	named     bool
+	hashNames bool
+	secrets   bool
+	// maxLength, if positive, bounds every length prefix.
+	maxLength int
+	// maxDecoded, if positive, bounds the memory decoded per value.
+	maxDecoded int
+	// maxDepth, if positive, replaces defaultMaxDepth.
+	maxDepth  int
+	observer  EventObserver
+	allocator Allocator
+}
+
+func (o *options) order() binary.ByteOrder {
+	if o.bigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// raw reports whether values described by info can be written as raw
+// memory with the options of o.
+func (o *options) raw(info *typeInfo) bool {
+	return info.memLayout && o.observer == nil && !o.bigEndian && !(o.arrayLens && info.arrays) && !(o.varint && info.integers) &&
+		!(o.named && info.structs)
+}
+
+// size returns the wire size of values described by info with the options
+// of o, or -1 if it depends on the value.
+func (o *options) size(info *typeInfo) int {
+	if o.arrayLens && info.arrays || o.varint && info.integers || o.named && info.structs {
+		return -1
+	}
+	return info.size
+}
+
+// minSize returns a lower bound for the wire size of values of t with the
+// options of o.
+func (o *options) minSize(t reflect.Type) int {
+	if o.varint || o.named {
+		return min(minWireSize(t), 1)
+	}
+	return minWireSize(t)
+}
+
+// An Option configures a Codec.
+type Option func(*options)
+
+// WithArrayLengths precedes every array with its length, like a slice.
+// Arrays of a different length than the destination are truncated or
+// padded with zero values when decoding, so array sizes can change between
+// versions.
+func WithArrayLengths() Option {
+	return func(o *options) { o.arrayLens = true }
+}
+
+// WithTruncateIntegers stores decoded integers too large for their
+// destination type truncated instead of failing with ErrOverflow.
+func WithTruncateIntegers() Option {
+	return func(o *options) { o.truncate = true }
+}
+
+// WithInterning writes repeated strings within a value as references to
+// their first occurrence, like EncodeInterned.
+func WithInterning() Option {
+	return func(o *options) { o.intern = true }
+}
+
+// WithBigEndian writes integers, floats and length prefixes in big-endian
+// byte order instead of little-endian.
+func WithBigEndian() Option {
+	return func(o *options) { o.bigEndian = true }
+}
+
+// WithVarints writes integers as varints, zigzag encoded if signed, so
+// small values take a byte or two. Length prefixes keep their fixed size.
+func WithVarints() Option {
+	return func(o *options) { o.varint = true }
+}
+
+// WithMaxLength makes decoding fail with ErrLimitExceeded for strings,
+// slices and maps longer than n.
+func WithMaxLength(n int) Option {
+	return func(o *options) { o.maxLength = n }
+}
+
+// WithMaxDecodedBytes makes decoding fail with ErrLimitExceeded once the
+// strings, slices and maps of a value take more than n bytes of memory in
+// total, counting their elements by their size in memory, so that many
+// medium-sized ones can't exhaust memory either.
+func WithMaxDecodedBytes(n int) Option {
+	return func(o *options) { o.maxDecoded = n }
+}
+
+// WithCanonical writes map entries sorted by their encoded key, so that
+// equal values always encode to the same bytes.
+func WithCanonical() Option {
+	return func(o *options) { o.canonical = true }
+}
+
+// WithStrict makes decoding reject input that Decode tolerates: bools
+// other than 0 and 1 and, for Codec.Decode, bytes after the value.
+func WithStrict() Option {
+	return func(o *options) { o.strict = true }
+}
+
+// WithMergeMaps makes decoding add the decoded entries to maps that already
+// exist instead of replacing their content, so that a map can be updated by
+// decoding only the entries that changed. Entries already present are
+// decoded into their current value, which merges nested maps the same way.
+func WithMergeMaps() Option {
+	return func(o *options) { o.mergeMaps = true }
+}
+
+// A Codec encodes and decodes values with a fixed configuration. Values
+// must be decoded by a Codec configured like the one that encoded them.
+// Encode and Decode use a Codec with the default configuration.
+//
+// A Codec is safe for concurrent use by multiple goroutines; its
+// configuration can't change after New. The Encoders and Decoders it
+// creates are not.
+type Codec struct {
+	opts options
+	// states holds encode states for reuse, to save growing a buffer for
+	// every value.
+	states sync.Pool
+	// metrics, if not nil, counts what the Codec encodes and decodes.
+	metrics *metrics
+}
+
+func New(opts ...Option) *Codec {
+	c := &Codec{}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	if c.opts.metrics {
+		c.metrics = &metrics{}
+	}
+	return c
+}
+
+var defaultCodec = New()
+
+// maxPooledBuffer bounds the buffers kept for reuse, so that one large
+// value doesn't pin its memory.
+const maxPooledBuffer = 64 << 10
+
+func (c *Codec) newEncodeState() *encodeState {
+	e := &encodeState{buf: bytes.NewBuffer(nil), options: c.opts}
+	if c.opts.intern {
+		e.strings = map[string]uint64{}
+	}
+	return e
+}
+
+func (c *Codec) newDecodeState(b []byte, r io.Reader) *decodeState {
+	return &decodeState{b: b, r: r, options: c.opts}
+}
+
+// encode encodes the top-level value v with e, a state of c, counting it in
+// the metrics of c.
+func (c *Codec) encode(e *encodeState, v reflect.Value) error {
+	c.metrics.plan(v)
+	start := e.buf.Len()
+	err := e.encodeRoot(v)
+	c.metrics.encode(e.buf.Len()-start, err)
+	return err
+}
+
+// decode is like encode for decoding.
+func (c *Codec) decode(d *decodeState, v reflect.Value) error {
+	c.metrics.plan(v)
+	start := d.consumed()
+	err := d.decodeRoot(v)
+	c.metrics.decode(d.consumed()-start, err)
+	return err
+}
+
+func (c *Codec) Encode(a any) ([]byte, error) {
+	return c.EncodeValue(addressable(reflect.ValueOf(a)))
+}
+
+func (c *Codec) EncodeValue(v reflect.Value) ([]byte, error) {
+	e := c.getState()
+	defer c.putState(e)
+	err := c.encode(e, v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(e.buf.Bytes()), nil
+}
+
+// EncodeValueTo is like the package-level EncodeValueTo.
+func (c *Codec) EncodeValueTo(w io.Writer, v reflect.Value) (int, error) {
+	e := c.getState()
+	defer c.putState(e)
+	err := c.encode(e, v)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(e.buf.Bytes())
+}
+
+func (c *Codec) getState() *encodeState {
+	e, ok := c.states.Get().(*encodeState)
+	if !ok {
+		e = c.newEncodeState()
+	}
+	return e
+}
+
+func (c *Codec) putState(e *encodeState) {
+	if e.secrets {
+		e.scrub()
+	}
+	if e.buf.Cap() <= maxPooledBuffer {
+		e.buf.Reset()
+		clear(e.strings)
+		c.states.Put(e)
+	}
+}
+
+func (c *Codec) Decode(b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	c.metrics.plan(v)
+	d := c.newDecodeState(b, nil)
+	err := d.decodeRoot(v)
+	if err == nil && c.opts.strict && d.off != len(b) {
+		err = &DecodeError{Err: ErrTrailingBytes, Offset: int64(d.off)}
+	}
+	c.metrics.decode(int64(d.off), err)
+	return err
+}
+
+// DecodeValue is like the package-level DecodeValue.
+func (c *Codec) DecodeValue(r io.Reader, v reflect.Value) error {
+	_, err := c.DecodeValueN(r, v)
+	return err
+}
+
+// DecodeValueN is like the package-level DecodeValueN.
+func (c *Codec) DecodeValueN(r io.Reader, v reflect.Value) (int64, error) {
+	d := c.newDecodeState(nil, r)
+	err := c.decode(d, v)
+	return d.n, err
+}
+
+// NewEncoder returns an Encoder writing values to w with the
+// configuration of c.
+func (c *Codec) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, e: *c.newEncodeState()}
+}
+
+// NewDecoder returns a Decoder reading values from r with the
+// configuration of c.
+func (c *Codec) NewDecoder(r io.Reader) *Decoder {
+	dec := NewDecoder(r)
+	dec.d.options = c.opts
+	return dec
+}
+
+// sortKeys sorts map keys by their encoding for canonical output. Keys are
+// encoded without string references, which would depend on the order.
+func (e *encodeState) sortKeys(keys []reflect.Value) error {
+	k := &encodeState{buf: bytes.NewBuffer(nil), options: e.options}
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		k.buf.Reset()
+		err := k.encode(key)
+		if err != nil {
+			return err
+		}
+		encoded[i] = bytes.Clone(k.buf.Bytes())
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return bytes.Compare(encoded[i], encoded[j])
+	})
+	sorted := make([]reflect.Value, len(keys))
+	for i, j := range order {
+		sorted[i] = keys[j]
+	}
+	copy(keys, sorted)
+	return nil
+}
@@ -0,0 +1,211 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type order struct {
+	ID    uint64
+	Items []string
+	Tags  map[string]int
+	Note  *string
+}
+
+// TestCodecConcurrent uses one Codec from many goroutines at once. Run it
+// with -race.
+func TestCodecConcurrent(t *testing.T) {
+	c := gensenc.New(gensenc.WithMetrics(), gensenc.WithInterning())
+	const goroutines, rounds = 16, 200
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			note := "note"
+			for i := range rounds {
+				v := order{
+					ID:    uint64(g*rounds + i),
+					Items: []string{"a", "b", "a"},
+					Tags:  map[string]int{"x": i},
+					Note:  &note,
+				}
+				b, err := c.Encode(v)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				var got order
+				err = c.Decode(b, &got)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !reflect.DeepEqual(got, v) {
+					t.Errorf("got %+v, want %+v", got, v)
+					return
+				}
+
+				var buf bytes.Buffer
+				err = c.NewEncoder(&buf).Encode(v)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				got = order{}
+				err = c.NewDecoder(&buf).Decode(&got)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if c.Decode(b[:len(b)/2], &got) == nil {
+					t.Error("decoding truncated input succeeded")
+					return
+				}
+				_ = c.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := c.Stats()
+	if s.Encoded != goroutines*rounds || s.Decoded != goroutines*rounds {
+		t.Errorf("counted %d encoded and %d decoded values, want %d each", s.Encoded, s.Decoded, goroutines*rounds)
+	}
+	if n := s.Errors[gensenc.ErrTruncated.Error()]; n != goroutines*rounds {
+		t.Errorf("counted %d truncated inputs, want %d", n, goroutines*rounds)
+	}
+}
+
+// TestCodecMetricsEntryPoints checks that the entry points of a Codec other
+// than Encode and Decode are counted too.
+func TestCodecMetricsEntryPoints(t *testing.T) {
+	c := gensenc.New(gensenc.WithMetrics())
+	v := order{ID: 1, Items: []string{"a"}}
+	var buf bytes.Buffer
+	_, err := c.EncodeValueTo(&buf, reflect.ValueOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	err = c.DecodeRaw(gensenc.Raw(buf.Bytes()), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.DecodeValueN(bytes.NewReader(buf.Bytes()[:3]), reflect.ValueOf(&got))
+	if err == nil {
+		t.Fatal("decoding truncated input succeeded")
+	}
+	s := c.Stats()
+	if s.Encoded != 1 || s.Decoded != 1 || s.DecodedBytes != int64(buf.Len()) {
+		t.Errorf("counted %d encoded and %d decoded values of %d bytes, want 1, 1 and %d", s.Encoded, s.Decoded, s.DecodedBytes, buf.Len())
+	}
+	if n := s.Errors[gensenc.ErrTruncated.Error()]; n != 1 {
+		t.Errorf("counted %d truncated inputs, want 1", n)
+	}
+}
+
+type (
+	raceLeaf struct {
+		Name string
+		Tags map[string]int
+	}
+	raceTree struct {
+		Leaves []raceLeaf
+		Next   *raceTree
+		Any    any
+	}
+)
+
+// TestCodecConcurrentFirstUse analyzes a type from many goroutines at once,
+// each with a Codec of its own configuration and some through the
+// package-level functions sharing the default Codec, while they register
+// types for interface values. Run it with -race.
+func TestCodecConcurrentFirstUse(t *testing.T) {
+	codecs := []*gensenc.Codec{
+		gensenc.New(),
+		gensenc.New(gensenc.WithVarints()),
+		gensenc.New(gensenc.WithCanonical(), gensenc.WithInterning()),
+		gensenc.New(gensenc.WithBigEndian(), gensenc.WithMetrics()),
+	}
+	gensenc.Register(raceLeaf{})
+	v := raceTree{
+		Leaves: []raceLeaf{{"a", map[string]int{"x": 1, "y": 2}}, {"b", map[string]int{}}},
+		Next:   &raceTree{Any: raceLeaf{"c", map[string]int{"z": 3}}},
+		Any:    "s",
+	}
+	const goroutines, rounds = 16, 50
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := codecs[g%len(codecs)]
+			for range rounds {
+				var got raceTree
+				b, err := c.Encode(v)
+				if err == nil {
+					err = c.Decode(b, &got)
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !reflect.DeepEqual(got, v) {
+					t.Errorf("got %+v, want %+v", got, v)
+					return
+				}
+				b, err = gensenc.EncodeBatch([]raceTree{v, {}})
+				if err == nil {
+					_, err = gensenc.DecodeBatch[raceTree](b)
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestCodecConcurrentEncoders uses Encoders and Decoders created by one
+// Codec from many goroutines, each using its own. Run it with -race.
+func TestCodecConcurrentEncoders(t *testing.T) {
+	c := gensenc.New(gensenc.WithInterning())
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			enc := c.NewEncoder(&buf)
+			for i := range 100 {
+				err := enc.Encode(order{ID: uint64(g*100 + i), Items: []string{"a", "b", "a"}})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			dec := c.NewDecoder(&buf)
+			for i := range 100 {
+				var got order
+				err := dec.Decode(&got)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got.ID != uint64(g*100+i) || len(got.Items) != 3 {
+					t.Errorf("decoded %+v as value %d", got, i)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,63 @@
+package gensenc
+
+import (
+	"reflect"
+)
+
+// columnar reports whether slices with elements of type t are laid out
+// column by column in columnar mode.
+func columnar(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && infoOf(t).codec == nil
+}
+
+func (e *encodeState) encodeColumns(v reflect.Value) error {
+	for _, f := range infoOf(v.Type().Elem()).fields {
+		for i := 0; i < v.Len(); i++ {
+			err := e.tick()
+			if err != nil {
+				return err
+			}
+			err = e.encodeField(&f, v.Index(i).Field(f.index))
+			if err != nil {
+				return e.at(err, index(i)+"."+f.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) decodeColumns(v reflect.Value) error {
+	for _, f := range infoOf(v.Type().Elem()).fields {
+		for i := 0; i < v.Len(); i++ {
+			err := d.tick()
+			if err != nil {
+				return err
+			}
+			err = d.decodeField(&f, v.Index(i).Field(f.index))
+			if err != nil {
+				return d.at(err, index(i)+"."+f.name)
+			}
+		}
+	}
+	return nil
+}
+
+// EncodeColumnar is like Encode but lays out every slice of structs column
+// by column: the slice length, then the first field of every element,
+// then the second field of every element and so on. The result must be
+// decoded with DecodeColumnar.
+func EncodeColumnar(a any) ([]byte, error) {
+	e := defaultCodec.newEncodeState()
+	e.columnar = true
+	err := defaultCodec.encode(e, addressable(reflect.ValueOf(a)))
+	if err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+func DecodeColumnar(b []byte, a any) error {
+	d := defaultCodec.newDecodeState(b, nil)
+	d.columnar = true
+	return defaultCodec.decode(d, reflect.ValueOf(a))
+}
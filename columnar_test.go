@@ -0,0 +1,80 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type point struct {
+	X, Y int32
+}
+
+type track struct {
+	Name   string
+	Points []point
+	Nested [][]point
+}
+
+func TestColumnar(t *testing.T) {
+	v := track{
+		Name:   "t",
+		Points: []point{{1, 2}, {3, 4}, {5, 6}},
+		Nested: [][]point{{{7, 8}}, {{9, 10}, {11, 12}}},
+	}
+	b, err := gensenc.EncodeColumnar(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The points follow the name and the slice length, X values first,
+	// each written as 8 bytes like all integers.
+	off := 8 + len(v.Name) + 8
+	var xs []int32
+	for i := range v.Points {
+		xs = append(xs, int32(binary.LittleEndian.Uint64(b[off+8*i:])))
+	}
+	if want := []int32{1, 3, 5}; !reflect.DeepEqual(xs, want) {
+		t.Errorf("first column %v, want %v", xs, want)
+	}
+	var got track
+	err = gensenc.DecodeColumnar(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	// The layouts differ, so decoding with Decode doesn't give v back.
+	got = track{}
+	if gensenc.Decode(b, &got) == nil && reflect.DeepEqual(got, v) {
+		t.Error("Decode read a columnar encoding like a plain one")
+	}
+}
+
+func TestColumnarTruncated(t *testing.T) {
+	name := strings.Repeat("n", 20)
+	b, err := gensenc.EncodeColumnar([]track{{Name: name}, {Name: name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The input ends within the length of the second Points, the last but
+	// one column.
+	var got []track
+	err = gensenc.DecodeColumnar(b[:len(b)-17], &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTruncated) {
+		t.Fatalf("decoding truncated input gave %v, want ErrTruncated", err)
+	}
+	if de.Path != "[1].Points" {
+		t.Errorf("error at %q, want [1].Points", de.Path)
+	}
+	// Too little is left for the elements the slice length announces.
+	err = gensenc.DecodeColumnar(b[:30], &got)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a slice longer than the input gave %v, want ErrInvalidLength", err)
+	}
+}
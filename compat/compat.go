@@ -0,0 +1,180 @@
+// Package compat checks that the gensenc wire format doesn't change by
+// accident. A Corpus of sample values is recorded once, typically into a
+// testdata directory committed with the code, and verified against the
+// current encoder and decoder in later tests:
+//
+//	var corpus compat.Corpus
+//	corpus.Add("order", Order{ID: 1, Items: []string{"a"}})
+//	err := corpus.Verify("testdata/compat") // or Record, to update it
+//
+// Each sample is stored as <name>.bin holding its encoding.
+package compat
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var (
+	ErrEncodingChanged error = errors.New("encoding differs from the recorded one")
+	ErrDecodingChanged error = errors.New("recorded encoding decodes to a different value")
+)
+
+// A SampleError reports a sample failing verification.
+type SampleError struct {
+	Name string
+	Err  error
+}
+
+func (e *SampleError) Error() string {
+	return "compat: sample " + e.Name + ": " + e.Err.Error()
+}
+
+func (e *SampleError) Unwrap() error {
+	return e.Err
+}
+
+type sample struct {
+	name  string
+	value any
+}
+
+// A Corpus is a set of named sample values. The zero value is an empty
+// corpus using the default configuration.
+type Corpus struct {
+	// Codec, if not nil, encodes and decodes the samples. Samples holding
+	// maps of more than one entry need one created with
+	// gensenc.WithCanonical to encode reproducibly.
+	Codec   *gensenc.Codec
+	samples []sample
+}
+
+// Add adds value to the corpus under name, which must be usable as a file
+// name.
+func (c *Corpus) Add(name string, value any) {
+	c.samples = append(c.samples, sample{name, value})
+}
+
+func (c *Corpus) codec() *gensenc.Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return gensenc.New()
+}
+
+// Record writes the encoding of every sample below dir, creating it if
+// necessary and replacing earlier recordings.
+func (c *Corpus) Record(dir string) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	codec := c.codec()
+	for _, s := range c.samples {
+		b, err := codec.Encode(s.value)
+		if err != nil {
+			return &SampleError{s.name, err}
+		}
+		err = os.WriteFile(filepath.Join(dir, s.name+".bin"), b, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks every sample against its recording below dir: the sample
+// must still encode to the recorded bytes, and the recorded bytes must
+// still decode to the sample. It reports all failing samples as
+// *SampleErrors joined with errors.Join.
+func (c *Corpus) Verify(dir string) error {
+	codec := c.codec()
+	var errs []error
+	for _, s := range c.samples {
+		err := verify(codec, dir, s)
+		if err != nil {
+			errs = append(errs, &SampleError{s.name, err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func verify(codec *gensenc.Codec, dir string, s sample) error {
+	recorded, err := os.ReadFile(filepath.Join(dir, s.name+".bin"))
+	if err != nil {
+		return err
+	}
+	b, err := codec.Encode(s.value)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(b, recorded) {
+		return ErrEncodingChanged
+	}
+	v := reflect.New(reflect.TypeOf(s.value))
+	err = codec.Decode(recorded, v.Interface())
+	if err != nil {
+		return err
+	}
+	if !equal(v.Elem(), reflect.ValueOf(s.value)) {
+		return ErrDecodingChanged
+	}
+	return nil
+}
+
+// equal is like reflect.DeepEqual for values that went through gensenc: it
+// ignores unexported fields and those tagged "-", which aren't encoded, and
+// doesn't distinguish nil from empty slices and maps. Structs without
+// exported fields, such as big.Int, have an encoding of their own and are
+// compared in full.
+func equal(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Struct:
+		if !slices.ContainsFunc(reflect.VisibleFields(a.Type()), func(f reflect.StructField) bool { return f.IsExported() }) {
+			break
+		}
+		for i := range a.NumField() {
+			f := a.Type().Field(i)
+			if f.IsExported() && f.Tag.Get("gensenc") != "-" && !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			e := b.MapIndex(k)
+			if !e.IsValid() || !equal(a.MapIndex(k), e) {
+				return false
+			}
+		}
+		return true
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return equal(a.Elem(), b.Elem())
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
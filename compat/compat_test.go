@@ -0,0 +1,91 @@
+package compat_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/compat"
+)
+
+type order struct {
+	ID     uint64
+	Items  []string
+	Tags   map[string]int
+	hidden int
+	Skip   int `gensenc:"-"`
+}
+
+func corpus() *compat.Corpus {
+	c := &compat.Corpus{Codec: gensenc.New(gensenc.WithCanonical())}
+	// Empty slices decode as nil ones and unencoded fields as zero, which
+	// verification allows for.
+	c.Add("order", order{ID: 1, Items: []string{}, Tags: map[string]int{"a": 1, "b": 2}, hidden: 3, Skip: 4})
+	c.Add("int", 42)
+	c.Add("pointer", &order{ID: 2})
+	return c
+}
+
+func TestRecordVerify(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "compat")
+	c := corpus()
+	if err := c.Record(dir); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "int.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := gensenc.Encode(42); string(b) != string(want) {
+		t.Errorf("recorded %x, want %x", b, want)
+	}
+	if err := c.Verify(dir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	dir := t.TempDir()
+	c := corpus()
+	if err := c.Record(dir); err != nil {
+		t.Fatal(err)
+	}
+	// A changed recording, and a missing one.
+	b, _ := gensenc.Encode(43)
+	if err := os.WriteFile(filepath.Join(dir, "int.bin"), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "pointer.bin")); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Verify(dir)
+	if !errors.Is(err, compat.ErrEncodingChanged) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want ErrEncodingChanged and os.ErrNotExist", err)
+	}
+	var se *compat.SampleError
+	if !errors.As(err, &se) || se.Name != "int" {
+		t.Errorf("first failing sample is %v, want int", se)
+	}
+
+	// Redacted fields encode alike whatever they hold but don't decode to
+	// what they held.
+	type secret struct {
+		Key string `gensenc:"redact"`
+	}
+	var r compat.Corpus
+	r.Add("secret", secret{Key: "k"})
+	if err := r.Record(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Verify(dir); !errors.Is(err, compat.ErrDecodingChanged) {
+		t.Errorf("got %v, want ErrDecodingChanged", err)
+	}
+
+	var u compat.Corpus
+	u.Add("chan", make(chan int))
+	if err := u.Record(dir); !errors.As(err, &se) || se.Name != "chan" || !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("recording a channel gave %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package gensenc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// An Incompatibility is a change between two schemas that keeps values
+// encoded with one from decoding correctly with the other.
+type Incompatibility struct {
+	// Path locates the changed value as in DecodeError, with "[]" for the
+	// elements of slices, arrays and map values and "[key]" for map keys.
+	Path   string
+	Reason string
+}
+
+func (i Incompatibility) String() string {
+	if i.Path == "" {
+		return i.Reason
+	}
+	return i.Path + ": " + i.Reason
+}
+
+// CheckCompatible reports the changes from old to new that break the wire
+// format in either direction. As structs are encoded as their fields in
+// order, adding, removing, renaming and reordering fields all break it, as
+// do changing their tag options, kinds other than between integers of the
+// same signedness, and array lengths. Adding and removing fields at the end
+// of a section, and renaming types, don't. It fails with ErrInvalidSchema
+// if a schema is missing the element or key schema its kind needs.
+func CheckCompatible(old, new Schema) ([]Incompatibility, error) {
+	var found []Incompatibility
+	err := checkCompatible(&old, &new, "", &found)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		found[i].Path = strings.TrimPrefix(found[i].Path, ".")
+	}
+	return found, nil
+}
+
+func integerClass(k reflect.Kind) int {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 1
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 2
+	}
+	return 0
+}
+
+// schemaRuns splits fields into runs of consecutive fields in the same
+// section, and single fields outside of any.
+func schemaRuns(fields []SchemaField) [][]SchemaField {
+	var runs [][]SchemaField
+	last := ""
+	for _, f := range fields {
+		s := parseTag(f.Tag).section()
+		if n := len(runs); n > 0 && s != "" && s == last {
+			runs[n-1] = append(runs[n-1], f)
+		} else {
+			runs = append(runs, []SchemaField{f})
+		}
+		last = s
+	}
+	return runs
+}
+
+func checkField(of, nf *SchemaField, path string, found *[]Incompatibility) error {
+	path += "." + of.Name
+	if of.Name != nf.Name {
+		*found = append(*found, Incompatibility{path, "field renamed or moved to " + nf.Name})
+		return nil
+	}
+	if of.Tag != nf.Tag {
+		*found = append(*found, Incompatibility{path, fmt.Sprintf("tag changed from %q to %q", of.Tag, nf.Tag)})
+		return nil
+	}
+	return checkCompatible(&of.Schema, &nf.Schema, path, found)
+}
+
+func checkCompatible(old, new *Schema, path string, found *[]Incompatibility) error {
+	report := func(format string, args ...any) {
+		*found = append(*found, Incompatibility{path, fmt.Sprintf(format, args...)})
+	}
+	if old.Kind != new.Kind && (integerClass(old.Kind) == 0 || integerClass(old.Kind) != integerClass(new.Kind)) {
+		report("kind changed from %v to %v", old.Kind, new.Kind)
+		return nil
+	}
+	if old.Ref || new.Ref {
+		if old.Ref != new.Ref {
+			report("recursion changed")
+		}
+		return nil
+	}
+	switch old.Kind {
+	case reflect.Struct:
+		oldRuns, newRuns := schemaRuns(old.Fields), schemaRuns(new.Fields)
+		for i := range max(len(oldRuns), len(newRuns)) {
+			var a, b []SchemaField
+			if i < len(oldRuns) {
+				a = oldRuns[i]
+			}
+			if i < len(newRuns) {
+				b = newRuns[i]
+			}
+			// Sections may grow or shrink at the end.
+			section := len(a) > 0 && len(b) > 0 && parseTag(a[0].Tag).section() != ""
+			for j := range max(len(a), len(b)) {
+				switch {
+				case j >= len(a):
+					if !section {
+						report("field %s added", b[j].Name)
+					}
+				case j >= len(b):
+					if !section {
+						report("field %s removed", a[j].Name)
+					}
+				default:
+					err := checkField(&a[j], &b[j], path, found)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	case reflect.Array, reflect.Slice, reflect.Pointer, reflect.Map:
+		if old.Elem == nil || new.Elem == nil {
+			return ErrInvalidSchema
+		}
+		if old.Kind == reflect.Array && old.Len != new.Len {
+			report("array length changed from %d to %d", old.Len, new.Len)
+			return nil
+		}
+		if old.Kind == reflect.Map {
+			if old.Key == nil || new.Key == nil {
+				return ErrInvalidSchema
+			}
+			err := checkCompatible(old.Key, new.Key, path+"[key]", found)
+			if err != nil {
+				return err
+			}
+		}
+		elem := path + "[]"
+		if old.Kind == reflect.Pointer {
+			elem = path
+		}
+		return checkCompatible(old.Elem, new.Elem, elem, found)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	compatOld struct {
+		X int32
+		Y string
+		Z []int
+		M map[string][2]int
+		N *compatOld
+	}
+	compatNew struct {
+		X int64
+		Y []byte
+		Z []uint
+		M map[int][3]int
+		N *compatNew
+		W [2]int
+	}
+	compatSwapped struct {
+		Y string
+		X int32 `gensenc:"varint"`
+	}
+)
+
+func TestCheckCompatible(t *testing.T) {
+	old := gensenc.DescribeType(reflect.TypeFor[compatOld]())
+	found, err := gensenc.CheckCompatible(old, old)
+	if err != nil || len(found) != 0 {
+		t.Errorf("a schema is incompatible with itself: %v, %v", found, err)
+	}
+
+	found, err = gensenc.CheckCompatible(old, gensenc.DescribeType(reflect.TypeFor[compatNew]()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range found {
+		got = append(got, i.String())
+	}
+	want := []string{
+		"Y: kind changed from string to slice",
+		"Z[]: kind changed from int to uint",
+		"M[key]: kind changed from string to int",
+		"M[]: array length changed from 2 to 3",
+		"field W added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	found, err = gensenc.CheckCompatible(
+		gensenc.DescribeType(reflect.TypeFor[compatSwapped]()),
+		gensenc.DescribeType(reflect.TypeFor[struct {
+			Y string
+			X int32
+		}]()))
+	if err != nil || len(found) != 1 || found[0].Path != "X" {
+		t.Errorf("changing a tag gave %v, %v", found, err)
+	}
+	found, err = gensenc.CheckCompatible(
+		gensenc.DescribeType(reflect.TypeFor[compatOld]()),
+		gensenc.DescribeType(reflect.TypeFor[compatSwapped]()))
+	if err != nil || len(found) == 0 || found[0].String() != "X: field renamed or moved to Y" {
+		t.Errorf("swapping fields gave %v, %v", found, err)
+	}
+}
+
+func TestCheckCompatibleInvalid(t *testing.T) {
+	slice := gensenc.Schema{Kind: reflect.Slice}
+	if _, err := gensenc.CheckCompatible(slice, slice); !errors.Is(err, gensenc.ErrInvalidSchema) {
+		t.Errorf("a slice without an element schema gave %v, want ErrInvalidSchema", err)
+	}
+	m := gensenc.Schema{Kind: reflect.Map, Elem: &gensenc.Schema{Kind: reflect.Int}}
+	if _, err := gensenc.CheckCompatible(m, m); !errors.Is(err, gensenc.ErrInvalidSchema) {
+		t.Errorf("a map without a key schema gave %v, want ErrInvalidSchema", err)
+	}
+}
@@ -0,0 +1,178 @@
+package gensenc
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"sync"
+)
+
+var ErrUnknownCompressor error = errors.New("unknown compressor")
+
+// A Compressor wraps streams in a compression format. ID is written as the
+// header byte of compressed payloads and must be unique and non-zero.
+type Compressor interface {
+	ID() byte
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) ID() byte { return 1 }
+
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zlibCompressor struct{}
+
+func (zlibCompressor) ID() byte { return 2 }
+
+func (zlibCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (zlibCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+type flateCompressor struct{}
+
+func (flateCompressor) ID() byte { return 3 }
+
+func (flateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.DefaultCompression)
+}
+
+func (flateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+var (
+	Gzip  Compressor = gzipCompressor{}
+	Zlib  Compressor = zlibCompressor{}
+	Flate Compressor = flateCompressor{}
+)
+
+var compressors = struct {
+	sync.RWMutex
+	byID   map[byte]Compressor
+	byName map[string]Compressor
+}{
+	byID:   map[byte]Compressor{1: Gzip, 2: Zlib, 3: Flate},
+	byName: map[string]Compressor{"gzip": Gzip, "zlib": Zlib, "flate": Flate},
+}
+
+// RegisterCompressor makes c available to DecodeCompressed and under name.
+// It panics if c's ID is zero or already taken, or if name is, as streams
+// of two compressors under one name couldn't be told apart.
+func RegisterCompressor(name string, c Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+	if c.ID() == 0 {
+		panic("gensenc: compressor id 0 is reserved")
+	}
+	if _, ok := compressors.byID[c.ID()]; ok {
+		panic("gensenc: duplicate compressor id")
+	}
+	if _, ok := compressors.byName[name]; ok {
+		panic("gensenc: duplicate compressor name " + name)
+	}
+	compressors.byID[c.ID()] = c
+	compressors.byName[name] = c
+}
+
+func compressorByID(id byte) (Compressor, error) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	c, ok := compressors.byID[id]
+	if !ok {
+		return nil, ErrUnknownCompressor
+	}
+	return c, nil
+}
+
+func compress(c Compressor, b []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(c.ID())
+	w, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maxDecompressed bounds the size of decompressed payloads when no tighter
+// limit applies, so that small inputs can't expand without bound.
+const maxDecompressed = 1 << 30
+
+// decompress decompresses the payload b, failing with ErrLimitExceeded if
+// it would expand past limit bytes.
+func decompress(b []byte, limit int) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if b[0] == 0 {
+		if len(b)-1 > limit {
+			return nil, ErrLimitExceeded
+		}
+		return b[1:], nil
+	}
+	c, err := compressorByID(b[0])
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.NewReader(bytes.NewReader(b[1:]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > limit {
+		return nil, ErrLimitExceeded
+	}
+	return out, nil
+}
+
+// EncodeCompressed encodes a and compresses the result with c, prefixed by
+// a header byte identifying c. A nil c stores the payload uncompressed.
+func EncodeCompressed(c Compressor, a any) ([]byte, error) {
+	b, err := Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return append([]byte{0}, b...), nil
+	}
+	return compress(c, b)
+}
+
+// DecodeCompressed decompresses b, written by EncodeCompressed, and decodes
+// the result into a. Payloads decompressing to more than 1 GiB fail with
+// ErrLimitExceeded.
+func DecodeCompressed(b []byte, a any) error {
+	payload, err := decompress(b, maxDecompressed)
+	if err != nil {
+		return err
+	}
+	return Decode(payload, a)
+}
@@ -0,0 +1,125 @@
+package gensenc_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// identity is a Compressor that stores payloads as they are.
+type identity struct{}
+
+func (identity) ID() byte { return 200 }
+
+func (identity) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopCloser{w}, nil
+}
+
+func (identity) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// other is like identity under another ID.
+type other struct{ identity }
+
+func (other) ID() byte { return 201 }
+
+func init() {
+	gensenc.RegisterCompressor("identity", identity{})
+}
+
+func TestRegisterCompressorDuplicate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		c    gensenc.Compressor
+	}{
+		{"identity", other{}},
+		{"gzip", other{}},
+		{"another", identity{}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %T as %q didn't panic", tt.c, tt.name)
+				}
+			}()
+			gensenc.RegisterCompressor(tt.name, tt.c)
+		}()
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	v := order{ID: 1, Items: []string{strings.Repeat("abc", 1000)}, Tags: map[string]int{"k": 1}}
+	plain, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		c    gensenc.Compressor
+	}{
+		{"none", nil},
+		{"gzip", gensenc.Gzip},
+		{"zlib", gensenc.Zlib},
+		{"flate", gensenc.Flate},
+		{"registered", identity{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gensenc.EncodeCompressed(tt.c, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := byte(0)
+			if tt.c != nil {
+				id = tt.c.ID()
+			}
+			if b[0] != id {
+				t.Errorf("header byte %d, want %d", b[0], id)
+			}
+			switch tt.c {
+			case nil, identity{}:
+				if len(b) != len(plain)+1 {
+					t.Errorf("stored %d bytes as %d", len(plain), len(b))
+				}
+			default:
+				if len(b) >= len(plain)/10 {
+					t.Errorf("compressed %d repetitive bytes to %d", len(plain), len(b))
+				}
+			}
+			var got order
+			err = gensenc.DecodeCompressed(b, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Error("value changed in a round trip")
+			}
+		})
+	}
+}
+
+func TestCompressedErrors(t *testing.T) {
+	b, err := gensenc.EncodeCompressed(gensenc.Gzip, order{ID: 1, Items: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	if err := gensenc.DecodeCompressed(nil, &got); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("decoding no input gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	unknown := append([]byte{99}, b[1:]...)
+	if err := gensenc.DecodeCompressed(unknown, &got); !errors.Is(err, gensenc.ErrUnknownCompressor) {
+		t.Errorf("decoding an unknown compressor gave %v, want ErrUnknownCompressor", err)
+	}
+	if err := gensenc.DecodeCompressed(b[:len(b)-4], &got); err == nil {
+		t.Error("decoding a truncated gzip stream succeeded")
+	}
+}
@@ -0,0 +1,37 @@
+package gensenc
+
+import (
+	"context"
+	"io"
+	"reflect"
+)
+
+// EncodeContext encodes v and writes it to w. The encoding is aborted with
+// ctx's error once ctx is done; cancellation is checked periodically while
+// traversing slices, arrays and maps.
+func EncodeContext(ctx context.Context, w io.Writer, v any) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	e := defaultCodec.newEncodeState()
+	e.ctx = ctx
+	err = defaultCodec.encode(e, addressable(reflect.ValueOf(v)))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(e.buf.Bytes())
+	return err
+}
+
+// DecodeContext decodes one value from r into v like DecodeValue, aborting
+// with ctx's error once ctx is done.
+func DecodeContext(ctx context.Context, r io.Reader, v any) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	d := defaultCodec.newDecodeState(nil, r)
+	d.ctx = ctx
+	return defaultCodec.decode(d, reflect.ValueOf(v))
+}
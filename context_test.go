@@ -0,0 +1,73 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// countdown is a context that is done once Err has been called n times.
+type countdown struct {
+	context.Context
+	n int
+}
+
+func (c *countdown) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestContext(t *testing.T) {
+	v := make([]string, 10000)
+	for i := range v {
+		v[i] = "s"
+	}
+	var buf bytes.Buffer
+	err := gensenc.EncodeContext(context.Background(), &buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = gensenc.DecodeContext(context.Background(), bytes.NewReader(buf.Bytes()), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Error("value changed in a round trip")
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	v := make([]string, 10000)
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := gensenc.EncodeContext(ctx, &buf, v); err != context.Canceled || buf.Len() != 0 {
+		t.Errorf("encoding with a canceled context gave %v and wrote %d bytes", err, buf.Len())
+	}
+	var got []string
+	if err := gensenc.DecodeContext(ctx, bytes.NewReader(b), &got); err != context.Canceled {
+		t.Errorf("decoding with a canceled context gave %v", err)
+	}
+
+	// Contexts done midway abort the traversal.
+	err = gensenc.EncodeContext(&countdown{context.Background(), 2}, &buf, v)
+	if !errors.Is(err, context.Canceled) || buf.Len() != 0 {
+		t.Errorf("encoding canceled midway gave %v and wrote %d bytes", err, buf.Len())
+	}
+	err = gensenc.DecodeContext(&countdown{context.Background(), 2}, bytes.NewReader(b), &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, context.Canceled) {
+		t.Errorf("decoding canceled midway gave %v, want a *DecodeError for context.Canceled", err)
+	}
+}
@@ -0,0 +1,273 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestDecodeTopLevelPointers(t *testing.T) {
+	n := 7
+	tests := []struct {
+		name string
+		// in is encoded, and dst, a pointer, decoded into; want is what dst
+		// points to afterwards.
+		in, dst, want any
+	}{
+		{"nil map", map[string]int{"a": 1}, new(map[string]int), map[string]int{"a": 1}},
+		{"non-nil map", map[string]int{"a": 1}, &map[string]int{"a": 2, "b": 3}, map[string]int{"a": 1}},
+		{"empty map", map[string]int{}, &map[string]int{"b": 3}, map[string]int{}},
+		{"nil slice", []int{1, 2}, new([]int), []int{1, 2}},
+		{"non-nil slice", []int{1, 2}, &[]int{3, 4, 5}, []int{1, 2}},
+		{"empty slice", []int{}, &[]int{3}, []int{}},
+		{"byte slice", []byte("ab"), new([]byte), []byte("ab")},
+		{"array", [3]int{1, 2, 3}, &[3]int{4, 5, 6}, [3]int{1, 2, 3}},
+		{"int", 42, new(int), 42},
+		{"int8", int8(-3), new(int8), int8(-3)},
+		{"float", 1.5, new(float64), 1.5},
+		{"bool", true, new(bool), true},
+		{"string", "hi", new(string), "hi"},
+		{"nil pointer", &n, new(*int), &n},
+		{"non-nil pointer", &n, func() any { m := 1; p := &m; return &p }(), &n},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gensenc.Encode(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = gensenc.Decode(b, tt.dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.ValueOf(tt.dst).Elem().Interface()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeNotPointer(t *testing.T) {
+	b, err := gensenc.Encode(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dst := range []any{nil, (*map[string]int)(nil), 0} {
+		if gensenc.Decode(b, dst) == nil {
+			t.Errorf("decoding into %#v succeeded", dst)
+		}
+	}
+
+	// Maps are decoded into directly.
+	m := map[string]int{"b": 2}
+	err = gensenc.Decode(b, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"a": 1}; !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
+
+// TestDecodeAt reads records stored back to back, starting from one in the
+// middle.
+func TestDecodeAt(t *testing.T) {
+	var b []byte
+	var offs []int
+	for i := range 3 {
+		offs = append(offs, len(b))
+		r, err := gensenc.Encode(order{ID: uint64(i), Items: []string{"x"}, Tags: map[string]int{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, r...)
+	}
+	off := offs[1]
+	for i := 1; i < 3; i++ {
+		var o order
+		next, err := gensenc.DecodeAt(b, off, &o)
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if o.ID != uint64(i) {
+			t.Errorf("record %d: got %+v", i, o)
+		}
+		off = next
+	}
+	if off != len(b) {
+		t.Errorf("ended at %d, want %d", off, len(b))
+	}
+	for _, off := range []int{-1, len(b) + 1} {
+		if _, err := gensenc.DecodeAt(b, off, new(order)); err != io.ErrUnexpectedEOF {
+			t.Errorf("offset %d: got %v, want io.ErrUnexpectedEOF", off, err)
+		}
+	}
+	var o order
+	_, err := gensenc.DecodeAt(b[:len(b)-1], offs[2], &o)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || de.Offset < int64(offs[2]) {
+		t.Errorf("decoding a truncated record gave %v, want an error past its start", err)
+	}
+}
+
+func TestDecodeAlias(t *testing.T) {
+	v := map[string][]string{"k": {"a", "bc", ""}}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string][]string
+	err = gensenc.DecodeAlias(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %v, want %v", got, v)
+	}
+	err = gensenc.DecodeAlias(b[:len(b)-1], &got)
+	if !errors.Is(err, gensenc.ErrInvalidLength) && !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated input gave %v", err)
+	}
+}
+
+// TestDecodeLengthBounds checks that length prefixes announcing more values
+// than the input left can hold fail with ErrInvalidLength right after the
+// prefix, while ones that fit exactly decode.
+func TestDecodeLengthBounds(t *testing.T) {
+	le := func(n ...uint64) []byte {
+		var b []byte
+		for _, n := range n {
+			b = binary.LittleEndian.AppendUint64(b, n)
+		}
+		return b
+	}
+	for _, tt := range []struct {
+		name      string
+		fits, not []byte
+		into      func() any
+	}{
+		{"[]int64", le(1, 7), le(2, 7), func() any { return new([]int64) }},
+		{"string", le(3, 0)[:11], le(4, 0)[:11], func() any { return new(string) }},
+		{"[]string", le(1, 0), le(2, 0), func() any { return new([]string) }},
+		{"map[string]int64", le(1, 0, 0), le(2, 0, 0), func() any { return new(map[string]int64) }},
+		{"[][2]int64", le(1, 0, 0), le(2, 0, 0), func() any { return new([][2]int64) }},
+		{"huge", le(0), le(math.MaxUint64), func() any { return new([]byte) }},
+	} {
+		if err := gensenc.Decode(tt.fits, tt.into()); err != nil {
+			t.Errorf("%s: decoding a length that fits: %v", tt.name, err)
+		}
+		err := gensenc.Decode(tt.not, tt.into())
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrInvalidLength) || de.Offset != 8 {
+			t.Errorf("%s: got %v, want ErrInvalidLength at offset 8", tt.name, err)
+		}
+	}
+}
+
+// TestDecodeOverflow decodes the bounds of every sized integer type and
+// values past them, with and without truncation. Values are encoded with
+// the signedness of the destination, which matters to varints.
+func TestDecodeOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		into      func() any
+		fits, not any
+		truncated any
+	}{
+		{func() any { return new(int8) }, int64(math.MinInt8), int64(math.MaxInt8 + 1), int8(math.MinInt8)},
+		{func() any { return new(int8) }, int64(math.MaxInt8), int64(math.MinInt8 - 1), int8(math.MaxInt8)},
+		{func() any { return new(int16) }, int64(math.MinInt16), int64(math.MaxInt16 + 1), int16(math.MinInt16)},
+		{func() any { return new(int32) }, int64(math.MaxInt32), int64(math.MinInt32 - 1), int32(math.MaxInt32)},
+		{func() any { return new(uint8) }, uint64(math.MaxUint8), uint64(math.MaxUint8 + 1), uint8(0)},
+		{func() any { return new(uint16) }, uint64(math.MaxUint16), uint64(math.MaxUint64), uint16(math.MaxUint16)},
+		{func() any { return new(uint32) }, uint64(math.MaxUint32), uint64(math.MaxUint32 + 2), uint32(1)},
+	} {
+		for _, c := range []*gensenc.Codec{gensenc.New(), gensenc.New(gensenc.WithVarints())} {
+			b, err := c.Encode(tt.fits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := tt.into()
+			if err := c.Decode(b, v); err != nil {
+				t.Errorf("decoding %v into %T: %v", tt.fits, v, err)
+			}
+			b, err = c.Encode(tt.not)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Decode(b, tt.into()); !errors.Is(err, gensenc.ErrOverflow) {
+				t.Errorf("decoding %v into %T gave %v, want ErrOverflow", tt.not, v, err)
+			}
+		}
+		b, err := gensenc.Encode(tt.not)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := tt.into()
+		if err := gensenc.New(gensenc.WithTruncateIntegers()).Decode(b, v); err != nil {
+			t.Errorf("decoding %v into %T with truncation: %v", tt.not, v, err)
+		}
+		if got := reflect.ValueOf(v).Elem().Interface(); got != tt.truncated {
+			t.Errorf("decoding %v with truncation gave %v, want %v", tt.not, got, tt.truncated)
+		}
+		dec := gensenc.NewDecoder(bytes.NewReader(b))
+		dec.TruncateIntegers()
+		v = tt.into()
+		if err := dec.Decode(v); err != nil {
+			t.Errorf("decoding %v into %T with a truncating Decoder: %v", tt.not, v, err)
+		}
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	b, err := gensenc.Encode(map[string]map[string]int{"a": {"x": 1}, "b": {"y": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]map[string]int{"a": {"z": 9}, "c": {"q": 3}}
+	if err := gensenc.New(gensenc.WithMergeMaps()).Decode(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]int{"a": {"x": 1, "z": 9}, "b": {"y": 2}, "c": {"q": 3}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("merged into %v, want %v", m, want)
+	}
+
+	dec := gensenc.NewDecoder(bytes.NewReader(b))
+	dec.MergeMaps()
+	m = map[string]map[string]int{"c": {"q": 3}}
+	if err := dec.Decode(&m); err != nil || len(m) != 3 {
+		t.Errorf("decoder merged into %v, %v", m, err)
+	}
+
+	// Decoding without the option replaces the content.
+	m = map[string]map[string]int{"a": {"z": 9}, "c": {"q": 3}}
+	if err := gensenc.Decode(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[string]map[string]int{"a": {"x": 1}, "b": {"y": 2}}); !reflect.DeepEqual(m, want) {
+		t.Errorf("replaced with %v, want %v", m, want)
+	}
+}
+
+func TestMergeMapsError(t *testing.T) {
+	b, err := gensenc.Encode(map[string]int{"a": 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]int8{"a": 1, "b": 2}
+	err = gensenc.New(gensenc.WithMergeMaps()).Decode(b, &m)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) || de.Path != "[a]" {
+		t.Errorf("got %v, want ErrOverflow at [a]", err)
+	}
+	if m["a"] != 1 || m["b"] != 2 {
+		t.Errorf("failing entry changed the map to %v", m)
+	}
+}
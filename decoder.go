@@ -0,0 +1,61 @@
+package gensenc
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+)
+
+// A Decoder reads consecutive encoded values from a stream.
+type Decoder struct {
+	d decodeState
+}
+
+// NewDecoder returns a Decoder reading from r. If r does not implement
+// io.ByteReader it is wrapped in a bufio.Reader, so the Decoder may read
+// data from r beyond the values it decodes.
+func NewDecoder(r io.Reader) *Decoder {
+	if _, ok := r.(io.ByteReader); !ok {
+		r = bufio.NewReader(r)
+	}
+	return &Decoder{d: *defaultCodec.newDecodeState(nil, r)}
+}
+
+func (dec *Decoder) Decode(a any) error {
+	return dec.d.decodeRoot(reflect.ValueOf(a))
+}
+
+func (dec *Decoder) DecodeValue(v reflect.Value) error {
+	return dec.d.decodeRoot(v)
+}
+
+// TruncateIntegers makes the Decoder store integers too large for their
+// destination type truncated, as Decode did before ErrOverflow existed,
+// instead of failing.
+func (dec *Decoder) TruncateIntegers() {
+	dec.d.truncate = true
+}
+
+// ArrayLengths makes the Decoder read arrays written by an Encoder with
+// ArrayLengths enabled. Arrays of a different length than the destination
+// are truncated or padded with zero values, so array sizes can change
+// between versions.
+func (dec *Decoder) ArrayLengths() {
+	dec.d.arrayLens = true
+}
+
+// MergeMaps makes the Decoder add decoded entries to existing maps instead
+// of replacing their content, like WithMergeMaps.
+func (dec *Decoder) MergeMaps() {
+	dec.d.mergeMaps = true
+}
+
+// Skip advances past one encoded value of type t without decoding it.
+func (dec *Decoder) Skip(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return dec.d.guard(func() error {
+		return dec.d.skip(t)
+	})
+}
@@ -0,0 +1,177 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// TestDecoderSkip skips values on a stream and decodes the ones between
+// them.
+func TestDecoderSkip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := gensenc.NewEncoder(&buf)
+	for i := range 3 {
+		p := testProfile()
+		p.ID = uint64(i)
+		if err := enc.Encode(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := gensenc.NewDecoder(&buf)
+	if err := dec.Skip(reflect.TypeFor[*profile]()); err != nil {
+		t.Fatal(err)
+	}
+	var got profile
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 {
+		t.Errorf("decoded value %d after skipping one, want 1", got.ID)
+	}
+	if err := dec.Skip(reflect.TypeFor[profile]()); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&got); err != io.EOF {
+		t.Errorf("decoding past the last value gave %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderSkipErrors(t *testing.T) {
+	b, err := gensenc.Encode(testProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := gensenc.NewDecoder(bytes.NewReader(b[:len(b)-1]))
+	err = dec.Skip(reflect.TypeFor[profile]())
+	if !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("skipping a truncated value gave %v, want ErrTruncated", err)
+	}
+	dec = gensenc.NewDecoder(bytes.NewReader(make([]byte, 8)))
+	err = dec.Skip(reflect.TypeFor[chan int]())
+	if !errors.Is(err, gensenc.ErrCantSkip) {
+		t.Errorf("skipping a channel gave %v, want ErrCantSkip", err)
+	}
+}
+
+// readCounter is a plain io.Reader counting the calls to Read.
+type readCounter struct {
+	r     io.Reader
+	calls int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	c.calls++
+	return c.r.Read(p)
+}
+
+// TestDecoderBuffers checks that a Decoder on an unbuffered reader reads
+// it in large blocks rather than per field.
+func TestDecoderBuffers(t *testing.T) {
+	var buf bytes.Buffer
+	enc := gensenc.NewEncoder(&buf)
+	for i := range 100 {
+		err := enc.Encode(order{ID: uint64(i), Items: []string{"a", "b"}, Tags: map[string]int{"x": i}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := buf.Len()
+	r := &readCounter{r: &buf}
+	dec := gensenc.NewDecoder(r)
+	for i := range 100 {
+		var o order
+		if err := dec.Decode(&o); err != nil || o.ID != uint64(i) {
+			t.Fatalf("value %d: got %+v, %v", i, o, err)
+		}
+	}
+	if limit := size/4096 + 2; r.calls > limit {
+		t.Errorf("%d bytes took %d reads, want at most %d", size, r.calls, limit)
+	}
+}
+
+// TestDecodeValueExact checks that DecodeValue leaves the input after the
+// value unread, and reads values of a fixed wire size at once.
+func TestDecodeValueExact(t *testing.T) {
+	fixed := [4]uint64{1, 2, 3, 4}
+	b, err := gensenc.Encode(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &readCounter{r: bytes.NewReader(append(b, "rest"...))}
+	var got [4]uint64
+	err = gensenc.DecodeValue(r, reflect.ValueOf(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != fixed || r.calls != 1 {
+		t.Errorf("read %v in %d calls, want %v in 1", got, r.calls, fixed)
+	}
+
+	b, err = gensenc.Encode(order{ID: 1, Items: []string{"a"}, Tags: map[string]int{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest := bytes.NewReader(append(b, "rest"...))
+	var o order
+	err = gensenc.DecodeValue(&readCounter{r: rest}, reflect.ValueOf(&o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail, _ := io.ReadAll(rest); string(tail) != "rest" {
+		t.Errorf("left %q unread, want \"rest\"", tail)
+	}
+}
+
+// TestDecodeValueN parses values embedded in framing of its own, resuming
+// after each value at the count DecodeValueN returns.
+func TestDecodeValueN(t *testing.T) {
+	values := []order{
+		{ID: 1, Items: []string{"a", "bc"}, Tags: map[string]int{"x": 1}},
+		{ID: 2, Tags: map[string]int{}},
+	}
+	var frame []byte
+	for _, o := range values {
+		b, err := gensenc.Encode(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame = append(append(frame, b...), '|')
+	}
+	r := bytes.NewReader(frame)
+	var read int64
+	for i, want := range values {
+		var o order
+		n, err := gensenc.DecodeValueN(r, reflect.ValueOf(&o))
+		if err != nil {
+			t.Fatal(err)
+		}
+		read += n
+		if sep, err := r.ReadByte(); err != nil || sep != '|' {
+			t.Fatalf("value %d ended at %d, before %q", i, n, sep)
+		}
+		read++
+		if !reflect.DeepEqual(o, want) {
+			t.Errorf("got %+v, want %+v", o, want)
+		}
+	}
+	if read != int64(len(frame)) {
+		t.Errorf("read %d bytes, want %d", read, len(frame))
+	}
+
+	// A failing value reports what was read up to the failure.
+	b, _ := gensenc.Encode([]int{1, 300})
+	var small []int8
+	n, err := gensenc.DecodeValueN(bytes.NewReader(b), reflect.ValueOf(&small))
+	if !errors.Is(err, gensenc.ErrOverflow) || n != int64(len(b)) {
+		t.Errorf("got %d bytes and %v, want %d and ErrOverflow", n, err, len(b))
+	}
+	n, err = gensenc.DecodeValueN(bytes.NewReader(b[:12]), reflect.ValueOf(&small))
+	if !errors.Is(err, gensenc.ErrTruncated) || n != 12 {
+		t.Errorf("got %d bytes and %v, want 12 and ErrTruncated", n, err)
+	}
+}
@@ -0,0 +1,108 @@
+package gensenc
+
+import "reflect"
+
+// deltaCodec encodes integer slice fields tagged with delta as the
+// difference of each element to the one before it, the first element
+// being taken as is. With the varint option the differences are written
+// as zigzag varints, so sorted IDs and timestamps take a byte or two per
+// element instead of eight.
+type deltaCodec struct {
+	varint bool
+}
+
+func newDeltaCodec(t reflect.Type, varint bool) wireCodec {
+	if t.Kind() != reflect.Slice {
+		return invalidTag{}
+	}
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return deltaCodec{varint}
+	}
+	return invalidTag{}
+}
+
+// integerBits returns the integer v as written on the wire.
+func integerBits(v reflect.Value) uint64 {
+	if v.CanInt() {
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+func (c deltaCodec) encode(e *encodeState, v reflect.Value) error {
+	e.writeUint64(uint64(v.Len()))
+	var prev uint64
+	for i := range v.Len() {
+		x := integerBits(v.Index(i))
+		c.write(e, x-prev)
+		prev = x
+	}
+	return nil
+}
+
+func (c deltaCodec) write(e *encodeState, delta uint64) {
+	if c.varint {
+		e.writeUvarint(delta<<1 ^ uint64(int64(delta)>>63))
+	} else {
+		e.writeUint64(delta)
+	}
+}
+
+func (c deltaCodec) read(d *decodeState) (uint64, error) {
+	if !c.varint {
+		return d.readUint64()
+	}
+	x, err := d.readUvarint()
+	return x>>1 ^ -(x & 1), err
+}
+
+func (c deltaCodec) length(d *decodeState) (uint64, error) {
+	length, err := d.readUint64()
+	if err != nil {
+		return 0, err
+	}
+	size := 8
+	if c.varint {
+		size = 1
+	}
+	return length, d.checkLength(length, size)
+}
+
+func (c deltaCodec) decode(d *decodeState, v reflect.Value) error {
+	length, err := c.length(d)
+	if err != nil {
+		return err
+	}
+	var prev uint64
+	return d.decodeElems(v, int(length), func(_ int, elem reflect.Value) error {
+		delta, err := c.read(d)
+		if err != nil {
+			return err
+		}
+		prev += delta
+		return d.setInteger(elem, prev)
+	})
+}
+
+func (c deltaCodec) skip(d *decodeState) error {
+	length, err := c.length(d)
+	if err != nil {
+		return err
+	}
+	if !c.varint {
+		return d.discard(length * 8)
+	}
+	for range length {
+		_, err = d.readUvarint()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (deltaCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
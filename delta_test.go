@@ -0,0 +1,75 @@
+package gensenc_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type series struct {
+	Times []int64  `gensenc:"delta"`
+	IDs   []uint32 `gensenc:"delta,varint"`
+}
+
+func TestDelta(t *testing.T) {
+	v := series{
+		Times: []int64{1_700_000_000, 1_700_000_001, 1_699_999_990, math.MinInt64, math.MaxInt64},
+		IDs:   []uint32{4, 5, 7, math.MaxUint32, 0},
+	}
+	for _, c := range []*gensenc.Codec{gensenc.New(), gensenc.New(gensenc.WithVarints())} {
+		if got := roundTrip(t, c, v); !reflect.DeepEqual(got, v) {
+			t.Errorf("got %+v, want %+v", got, v)
+		}
+	}
+	// Close sorted values take a byte each as varints, after the first.
+	ids := struct {
+		IDs []uint64 `gensenc:"delta,varint"`
+	}{IDs: make([]uint64, 100)}
+	for i := range ids.IDs {
+		ids.IDs[i] = 1<<40 + uint64(i)*3
+	}
+	b, err := gensenc.Encode(ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > 8+6+99 {
+		t.Errorf("100 close IDs took %d bytes", len(b))
+	}
+	got := roundTrip(t, gensenc.New(), ids)
+	if !reflect.DeepEqual(got, ids) {
+		t.Errorf("got %v, want %v", got.IDs, ids.IDs)
+	}
+}
+
+func TestDeltaErrors(t *testing.T) {
+	type wide struct {
+		Times []int64 `gensenc:"delta"`
+	}
+	type narrow struct {
+		Times []int8 `gensenc:"delta"`
+	}
+	b, err := gensenc.Encode(wide{Times: []int64{100, 127, 128}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n narrow
+	err = gensenc.Decode(b, &n)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) || de.Path != "Times[2]" {
+		t.Errorf("decoding 128 into an int8 gave %v, want ErrOverflow at Times[2]", err)
+	}
+	var w wide
+	err = gensenc.Decode(b[:len(b)-1], &w)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding truncated deltas gave %v, want ErrInvalidLength", err)
+	}
+	_, err = gensenc.Encode(struct {
+		F []float64 `gensenc:"delta"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("tagging floats gave %v, want ErrInvalidTag", err)
+	}
+}
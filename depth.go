@@ -0,0 +1,66 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+)
+
+var ErrMaxDepth error = errors.New("maximum nesting depth exceeded")
+
+// defaultMaxDepth bounds the nesting of values, each level of which takes
+// a few frames of the goroutine stack, so that deep input or cyclic values
+// fail instead of overflowing it.
+//
+// Encoding and decoding stay recursive rather than walking values with an
+// explicit stack of work. Codecs of types and tag options nest calls to
+// encode and decode wherever they please, which a work stack would have to
+// turn inside out. Instead every walker counts its levels against this
+// limit: encoding and decoding, skipping, formats, flat encodings, dynamic
+// values and protobuf messages. Format readers skip values iteratively, and
+// error paths keep only their ends, so a value nested up to the limit costs
+// some megabytes of stack, far below the gigabyte the runtime allows.
+const defaultMaxDepth = 10000
+
+// WithMaxDepth makes encoding and decoding fail with ErrMaxDepth for values
+// nested more than n levels deep, counting every struct, slice, array, map,
+// pointer and interface on the way, instead of the default of 10000.
+func WithMaxDepth(n int) Option {
+	return func(o *options) { o.maxDepth = n }
+}
+
+func (o *options) depthLimit() int {
+	if o.maxDepth > 0 {
+		return o.maxDepth
+	}
+	return defaultMaxDepth
+}
+
+func (e *encodeState) encode(v reflect.Value) error {
+	if e.depth >= e.depthLimit() {
+		return ErrMaxDepth
+	}
+	e.depth++
+	err := e.encodeValue(v)
+	e.depth--
+	return err
+}
+
+func (d *decodeState) decode(v reflect.Value) error {
+	if d.depth >= d.depthLimit() {
+		return ErrMaxDepth
+	}
+	d.depth++
+	err := d.decodeValue(v)
+	d.depth--
+	return err
+}
+
+func (d *decodeState) skip(t reflect.Type) error {
+	if d.depth >= d.depthLimit() {
+		return ErrMaxDepth
+	}
+	d.depth++
+	err := d.skipType(t)
+	d.depth--
+	return err
+}
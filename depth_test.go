@@ -0,0 +1,85 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type depthNode struct {
+	Value int
+	Next  *depthNode
+}
+
+// TestDepthCycle checks that encoding a cyclic value fails with
+// ErrMaxDepth, and that the path of the error is cut short rather than
+// running through all the levels up to the limit.
+func TestDepthCycle(t *testing.T) {
+	n := &depthNode{}
+	n.Next = n
+	_, err := gensenc.Encode(n)
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Fatalf("encoding a cycle gave %v, want ErrMaxDepth", err)
+	}
+	if len(ee.Path) > 1000 {
+		t.Errorf("error path is %d bytes long", len(ee.Path))
+	}
+	if !strings.HasPrefix(ee.Path, "Next.Next") || !strings.Contains(ee.Path, " more)") {
+		t.Errorf("error at %q, want an elided path of Next fields", ee.Path)
+	}
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	var n *depthNode
+	for i := range 10 {
+		n = &depthNode{Value: i, Next: n}
+	}
+	b, err := gensenc.Encode(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := gensenc.New(gensenc.WithMaxDepth(5))
+	if _, err := c.Encode(n); !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("encoding 10 levels with a limit of 5 gave %v, want ErrMaxDepth", err)
+	}
+	var got *depthNode
+	err = c.Decode(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("decoding 10 levels with a limit of 5 gave %v, want ErrMaxDepth", err)
+	}
+}
+
+// TestDepthSkip decodes a struct from a map holding, under a key the struct
+// lacks, arrays nested a million levels deep, which must be skipped without
+// overflowing the stack.
+func TestDepthSkip(t *testing.T) {
+	const levels = 1_000_000
+	input := map[string][]byte{
+		"msgpack": nested([]byte{0x81, 0xa1, 'B'}, 0x91, levels, 0xc0),
+		"cbor":    nested([]byte{0xa1, 0x61, 'B'}, 0x81, levels, 0xf6),
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var got struct{ A int }
+			if err := gensenc.DecodeFormat(f.format, input[f.name], &got); err != nil {
+				t.Fatal(err)
+			}
+			var v any
+			err := gensenc.DecodeFormat(f.format, input[f.name], &v)
+			if !errors.Is(err, gensenc.ErrMaxDepth) {
+				t.Errorf("decoding into an interface gave %v, want ErrMaxDepth", err)
+			}
+		})
+	}
+}
+
+// nested returns head followed by n copies of the array header b and the
+// final value last.
+func nested(head []byte, b byte, n int, last byte) []byte {
+	return append(append(head, bytes.Repeat([]byte{b}, n)...), last)
+}
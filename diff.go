@@ -0,0 +1,100 @@
+package gensenc
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// A FieldDiff is a value that differs between two payloads. A and B are
+// the values in either payload, nil for elements and map entries only one
+// of them has.
+type FieldDiff struct {
+	// Path locates the value as in DecodeError.
+	Path string
+	A, B any
+}
+
+// Diff decodes a and b as values of type t and reports the values that
+// differ between them, down to individual struct fields, slice and array
+// elements and map entries, in field order and, for maps, the order of
+// their keys' fmt.Sprint form.
+func Diff(a, b []byte, t reflect.Type) ([]FieldDiff, error) {
+	va, vb := reflect.New(t), reflect.New(t)
+	err := Decode(a, va.Interface())
+	if err != nil {
+		return nil, err
+	}
+	err = Decode(b, vb.Interface())
+	if err != nil {
+		return nil, err
+	}
+	var diffs []FieldDiff
+	diff(va.Elem(), vb.Elem(), "", &diffs)
+	for i := range diffs {
+		diffs[i].Path = strings.TrimPrefix(diffs[i].Path, ".")
+	}
+	return diffs, nil
+}
+
+func diff(a, b reflect.Value, path string, diffs *[]FieldDiff) {
+	leaf := func() {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*diffs = append(*diffs, FieldDiff{path, a.Interface(), b.Interface()})
+		}
+	}
+	if infoOf(a.Type()).codec != nil {
+		leaf()
+		return
+	}
+	switch a.Kind() {
+	case reflect.Struct:
+		for _, f := range infoOf(a.Type()).fields {
+			diff(a.Field(f.index), b.Field(f.index), path+"."+f.name, diffs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range max(a.Len(), b.Len()) {
+			switch {
+			case i >= a.Len():
+				*diffs = append(*diffs, FieldDiff{path + index(i), nil, b.Index(i).Interface()})
+			case i >= b.Len():
+				*diffs = append(*diffs, FieldDiff{path + index(i), a.Index(i).Interface(), nil})
+			default:
+				diff(a.Index(i), b.Index(i), path+index(i), diffs)
+			}
+		}
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		slices.SortFunc(keys, func(x, y reflect.Value) int {
+			return strings.Compare(fmt.Sprint(x), fmt.Sprint(y))
+		})
+		for _, k := range keys {
+			p := path + "[" + fmt.Sprint(k) + "]"
+			va, vb := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !va.IsValid():
+				*diffs = append(*diffs, FieldDiff{p, nil, vb.Interface()})
+			case !vb.IsValid():
+				*diffs = append(*diffs, FieldDiff{p, va.Interface(), nil})
+			default:
+				diff(va, vb, p, diffs)
+			}
+		}
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*diffs = append(*diffs, FieldDiff{path, a.Interface(), b.Interface()})
+			}
+			return
+		}
+		diff(a.Elem(), b.Elem(), path, diffs)
+	default:
+		leaf()
+	}
+}
@@ -0,0 +1,82 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type replica struct {
+	Name    string
+	Orders  []order
+	Points  map[string]point
+	When    time.Time
+	Primary *point
+}
+
+func TestDiff(t *testing.T) {
+	note := "n"
+	a := replica{
+		Name:   "r1",
+		Orders: []order{{ID: 1, Items: []string{"x"}, Tags: map[string]int{"k": 1}}},
+		Points: map[string]point{"a": {1, 2}, "b": {3, 4}},
+		When:   time.Unix(0, 0).UTC(),
+	}
+	b := a
+	b.Orders = []order{
+		{ID: 1, Items: []string{"y"}, Tags: map[string]int{"k": 2}, Note: &note},
+		{ID: 2, Tags: map[string]int{}},
+	}
+	b.Points = map[string]point{"b": {3, 5}, "c": {0, 0}}
+	b.When = time.Unix(1, 0).UTC()
+	b.Primary = &point{}
+	ea, err := gensenc.Encode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eb, err := gensenc.Encode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := gensenc.Diff(ea, eb, reflect.TypeFor[replica]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gensenc.FieldDiff{
+		{"Orders[0].Items[0]", "x", "y"},
+		{"Orders[0].Tags[k]", 1, 2},
+		{"Orders[0].Note", (*string)(nil), &note},
+		{"Orders[1]", nil, order{ID: 2, Tags: map[string]int{}}},
+		{"Points[a]", point{1, 2}, nil},
+		{"Points[b].Y", int32(4), int32(5)},
+		{"Points[c]", nil, point{}},
+		// Types with a dedicated encoding are compared whole.
+		{"When", a.When, b.When},
+		{"Primary", (*point)(nil), &point{}},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", diffs, want)
+	}
+	diffs, err = gensenc.Diff(ea, ea, reflect.TypeFor[replica]())
+	if err != nil || len(diffs) != 0 {
+		t.Errorf("diffing a payload with itself gave %+v, %v", diffs, err)
+	}
+}
+
+func TestDiffErrors(t *testing.T) {
+	b, err := gensenc.Encode(replica{Points: map[string]point{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := reflect.TypeFor[replica]()
+	for _, args := range [][2][]byte{{b[:len(b)-1], b}, {b, b[:len(b)-1]}} {
+		_, err := gensenc.Diff(args[0], args[1], typ)
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) {
+			t.Errorf("diffing a truncated payload gave %v, want a *DecodeError", err)
+		}
+	}
+}
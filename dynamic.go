@@ -0,0 +1,397 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+)
+
+// codecTypes maps the names of the types in typeCodecs to the types, so
+// DecodeDynamic can decode them without knowing them statically.
+var codecTypes = func() map[string]reflect.Type {
+	m := map[string]reflect.Type{}
+	for t := range typeCodecs {
+		m[typeName(t)] = t
+	}
+	return m
+}()
+
+// DecodeDynamic decodes b, holding a struct described by s, without the Go
+// type at hand. Structs become maps from field names to values, slices and
+// arrays []any and maps map[any]any. Signed integers become int64,
+// unsigned ones uint64, nil pointers nil and the values they point to
+// otherwise the value itself. Types with a dedicated encoding, such as
+// big.Int or netip.Addr, are returned as values of their Go type, as are
+// the values of interfaces, which must be registered, and fields tagged
+// with options such as delta as the corresponding slice of int64, uint64
+// and so on. Fields missing from the end of a section are left out of the
+// map of their struct. Since s may be as untrusted as b, array lengths are
+// checked against the input like slice lengths, and values nested deeper
+// than the depth limit fail with ErrMaxDepth.
+func DecodeDynamic(b []byte, s Schema) (map[string]any, error) {
+	for s.Kind == reflect.Pointer && s.Elem != nil {
+		s = *s.Elem
+	}
+	if s.Kind != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	var m map[string]any
+	err := d.guard(func() error {
+		v, err := d.decodeDynamic(&s, nil, nil)
+		m, _ = v.(map[string]any)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// decodeDynamic decodes a value described by s with the tag options opts.
+// ancestors holds the enclosing schemas for resolving references. Like
+// decode, it fails with ErrMaxDepth for values nested too deep, as those of
+// recursive schemas can be.
+func (d *decodeState) decodeDynamic(s *Schema, opts tagOptions, ancestors []*Schema) (any, error) {
+	if d.depth >= d.depthLimit() {
+		return nil, ErrMaxDepth
+	}
+	d.depth++
+	v, err := d.decodeDynamicValue(s, opts, ancestors)
+	d.depth--
+	return v, err
+}
+
+func (d *decodeState) decodeDynamicValue(s *Schema, opts tagOptions, ancestors []*Schema) (any, error) {
+	if s.Ref {
+		for i := len(ancestors) - 1; ; i-- {
+			if i < 0 {
+				return nil, ErrInvalidSchema
+			}
+			if ancestors[i].Name == s.Name && ancestors[i].Kind == s.Kind {
+				s = ancestors[i]
+				break
+			}
+		}
+	}
+	ancestors = append(ancestors, s)
+	switch {
+	case opts.has("redact"):
+		// Redacted fields are written like those without the option.
+		rest := maps.Clone(opts)
+		delete(rest, "redact")
+		return d.decodeDynamic(s, rest, ancestors)
+	case opts.has("enum"):
+		return d.readString()
+	case opts.has("uuid"):
+		b, err := d.take(16)
+		if err != nil {
+			return nil, err
+		}
+		if s.Kind == reflect.String && UUID(b) == (UUID{}) {
+			return "", nil
+		}
+		return UUID(b).String(), nil
+	case opts.has("oneof"):
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]any{}
+		n := int(b[0])
+		if n > len(s.Fields) {
+			return nil, ErrMalformed
+		}
+		if n > 0 {
+			f := &s.Fields[n-1]
+			m[f.Name], err = d.decodeDynamic(&f.Schema, parseTag(f.Tag), ancestors)
+			if err != nil {
+				return nil, d.at(err, "."+f.Name)
+			}
+		}
+		return m, nil
+	case opts.has("arraylen"):
+		n, err := d.readUint64()
+		if err != nil {
+			return nil, err
+		}
+		if n != uint64(s.Len) {
+			return nil, ErrArrayLength
+		}
+	case hasFieldCodec(opts):
+		t := plainType(s)
+		if opts.has("compress") && s.Kind == reflect.Slice {
+			// Only byte slices can be compressed.
+			t = reflect.TypeFor[[]byte]()
+		}
+		if t == nil {
+			if opts.has("rle") && s.Kind == reflect.Slice && s.Elem != nil {
+				return d.decodeDynamicRLE(s.Elem, ancestors)
+			}
+			// The remaining options only apply to basic types and slices
+			// and arrays of them.
+			return nil, ErrInvalidTag
+		}
+		v := reflect.New(t).Elem()
+		err := newFieldCodec(t, opts).decode(d, v)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+	if t, ok := codecTypes[s.Name]; ok {
+		v := reflect.New(t).Elem()
+		err := d.decode(v)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+	switch s.Kind {
+	case reflect.Struct:
+		m := make(map[string]any, len(s.Fields))
+		for start := 0; start < len(s.Fields); {
+			section := parseTag(s.Fields[start].Tag).section()
+			end := start + 1
+			for section != "" && end < len(s.Fields) && parseTag(s.Fields[end].Tag).section() == section {
+				end++
+			}
+			var err error
+			if section == "" {
+				err = d.decodeDynamicField(m, &s.Fields[start], ancestors)
+			} else {
+				err = d.decodeDynamicSection(m, s.Fields[start:end], ancestors)
+			}
+			if err != nil {
+				return nil, err
+			}
+			start = end
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if s.Elem == nil {
+			return nil, ErrInvalidSchema
+		}
+		if s.Len < 0 {
+			return nil, ErrInvalidSchema
+		}
+		length := uint64(s.Len)
+		if s.Kind == reflect.Slice {
+			var err error
+			length, err = d.readUint64()
+			if err != nil {
+				return nil, err
+			}
+		}
+		// The lengths of arrays come from the schema, which may be as
+		// untrusted as the input.
+		err := d.checkLength(length, max(s.Elem.Size, 1))
+		if err == nil {
+			err = d.charge(length, reflect.TypeFor[any]().Size())
+		}
+		if err != nil {
+			return nil, err
+		}
+		l := make([]any, length)
+		for i := range l {
+			var err error
+			l[i], err = d.decodeDynamic(s.Elem, nil, ancestors)
+			if err != nil {
+				return nil, d.at(err, index(i))
+			}
+		}
+		return l, nil
+	case reflect.Map:
+		if s.Key == nil || s.Elem == nil {
+			return nil, ErrInvalidSchema
+		}
+		switch s.Key.Kind {
+		case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+			return nil, ErrUnsupportedKind
+		}
+		length, err := d.readUint64()
+		if err != nil {
+			return nil, err
+		}
+		err = d.checkLength(length, max(s.Key.Size, 1))
+		if err == nil {
+			err = d.charge(length, 2*reflect.TypeFor[any]().Size())
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[any]any, length)
+		for i := range length {
+			key, err := d.decodeDynamic(s.Key, nil, ancestors)
+			if err != nil {
+				return nil, d.at(err, index(int(i)))
+			}
+			m[key], err = d.decodeDynamic(s.Elem, nil, ancestors)
+			if err != nil {
+				return nil, d.at(err, "["+fmt.Sprint(key)+"]")
+			}
+		}
+		return m, nil
+	case reflect.Pointer:
+		if s.Elem == nil {
+			return nil, ErrInvalidSchema
+		}
+		present, err := d.readPresence()
+		if err != nil || !present {
+			return nil, err
+		}
+		return d.decodeDynamic(s.Elem, nil, ancestors)
+	case reflect.Interface:
+		t, err := d.readType()
+		if err != nil || t == nil {
+			return nil, err
+		}
+		v := reflect.New(t).Elem()
+		err = d.decode(v)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	case reflect.String:
+		return d.readString()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := d.readUint64()
+		return int64(x), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return d.readUint64()
+	case reflect.Bool:
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case reflect.Float32:
+		b, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case reflect.Float64:
+		x, err := d.readUint64()
+		return math.Float64frombits(x), err
+	case reflect.Complex64:
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		re := math.Float32frombits(binary.LittleEndian.Uint32(b))
+		im := math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))
+		return complex(re, im), nil
+	case reflect.Complex128:
+		re, err := d.readUint64()
+		if err != nil {
+			return nil, err
+		}
+		im, err := d.readUint64()
+		return complex(math.Float64frombits(re), math.Float64frombits(im)), err
+	}
+	return nil, ErrUnsupportedKind
+}
+
+func (d *decodeState) decodeDynamicField(m map[string]any, f *SchemaField, ancestors []*Schema) error {
+	v, err := d.decodeDynamic(&f.Schema, parseTag(f.Tag), ancestors)
+	if err != nil {
+		return d.at(err, "."+f.Name)
+	}
+	m[f.Name] = v
+	return nil
+}
+
+// decodeDynamicSection decodes the fields of a section into m, like
+// decodeSection.
+func (d *decodeState) decodeDynamicSection(m map[string]any, fields []SchemaField, ancestors []*Schema) error {
+	infos := make([]fieldInfo, len(fields))
+	for i, f := range fields {
+		infos[i] = fieldInfo{index: i, name: f.Name}
+	}
+	_, err := d.readSection(infos, func(f *fieldInfo) (bool, error) {
+		return false, d.decodeDynamicField(m, &fields[f.index], ancestors)
+	})
+	return err
+}
+
+// decodeDynamicRLE decodes a slice tagged with rle of elements described
+// by elem, decoding repeated elements again for each so that they don't
+// share maps and slices.
+func (d *decodeState) decodeDynamicRLE(elem *Schema, ancestors []*Schema) ([]any, error) {
+	length, err := d.readUint64()
+	if err == nil {
+		err = d.checkLength(length, 0)
+	}
+	if err == nil {
+		err = d.chargeRuns(length, reflect.TypeFor[any]().Size())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l []any
+	for uint64(len(l)) < length {
+		run, err := d.readUint64()
+		if err != nil {
+			return nil, err
+		}
+		if run == 0 || run > length-uint64(len(l)) {
+			return nil, ErrMalformed
+		}
+		start := d.off
+		for i := range run {
+			if i > 0 {
+				d.off = start
+			}
+			v, err := d.decodeDynamic(elem, nil, ancestors)
+			if err != nil {
+				return nil, d.at(err, index(len(l)))
+			}
+			l = append(l, v)
+		}
+	}
+	return l, nil
+}
+
+// plainTypes maps the kinds of basic types to the types DecodeDynamic
+// decodes them as.
+var plainTypes = map[reflect.Kind]reflect.Type{
+	reflect.Int:        reflect.TypeFor[int64](),
+	reflect.Int8:       reflect.TypeFor[int64](),
+	reflect.Int16:      reflect.TypeFor[int64](),
+	reflect.Int32:      reflect.TypeFor[int64](),
+	reflect.Int64:      reflect.TypeFor[int64](),
+	reflect.Uint:       reflect.TypeFor[uint64](),
+	reflect.Uint8:      reflect.TypeFor[uint64](),
+	reflect.Uint16:     reflect.TypeFor[uint64](),
+	reflect.Uint32:     reflect.TypeFor[uint64](),
+	reflect.Uint64:     reflect.TypeFor[uint64](),
+	reflect.Bool:       reflect.TypeFor[bool](),
+	reflect.Float32:    reflect.TypeFor[float32](),
+	reflect.Float64:    reflect.TypeFor[float64](),
+	reflect.Complex64:  reflect.TypeFor[complex64](),
+	reflect.Complex128: reflect.TypeFor[complex128](),
+	reflect.String:     reflect.TypeFor[string](),
+}
+
+// plainType returns the unnamed type that DecodeDynamic uses for values
+// described by s, or nil if there is none.
+func plainType(s *Schema) reflect.Type {
+	switch s.Kind {
+	case reflect.Slice, reflect.Array:
+		if s.Elem == nil || s.Elem.Ref {
+			return nil
+		}
+		elem := plainType(s.Elem)
+		if elem == nil {
+			return nil
+		}
+		if s.Kind == reflect.Array {
+			return reflect.ArrayOf(s.Len, elem)
+		}
+		return reflect.SliceOf(elem)
+	}
+	return plainTypes[s.Kind]
+}
@@ -0,0 +1,106 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type dynNode struct {
+	Value int
+	Next  *dynNode
+}
+
+type dynDoc struct {
+	Name   string
+	Count  uint16
+	Scores []float64
+	Grid   [2]int8
+	Attrs  map[string]bool
+	Node   *dynNode
+	Level  level `gensenc:"enum"`
+	Deltas []int `gensenc:"delta"`
+}
+
+func TestDecodeDynamic(t *testing.T) {
+	in := dynDoc{
+		Name:   "doc",
+		Count:  3,
+		Scores: []float64{1.5},
+		Grid:   [2]int8{-1, 1},
+		Attrs:  map[string]bool{"x": true},
+		Node:   &dynNode{Value: 1, Next: &dynNode{Value: 2}},
+		Level:  info,
+		Deltas: []int{5, 6},
+	}
+	b, err := gensenc.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gensenc.DecodeDynamic(b, gensenc.DescribeType(reflect.TypeFor[dynDoc]()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"Name":   "doc",
+		"Count":  uint64(3),
+		"Scores": []any{1.5},
+		"Grid":   []any{int64(-1), int64(1)},
+		"Attrs":  map[any]any{"x": true},
+		"Node": map[string]any{
+			"Value": int64(1),
+			"Next":  map[string]any{"Value": int64(2), "Next": nil},
+		},
+		"Level":  "info",
+		"Deltas": []int64{5, 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+// TestDecodeDynamicDeep feeds a recursive schema input nesting deeper than
+// the depth limit, which must fail rather than overflow the stack.
+func TestDecodeDynamicDeep(t *testing.T) {
+	b := bytes.Repeat([]byte{0, 0, 0, 0, 0, 0, 0, 0, 1}, 100000)
+	_, err := gensenc.DecodeDynamic(b, gensenc.DescribeType(reflect.TypeFor[dynNode]()))
+	if !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("got %v, want ErrMaxDepth", err)
+	}
+}
+
+func TestDecodeDynamicSchemaLengths(t *testing.T) {
+	huge := gensenc.Schema{
+		Kind: reflect.Struct,
+		Fields: []gensenc.SchemaField{{
+			Name: "A",
+			Schema: gensenc.Schema{
+				Kind: reflect.Array,
+				Len:  1 << 40,
+				Elem: &gensenc.Schema{Kind: reflect.Int, Size: 8},
+				Size: 8 << 40,
+			},
+		}},
+	}
+	_, err := gensenc.DecodeDynamic(make([]byte, 64), huge)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("array longer than the input: got %v, want ErrInvalidLength", err)
+	}
+	huge.Fields[0].Schema.Len = -1
+	_, err = gensenc.DecodeDynamic(make([]byte, 64), huge)
+	if !errors.Is(err, gensenc.ErrInvalidSchema) {
+		t.Errorf("negative array length: got %v, want ErrInvalidSchema", err)
+	}
+
+	_, err = gensenc.DecodeDynamic(nil, gensenc.DescribeType(reflect.TypeFor[int]()))
+	if !errors.Is(err, gensenc.ErrNotStruct) {
+		t.Errorf("schema of an int: got %v, want ErrNotStruct", err)
+	}
+	_, err = gensenc.DecodeDynamic([]byte{1, 2}, gensenc.DescribeType(reflect.TypeFor[dynDoc]()))
+	if !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("truncated input: got %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,58 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// writeRecorder keeps each Write it gets, failing them with err if set.
+type writeRecorder struct {
+	writes [][]byte
+	err    error
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, bytes.Clone(p))
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func TestEncodeValueTo(t *testing.T) {
+	v := order{ID: 1, Items: []string{"a", "b"}, Tags: map[string]int{"x": 1}}
+	want, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &writeRecorder{}
+	n, err := gensenc.EncodeValueTo(w, reflect.ValueOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) || len(w.writes) != 1 || !bytes.Equal(w.writes[0], want) {
+		t.Errorf("wrote %d bytes in %d writes, want %d in 1", n, len(w.writes), len(want))
+	}
+
+	// Nothing is written for a value that can't be encoded.
+	w = &writeRecorder{}
+	bad := struct {
+		ID int
+		C  chan int
+	}{C: make(chan int)}
+	n, err = gensenc.EncodeValueTo(w, reflect.ValueOf(bad))
+	if !errors.Is(err, gensenc.ErrUnsupportedKind) || n != 0 || len(w.writes) != 0 {
+		t.Errorf("got %d bytes in %d writes and %v, want none and ErrUnsupportedKind", n, len(w.writes), err)
+	}
+
+	failed := errors.New("disk full")
+	w = &writeRecorder{err: failed}
+	_, err = gensenc.New(gensenc.WithVarints()).EncodeValueTo(w, reflect.ValueOf(v))
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v, want the writer's error", err)
+	}
+}
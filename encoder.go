@@ -0,0 +1,39 @@
+package gensenc
+
+import (
+	"io"
+	"reflect"
+)
+
+// An Encoder writes consecutive encoded values to a stream.
+type Encoder struct {
+	w io.Writer
+	e encodeState
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return defaultCodec.NewEncoder(w)
+}
+
+// ArrayLengths makes the Encoder precede every array with its length, like
+// a slice. The values must be read by a Decoder with ArrayLengths enabled.
+func (enc *Encoder) ArrayLengths() {
+	enc.e.arrayLens = true
+}
+
+func (enc *Encoder) Encode(a any) error {
+	return enc.EncodeValue(addressable(reflect.ValueOf(a)))
+}
+
+func (enc *Encoder) EncodeValue(v reflect.Value) error {
+	enc.e.buf.Reset()
+	if enc.e.secrets {
+		defer enc.e.scrub()
+	}
+	err := enc.e.encodeRoot(v)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(enc.e.buf.Bytes())
+	return err
+}
@@ -0,0 +1,158 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var ErrUnknownEnum error = errors.New("unknown enum value")
+
+// integer is the constraint satisfied by enum types.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// enumNames holds the names registered for an enum type. Values are kept
+// as the bits of their uint64 conversion.
+type enumNames struct {
+	byName  map[string]uint64
+	byValue map[uint64]string
+}
+
+var enums = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*enumNames
+}{byType: map[reflect.Type]*enumNames{}}
+
+// RegisterEnum registers the names of the values of the enum type T.
+// Fields of type T tagged with enum are encoded as the name of their value
+// rather than the value itself, so constants can be reordered or
+// renumbered between versions as long as their names stay the same.
+// Registering T again replaces its names. RegisterEnum panics if two names
+// share a value.
+func RegisterEnum[T integer](names map[string]T) {
+	n := &enumNames{
+		byName:  make(map[string]uint64, len(names)),
+		byValue: make(map[uint64]string, len(names)),
+	}
+	for name, value := range names {
+		bits := uint64(value)
+		if _, ok := n.byValue[bits]; ok {
+			panic("gensenc: duplicate enum value for " + name)
+		}
+		n.byName[name] = bits
+		n.byValue[bits] = name
+	}
+	enums.Lock()
+	defer enums.Unlock()
+	enums.byType[reflect.TypeFor[T]()] = n
+}
+
+func enumNamesOf(t reflect.Type) *enumNames {
+	enums.RLock()
+	defer enums.RUnlock()
+	return enums.byType[t]
+}
+
+// enumCodec encodes integer fields tagged with enum by the registered name
+// of their value. Names are looked up on use, so types may be registered
+// after their first use in a field.
+type enumCodec struct {
+	t reflect.Type
+}
+
+func newEnumCodec(t reflect.Type) wireCodec {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return enumCodec{t}
+	}
+	return invalidTag{}
+}
+
+// name returns the registered name of the value v.
+func (c enumCodec) name(v reflect.Value) (string, error) {
+	n := enumNamesOf(c.t)
+	if n == nil {
+		return "", ErrUnknownEnum
+	}
+	var bits uint64
+	if v.CanInt() {
+		bits = uint64(v.Int())
+	} else {
+		bits = v.Uint()
+	}
+	name, ok := n.byValue[bits]
+	if !ok {
+		return "", ErrUnknownEnum
+	}
+	return name, nil
+}
+
+// set sets v to the value registered under name.
+func (c enumCodec) set(v reflect.Value, name string) error {
+	n := enumNamesOf(c.t)
+	if n == nil {
+		return ErrUnknownEnum
+	}
+	bits, ok := n.byName[name]
+	if !ok {
+		return ErrUnknownEnum
+	}
+	if v.CanInt() {
+		v.SetInt(int64(bits))
+	} else {
+		v.SetUint(bits)
+	}
+	return nil
+}
+
+func (c enumCodec) encode(e *encodeState, v reflect.Value) error {
+	name, err := c.name(v)
+	if err != nil {
+		return err
+	}
+	e.writeString(name)
+	return nil
+}
+
+func (c enumCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	name, err := d.readString()
+	if err != nil {
+		return err
+	}
+	return c.set(v, name)
+}
+
+func (enumCodec) skip(d *decodeState) error {
+	return d.skip(reflect.TypeFor[string]())
+}
+
+func (enumCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
+
+// writeFormat writes the name of the value as a string, as encode does.
+func (c enumCodec) writeFormat(_ *encodeState, w FormatWriter, v reflect.Value) error {
+	name, err := c.name(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteString(name)
+}
+
+func (c enumCodec) readFormat(_ *decodeState, r FormatReader, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	name, err := r.ReadString()
+	if err != nil {
+		return err
+	}
+	return c.set(v, name)
+}
@@ -0,0 +1,87 @@
+package gensenc_test
+
+import (
+	"errors"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type status uint8
+
+type job struct {
+	ID     int
+	Status status `gensenc:"enum"`
+}
+
+// TestEnumRenumbered encodes an enum, renumbers its constants and decodes
+// it again.
+func TestEnumRenumbered(t *testing.T) {
+	gensenc.RegisterEnum(map[string]status{"queued": 0, "running": 1, "done": 2})
+	b, err := gensenc.Encode(job{ID: 1, Status: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[8:]) != "\x07\x00\x00\x00\x00\x00\x00\x00running" {
+		t.Errorf("encoded as %q, want the name running", b)
+	}
+	gensenc.RegisterEnum(map[string]status{"failed": 0, "queued": 1, "running": 2, "done": 3})
+	var got job
+	err = gensenc.Decode(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (job{ID: 1, Status: 2}) {
+		t.Errorf("got %+v, want running, now 2", got)
+	}
+}
+
+type priority int16
+
+func TestEnumErrors(t *testing.T) {
+	type task struct {
+		P priority `gensenc:"enum"`
+	}
+	// Types may be used before they are registered, but not encoded.
+	_, err := gensenc.Encode(task{P: 1})
+	if !errors.Is(err, gensenc.ErrUnknownEnum) {
+		t.Errorf("encoding an unregistered enum gave %v, want ErrUnknownEnum", err)
+	}
+	gensenc.RegisterEnum(map[string]priority{"low": -1, "high": 1})
+	b, err := gensenc.Encode(task{P: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got task
+	if err := gensenc.Decode(b, &got); err != nil || got.P != -1 {
+		t.Errorf("decoded %+v, %v, want low", got, err)
+	}
+
+	_, err = gensenc.Encode(task{P: 2})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnknownEnum) || ee.Path != "P" {
+		t.Errorf("encoding an unnamed value gave %v, want ErrUnknownEnum at P", err)
+	}
+	b, err = gensenc.Encode("urgent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gensenc.Decode(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrUnknownEnum) || de.Path != "P" {
+		t.Errorf("decoding an unknown name gave %v, want ErrUnknownEnum at P", err)
+	}
+
+	_, err = gensenc.Encode(struct {
+		S string `gensenc:"enum"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("tagging a string gave %v, want ErrInvalidTag", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering two names for a value didn't panic")
+		}
+	}()
+	gensenc.RegisterEnum(map[string]priority{"low": 1, "high": 1})
+}
@@ -0,0 +1,90 @@
+package gensenc
+
+import (
+	"reflect"
+	"time"
+)
+
+// An Envelope wraps an encoded message with the metadata message bus
+// producers and consumers commonly attach to one.
+type Envelope struct {
+	Time          time.Time
+	CorrelationID string
+	// ContentType names the type of the payload. Wrap sets it to the name
+	// the type is registered under.
+	ContentType string
+	Payload     []byte
+}
+
+// Wrap encodes a into the payload of an Envelope stamped with the current
+// time. The type of a must be registered with Register or RegisterName.
+func Wrap(correlationID string, a any) (Envelope, error) {
+	name, ok := registeredName(reflect.TypeOf(a))
+	if !ok {
+		return Envelope{}, ErrUnregisteredType
+	}
+	b, err := Encode(a)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{time.Now(), correlationID, name, b}, nil
+}
+
+// Unwrap decodes the payload as the type registered under ContentType.
+func (env Envelope) Unwrap() (any, error) {
+	t, ok := registeredType(env.ContentType)
+	if !ok {
+		return nil, ErrUnknownType
+	}
+	v := reflect.New(t)
+	err := Decode(env.Payload, v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// envelopeCodec writes the payload of an Envelope as a byte string rather
+// than as a slice of eight-byte integers.
+type envelopeCodec struct{}
+
+func (envelopeCodec) encode(e *encodeState, v reflect.Value) error {
+	err := e.encode(v.Field(0))
+	if err != nil {
+		return err
+	}
+	e.writeString(v.Field(1).String())
+	e.writeString(v.Field(2).String())
+	return bytesOf.encode(e, v.Field(3))
+}
+
+func (envelopeCodec) decode(d *decodeState, v reflect.Value) error {
+	err := d.decode(v.Field(0))
+	if err == nil {
+		err = d.decode(v.Field(1))
+	}
+	if err == nil {
+		err = d.decode(v.Field(2))
+	}
+	if err == nil {
+		err = bytesOf.decode(d, v.Field(3))
+	}
+	return err
+}
+
+func (envelopeCodec) skip(d *decodeState) error {
+	err := d.skip(reflect.TypeFor[time.Time]())
+	for range 2 {
+		if err == nil {
+			err = d.skip(reflect.TypeFor[string]())
+		}
+	}
+	if err == nil {
+		err = bytesOf.skip(d)
+	}
+	return err
+}
+
+func (envelopeCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
@@ -0,0 +1,89 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestEnvelope(t *testing.T) {
+	before := time.Now()
+	env, err := gensenc.Wrap("req-1", pong{1, "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.ContentType != "pong" || env.CorrelationID != "req-1" || env.Time.Before(before) {
+		t.Errorf("wrapped as %+v", env)
+	}
+	body, _ := gensenc.Encode(pong{1, "a"})
+	if !bytes.Equal(env.Payload, body) {
+		t.Errorf("payload is %x, want %x", env.Payload, body)
+	}
+
+	// The payload is written as a byte string.
+	env.Time = time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+	b, err := gensenc.Encode(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp, _ := gensenc.Encode(env.Time)
+	if want := len(stamp) + 8 + 5 + 8 + 4 + 8 + len(body); len(b) != want {
+		t.Errorf("encoded as %d bytes, want %d", len(b), want)
+	}
+	var got gensenc.Envelope
+	if err := gensenc.Decode(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(env.Time) || got.CorrelationID != env.CorrelationID || got.ContentType != env.ContentType || !bytes.Equal(got.Payload, body) {
+		t.Errorf("got %+v, want %+v", got, env)
+	}
+	v, err := got.Unwrap()
+	if err != nil || v != (pong{1, "a"}) {
+		t.Errorf("unwrapped %v, %v", v, err)
+	}
+
+	// Envelopes skip as they encode.
+	var buf bytes.Buffer
+	enc := gensenc.NewEncoder(&buf)
+	for _, v := range []any{env, ping{2}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := gensenc.NewDecoder(&buf)
+	if err := dec.Skip(reflect.TypeFor[gensenc.Envelope]()); err != nil {
+		t.Fatal(err)
+	}
+	var p ping
+	if err := dec.Decode(&p); err != nil || p.Seq != 2 {
+		t.Errorf("decoded %v, %v after the envelope", p, err)
+	}
+}
+
+func TestEnvelopeErrors(t *testing.T) {
+	if _, err := gensenc.Wrap("", order{}); !errors.Is(err, gensenc.ErrUnregisteredType) {
+		t.Errorf("wrapping an unregistered type gave %v, want ErrUnregisteredType", err)
+	}
+	env, err := gensenc.Wrap("", ping{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := env
+	unknown.ContentType = "nope"
+	if _, err := unknown.Unwrap(); !errors.Is(err, gensenc.ErrUnknownType) {
+		t.Errorf("unwrapping an unknown type gave %v, want ErrUnknownType", err)
+	}
+	env.Payload = env.Payload[:4]
+	if _, err := env.Unwrap(); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("unwrapping a truncated payload gave %v, want ErrTruncated", err)
+	}
+	b, _ := gensenc.Encode(env)
+	var got gensenc.Envelope
+	if err := gensenc.Decode(b[:len(b)-1], &got); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a truncated envelope gave %v, want ErrInvalidLength", err)
+	}
+}
@@ -0,0 +1,45 @@
+package gensenc
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+)
+
+var errUnequal = errors.New("encodings differ")
+
+// prefixWriter checks that what is written to it continues b.
+type prefixWriter struct {
+	b []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if !bytes.HasPrefix(w.b, p) {
+		return 0, errUnequal
+	}
+	w.b = w.b[len(p):]
+	return len(p), nil
+}
+
+// EqualEncoded reports whether a and b have the same canonical encoding, as
+// with WithCanonical. Unlike reflect.DeepEqual it ignores unexported
+// fields and compares what is encoded, such as the placeholder of redacted
+// fields. The encoding of b is compared to that of a as it is produced,
+// stopping at the first difference.
+func EqualEncoded(a, b any) (bool, error) {
+	var buf bytes.Buffer
+	opts := options{canonical: true}
+	err := encodeStream(&buf, opts, addressable(reflect.ValueOf(a)))
+	if err != nil {
+		return false, err
+	}
+	w := &prefixWriter{buf.Bytes()}
+	err = encodeStream(w, opts, addressable(reflect.ValueOf(b)))
+	if errors.Is(err, errUnequal) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(w.b) == 0, nil
+}
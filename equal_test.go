@@ -0,0 +1,51 @@
+package gensenc_test
+
+import (
+	"errors"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type compared struct {
+	A      int
+	hidden int
+	M      map[int]string
+	S      []int
+	Secret string `gensenc:"redact"`
+}
+
+func TestEqualEncoded(t *testing.T) {
+	long := make([]int, 100000)
+	changed := make([]int, len(long))
+	changed[0] = 1
+	for _, c := range []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{"equal", compared{A: 1, M: map[int]string{1: "a", 2: "b"}}, compared{A: 1, M: map[int]string{2: "b", 1: "a"}}, true},
+		{"pointer", compared{A: 1}, &compared{A: 1}, true},
+		{"unencoded", compared{hidden: 1, S: []int{}, Secret: "x"}, compared{hidden: 2, Secret: "y"}, true},
+		{"field", compared{A: 1}, compared{A: 2}, false},
+		{"map", compared{M: map[int]string{1: "a"}}, compared{M: map[int]string{1: "b"}}, false},
+		{"first", long, changed, false},
+		{"shorter", long, long[:len(long)-1], false},
+		{"longer", long[:len(long)-1], long, false},
+	} {
+		got, err := gensenc.EqualEncoded(c.a, c.b)
+		if err != nil || got != c.want {
+			t.Errorf("%s: got %v, %v, want %v", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestEqualEncodedErrors(t *testing.T) {
+	bad := struct{ C chan int }{}
+	if _, err := gensenc.EqualEncoded(bad, bad); !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("comparing channels gave %v, want ErrUnsupportedKind", err)
+	}
+	if _, err := gensenc.EqualEncoded(1, make(chan int)); !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("comparing to a channel gave %v, want ErrUnsupportedKind", err)
+	}
+}
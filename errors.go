@@ -0,0 +1,184 @@
+package gensenc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Errors returned while decoding are wrapped in a *DecodeError; use
+// errors.Is to test for them.
+var (
+	ErrCantSet         error = errors.New("cannot set")
+	ErrUnsupportedKind error = errors.New("unsupported kind")
+	ErrInvalidLength   error = errors.New("invalid length")
+	ErrOverflow        error = errors.New("value overflows destination type")
+	ErrNilPointer      error = errors.New("nil pointer")
+	ErrMalformed       error = errors.New("malformed input")
+	ErrArrayLength     error = errors.New("array length mismatch")
+	ErrInvalidTag      error = errors.New("tag option does not apply to the field type")
+
+	// ErrAddress is reported for uintptr and unsafe.Pointer values, which
+	// hold memory addresses that mean nothing outside the process. It wraps
+	// ErrUnsupportedKind.
+	ErrAddress error = fmt.Errorf("%w: uintptr or unsafe.Pointer address, exclude the field with a gensenc:\"-\" tag", ErrUnsupportedKind)
+
+	// ErrTruncated is io.ErrUnexpectedEOF, so existing checks for the
+	// latter keep working. Input that ends before a value starts is
+	// reported as a plain io.EOF instead.
+	ErrTruncated error = io.ErrUnexpectedEOF
+)
+
+// A DecodeError describes where decoding failed.
+type DecodeError struct {
+	Err error
+	// Offset is the number of input bytes consumed when decoding failed.
+	Offset int64
+	// Path locates the failing value within the decoded one, as in
+	// "Items[3].Name". It is empty for the decoded value itself.
+	Path string
+
+	// elems holds the elements of the path recorded by at, innermost
+	// first, until guard prepends them to Path. Joining them once keeps
+	// errors in deeply nested values cheap.
+	elems []string
+}
+
+func (e *DecodeError) Error() string {
+	s := "decode"
+	if e.Path != "" {
+		s += " " + e.Path
+	}
+	return s + " at offset " + strconv.FormatInt(e.Offset, 10) + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// An EncodeError describes where encoding a value failed.
+type EncodeError struct {
+	Err error
+	// Path locates the failing value within the encoded one, as in
+	// DecodeError, with map entries selected by the fmt.Sprint form of
+	// their key.
+	Path string
+
+	elems []string
+}
+
+func (e *EncodeError) Error() string {
+	return "encode " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// at records that err occurred while encoding the element elem of the
+// current value, like decodeState.at.
+func (e *encodeState) at(err error, elem string) error {
+	ee, ok := err.(*EncodeError)
+	if !ok {
+		ee = &EncodeError{Err: err}
+	}
+	ee.elems = append(ee.elems, elem)
+	return ee
+}
+
+// joinPath completes the path of an *EncodeError returned from encoding a
+// top-level value. Errors of the value itself are returned unwrapped.
+func joinPath(err error) error {
+	ee, ok := err.(*EncodeError)
+	if ok && ee.elems != nil {
+		ee.Path = joinElems(ee.elems, ee.Path)
+		ee.elems = nil
+	}
+	return err
+}
+
+// maxPathElems bounds the elements of error paths, which for values nested
+// up to the depth limit, such as cyclic ones, would run to thousands.
+const maxPathElems = 64
+
+// joinElems returns the path made of the elements elems, innermost first,
+// followed by path. Of paths longer than maxPathElems, only the first and
+// last elements are kept, around the number of those left out, as in
+// "Next.Next...(9936 more).Next".
+func joinElems(elems []string, path string) string {
+	slices.Reverse(elems)
+	if n := len(elems) - maxPathElems; n > 0 {
+		elems = slices.Concat(elems[:maxPathElems/2], []string{"...(" + strconv.Itoa(n) + " more)"}, elems[len(elems)-maxPathElems/2:])
+	}
+	return strings.TrimPrefix(strings.Join(elems, "")+path, ".")
+}
+
+// at records that err occurred while decoding the element elem, such as
+// ".Name" or "[3]", of the current value.
+func (d *decodeState) at(err error, elem string) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		de = &DecodeError{Err: err, Offset: d.offset()}
+	}
+	de.elems = append(de.elems, elem)
+	return de
+}
+
+func index(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// A PanicError reports a panic recovered while decoding, whether malformed
+// input made reflection panic or a method of a decoded type did. It
+// matches ErrMalformed with errors.Is, as well as the panic value if that
+// is an error.
+type PanicError struct {
+	Value any
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return ErrMalformed.Error() + ": panic: " + fmt.Sprint(e.Value)
+}
+
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrMalformed, err}
+	}
+	return []error{ErrMalformed}
+}
+
+// guard runs f, which decodes a top-level value, and turns its error into
+// the one reported to the caller. decode checks its input before acting on
+// it, but should malformed input still make reflection panic, the panic is
+// reported as a *PanicError.
+func (d *decodeState) guard(f func() error) (err error) {
+	start := d.offset()
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+		if err == nil || errors.Is(err, io.EOF) && d.offset() == start {
+			if err != nil {
+				err = io.EOF
+			}
+			return
+		}
+		de, ok := err.(*DecodeError)
+		if !ok {
+			de = &DecodeError{Err: err, Offset: d.offset()}
+		}
+		if de.Err == io.EOF {
+			de.Err = ErrTruncated
+		}
+		de.Path = joinElems(de.elems, de.Path)
+		de.elems = nil
+		err = de
+	}()
+	return f()
+}
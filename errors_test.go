@@ -0,0 +1,183 @@
+package gensenc_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type errInner struct{ V []int8 }
+
+type errOuter struct {
+	A int
+	M map[string]errInner
+	P *errInner
+}
+
+// errWide encodes like errOuter, with elements too large for it.
+type errWide struct {
+	A int
+	M map[string]struct{ V []int }
+	P *struct{ V []int }
+}
+
+func TestDecodeErrors(t *testing.T) {
+	inMap, err := gensenc.Encode(errWide{M: map[string]struct{ V []int }{"k": {V: []int{1, 300}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	behind, err := gensenc.Encode(errWide{M: map[string]struct{ V []int }{}, P: &struct{ V []int }{V: []int{1000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		b      []byte
+		into   any
+		want   error
+		path   string
+		offset int64
+		msg    string
+	}{
+		{"map entry", inMap, new(errOuter), gensenc.ErrOverflow, "M[k].V[1]", 49,
+			"decode M[k].V[1] at offset 49: value overflows destination type"},
+		{"pointer", behind, new(errOuter), gensenc.ErrOverflow, "P.V[0]", 33,
+			"decode P.V[0] at offset 33: value overflows destination type"},
+		{"truncated", behind[:5], new(errOuter), gensenc.ErrTruncated, "A", 5,
+			"decode A at offset 5: unexpected EOF"},
+		{"nil", behind, nil, gensenc.ErrNilPointer, "", 0,
+			"decode at offset 0: nil pointer"},
+		{"not a pointer", behind, errOuter{}, gensenc.ErrCantSet, "A", 0,
+			"decode A at offset 0: cannot set"},
+	} {
+		err := gensenc.Decode(tt.b, tt.into)
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want a *DecodeError wrapping %v", tt.name, err, tt.want)
+			continue
+		}
+		if de.Path != tt.path || de.Offset != tt.offset || err.Error() != tt.msg {
+			t.Errorf("%s: got %q at %q, offset %d; want %q", tt.name, err, de.Path, de.Offset, tt.msg)
+		}
+	}
+	// Input ending before a value starts is distinguished from a truncated
+	// value, for reading streams until their end.
+	if err := gensenc.Decode(nil, new(errOuter)); err != io.EOF {
+		t.Errorf("decoding no input gave %v, want io.EOF", err)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	_, err := gensenc.Encode(struct{ M map[int]func() }{M: map[int]func(){3: nil}})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Fatalf("encoding a func gave %v, want an *EncodeError wrapping ErrUnsupportedKind", err)
+	}
+	if ee.Path != "M[3]" || err.Error() != "encode M[3]: unsupported kind" {
+		t.Errorf("got %q at %q", err, ee.Path)
+	}
+	_, err = gensenc.Encode(struct{ P uintptr }{})
+	if !errors.Is(err, gensenc.ErrAddress) || !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("encoding a uintptr gave %v, want ErrAddress", err)
+	}
+	if _, err = gensenc.Encode(nil); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("encoding nil gave %v, want ErrNilPointer", err)
+	}
+}
+
+type (
+	chanInner struct {
+		C chan int
+	}
+	chanOuter struct {
+		A    int
+		S    []chanInner
+		Skip chan int `gensenc:"-"`
+		F    func()   `gensenc:"-"`
+	}
+)
+
+// TestUnsupportedKinds checks that channels and functions fail where they
+// are unless tagged "-".
+func TestUnsupportedKinds(t *testing.T) {
+	_, err := gensenc.Encode(chanOuter{S: []chanInner{{}, {}}})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnsupportedKind) || ee.Path != "S[0].C" {
+		t.Errorf("got %v, want ErrUnsupportedKind at S[0].C", err)
+	}
+	if _, err := gensenc.Encode(make(chan int)); err != gensenc.ErrUnsupportedKind {
+		t.Errorf("encoding a channel gave %v, want ErrUnsupportedKind itself", err)
+	}
+	// A of 2 and S of one element.
+	var v chanOuter
+	b, _ := gensenc.Encode([]int{1, 0})
+	err = gensenc.Decode(b, &v)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrUnsupportedKind) || de.Path != "S[0].C" {
+		t.Errorf("decoding channels gave %v, want ErrUnsupportedKind at S[0].C", err)
+	}
+
+	skipped := struct {
+		A int
+		C chan int `gensenc:"-"`
+		F func()   `gensenc:"-"`
+	}{A: 3, C: make(chan int), F: func() {}}
+	b, err = gensenc.Encode(skipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\x03\x00\x00\x00\x00\x00\x00\x00" {
+		t.Errorf("encoded as %x, want A alone", b)
+	}
+	got := skipped
+	got.A = 0
+	if err := gensenc.Decode(b, &got); err != nil || got.A != 3 || got.C == nil {
+		t.Errorf("decoding gave %+v, %v, want A and the fields tagged \"-\" kept", got, err)
+	}
+}
+
+type addressed struct {
+	A int
+	P unsafe.Pointer
+	U uintptr `gensenc:"-"`
+}
+
+// TestAddressKinds checks that uintptr and unsafe.Pointer values fail
+// with ErrAddress where they are unless tagged "-".
+func TestAddressKinds(t *testing.T) {
+	x := 1
+	_, err := gensenc.Encode(addressed{1, unsafe.Pointer(&x), 5})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrAddress) || ee.Path != "P" {
+		t.Errorf("got %v, want ErrAddress at P", err)
+	}
+	if !strings.Contains(err.Error(), `gensenc:"-"`) {
+		t.Errorf("%q doesn't suggest the tag", err)
+	}
+
+	b := make([]byte, 16)
+	var v addressed
+	err = gensenc.Decode(b, &v)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrAddress) || de.Path != "P" {
+		t.Errorf("decoding gave %v, want ErrAddress at P", err)
+	}
+	if err := gensenc.Validate(b, reflect.TypeFor[addressed]()); !errors.Is(err, gensenc.ErrAddress) {
+		t.Errorf("validating gave %v, want ErrAddress", err)
+	}
+
+	skipped := struct {
+		A int
+		U uintptr        `gensenc:"-"`
+		P unsafe.Pointer `gensenc:"-"`
+	}{1, 2, unsafe.Pointer(&x)}
+	b, err = gensenc.Encode(skipped)
+	if err != nil || len(b) != 8 {
+		t.Errorf("encoded as %x, %v, want A alone", b, err)
+	}
+}
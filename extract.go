@@ -0,0 +1,184 @@
+package gensenc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath error = errors.New("invalid field path")
+	ErrNoElement   error = errors.New("no element at index or key")
+)
+
+// ExtractField decodes the value at path within b, holding a value of type
+// t, skipping over everything else. Paths are written like those of
+// DecodeError, as in "Items[3].Name", and may index slices and arrays and
+// select map values by the fmt.Sprint form of their key. Pointers on the
+// path must be set, and fields with tag options or types with a dedicated
+// encoding can't be looked into.
+func ExtractField(b []byte, t reflect.Type, path string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	elems, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	var v reflect.Value
+	err = d.guard(func() error {
+		var err error
+		v, err = d.extract(t, elems)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// splitPath splits a path into field names, prefixed with ".", and
+// indices or keys in brackets.
+func splitPath(path string) ([]string, error) {
+	var elems []string
+	if path != "" && path[0] != '[' {
+		path = "." + path
+	}
+	for path != "" {
+		var n int
+		switch path[0] {
+		case '.':
+			n = strings.IndexAny(path[1:], ".[") + 1
+			if n == 0 {
+				n = len(path)
+			}
+			if n == 1 {
+				return nil, ErrInvalidPath
+			}
+		case '[':
+			n = strings.IndexByte(path, ']') + 1
+			if n == 0 {
+				return nil, ErrInvalidPath
+			}
+		default:
+			return nil, ErrInvalidPath
+		}
+		elems = append(elems, path[:n])
+		path = path[n:]
+	}
+	return elems, nil
+}
+
+func (d *decodeState) extract(t reflect.Type, path []string) (reflect.Value, error) {
+	if len(path) == 0 {
+		v := reflect.New(t).Elem()
+		return v, d.decode(v)
+	}
+	elem := path[0]
+	if infoOf(t).codec != nil {
+		return reflect.Value{}, ErrInvalidPath
+	}
+	if t.Kind() == reflect.Pointer {
+		present, err := d.readPresence()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !present {
+			return reflect.Value{}, ErrNilPointer
+		}
+		return d.extract(t.Elem(), path)
+	}
+	if elem[0] == '.' {
+		if t.Kind() != reflect.Struct {
+			return reflect.Value{}, ErrInvalidPath
+		}
+		for _, f := range infoOf(t).fields {
+			if f.name != elem[1:] {
+				err := d.skipField(&f)
+				if err != nil {
+					return reflect.Value{}, d.at(err, "."+f.name)
+				}
+				continue
+			}
+			if f.codec != nil {
+				if len(path) > 1 {
+					return reflect.Value{}, ErrInvalidPath
+				}
+				v := reflect.New(f.typ).Elem()
+				return v, d.decodeField(&f, v)
+			}
+			v, err := d.extract(f.typ, path[1:])
+			if err != nil {
+				return v, d.at(err, elem)
+			}
+			return v, nil
+		}
+		return reflect.Value{}, ErrUnknownField
+	}
+	key := elem[1 : len(elem)-1]
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(key)
+		if err != nil {
+			return reflect.Value{}, ErrInvalidPath
+		}
+		var length uint64
+		if t.Kind() == reflect.Slice {
+			length, err = d.readUint64()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+		} else {
+			length = uint64(t.Len())
+		}
+		if i < 0 || uint64(i) >= length {
+			return reflect.Value{}, ErrNoElement
+		}
+		if s := d.size(infoOf(t.Elem())); s >= 0 {
+			err = d.discard(uint64(i) * uint64(s))
+		} else {
+			for j := range i {
+				err = d.skip(t.Elem())
+				if err != nil {
+					return reflect.Value{}, d.at(err, index(j))
+				}
+			}
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v, err := d.extract(t.Elem(), path[1:])
+		if err != nil {
+			return v, d.at(err, elem)
+		}
+		return v, nil
+	case reflect.Map:
+		length, err := d.readUint64()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		for i := range length {
+			k := reflect.New(t.Key()).Elem()
+			err = d.decode(k)
+			if err != nil {
+				return reflect.Value{}, d.at(err, index(int(i)))
+			}
+			if fmt.Sprint(k) == key {
+				v, err := d.extract(t.Elem(), path[1:])
+				if err != nil {
+					return v, d.at(err, elem)
+				}
+				return v, nil
+			}
+			err = d.skip(t.Elem())
+			if err != nil {
+				return reflect.Value{}, d.at(err, "["+fmt.Sprint(k)+"]")
+			}
+		}
+		return reflect.Value{}, ErrNoElement
+	}
+	return reflect.Value{}, ErrInvalidPath
+}
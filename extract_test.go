@@ -0,0 +1,105 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type shipment struct {
+	ID      uint64
+	Orders  []order
+	ByCode  map[int]point
+	Grid    [2][2]int16
+	Parent  *shipment
+	Carrier string
+}
+
+func shipmentBytes(t *testing.T) []byte {
+	t.Helper()
+	note := "fragile"
+	b, err := gensenc.Encode(shipment{
+		ID: 1,
+		Orders: []order{
+			{ID: 10, Items: []string{"a"}, Tags: map[string]int{}},
+			{ID: 11, Items: []string{"b", "c"}, Tags: map[string]int{"x": 1}, Note: &note},
+		},
+		ByCode:  map[int]point{7: {1, 2}},
+		Grid:    [2][2]int16{{1, 2}, {3, 4}},
+		Parent:  &shipment{ID: 0, ByCode: map[int]point{}, Carrier: "up"},
+		Carrier: "acme",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestExtractField(t *testing.T) {
+	b := shipmentBytes(t)
+	typ := reflect.TypeFor[shipment]()
+	note := "fragile"
+	for _, tt := range []struct {
+		path string
+		want any
+	}{
+		{"ID", uint64(1)},
+		{"Carrier", "acme"},
+		{"Orders[1].Items[1]", "c"},
+		{"Orders[1].Note", &note},
+		{"Orders[1].Tags[x]", 1},
+		{"ByCode[7].Y", int32(2)},
+		{"Grid[1][0]", int16(3)},
+		{"Parent.Carrier", "up"},
+		{"Orders[0]", order{ID: 10, Items: []string{"a"}, Tags: map[string]int{}}},
+	} {
+		got, err := gensenc.ExtractField(b, typ, tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+	// The empty path is the whole value.
+	got, err := gensenc.ExtractField(b, typ, "")
+	if s, ok := got.(shipment); err != nil || !ok || s.Carrier != "acme" {
+		t.Errorf("the empty path gave %+v, %v", got, err)
+	}
+}
+
+func TestExtractFieldErrors(t *testing.T) {
+	b := shipmentBytes(t)
+	typ := reflect.TypeFor[shipment]()
+	for _, tt := range []struct {
+		path string
+		want error
+	}{
+		{"Missing", gensenc.ErrUnknownField},
+		{"Orders[2]", gensenc.ErrNoElement},
+		{"Orders[-1]", gensenc.ErrNoElement},
+		{"ByCode[8]", gensenc.ErrNoElement},
+		{"Orders[x]", gensenc.ErrInvalidPath},
+		{"Orders[1", gensenc.ErrInvalidPath},
+		{"ID.X", gensenc.ErrInvalidPath},
+		{"Carrier[0]", gensenc.ErrInvalidPath},
+		{"Orders..ID", gensenc.ErrInvalidPath},
+		{"Parent.Parent.ID", gensenc.ErrNilPointer},
+	} {
+		if _, err := gensenc.ExtractField(b, typ, tt.path); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.path, err, tt.want)
+		}
+	}
+	// Fields after the one wanted aren't read, those before it are.
+	if _, err := gensenc.ExtractField(b[:9], typ, "ID"); err != nil {
+		t.Errorf("extracting the first field of a truncated value: %v", err)
+	}
+	_, err := gensenc.ExtractField(b[:len(b)-1], typ, "Carrier")
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || de.Path != "Carrier" {
+		t.Errorf("extracting a truncated field gave %v, want an error at Carrier", err)
+	}
+}
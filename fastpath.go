@@ -0,0 +1,83 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"reflect"
+)
+
+var littleEndianHost = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// computeMemLayout reports whether the in-memory representation of t is
+// byte for byte identical to its wire encoding, so that values can be
+// copied to and from the wire without per-field reflection. This is only
+// the case on little-endian hosts, for 8-byte integers, floats and
+// complex numbers, and for arrays and structs of those without unexported
+// fields or padding, and never in builds without unsafe memory access.
+func computeMemLayout(t reflect.Type) bool {
+	if !rawMemory || !littleEndianHost || typeCodecs[t] != nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint:
+		return t.Size() == 8
+	case reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return computeMemLayout(t.Elem())
+	case reflect.Struct:
+		var off uintptr
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			opts := parseTag(f.Tag.Get("gensenc"))
+			if !encoded(f) || f.Offset != off || !computeMemLayout(f.Type) || newFieldCodec(f.Type, opts) != nil ||
+				opts.section() != "" || opts.has("idx") {
+				return false
+			}
+			off += f.Type.Size()
+		}
+		return off == t.Size()
+	}
+	return false
+}
+
+// intElems reports whether the slice or array type t has integer elements
+// in the default encoding, which encodeInts and decodeInts handle in one
+// loop instead of going through encode and decode for every element.
+func intElems(t reflect.Type) bool {
+	return integerClass(t.Elem().Kind()) != 0 && typeCodecs[t.Elem()] == nil
+}
+
+func (e *encodeState) encodeInts(v reflect.Value) error {
+	signed := integerClass(v.Type().Elem().Kind()) == 1
+	for i := range v.Len() {
+		err := e.tick()
+		if err != nil {
+			return err
+		}
+		e.writeInteger(integerBits(v.Index(i)), signed)
+	}
+	return nil
+}
+
+// decodeInts decodes the elements of v, which start at index first of the
+// decoded value.
+func (d *decodeState) decodeInts(v reflect.Value, first int) error {
+	if v.Len() > 0 && !v.Index(0).CanSet() {
+		return ErrCantSet
+	}
+	signed := integerClass(v.Type().Elem().Kind()) == 1
+	for i := range v.Len() {
+		err := d.tick()
+		if err != nil {
+			return err
+		}
+		x, err := d.readInteger(signed)
+		if err == nil {
+			err = d.setInteger(v.Index(i), x)
+		}
+		if err != nil {
+			return d.at(err, index(first+i))
+		}
+	}
+	return nil
+}
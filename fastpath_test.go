@@ -0,0 +1,158 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var gridCodecs = []struct {
+	name  string
+	codec *gensenc.Codec
+}{
+	{"default", gensenc.New()},
+	{"varints", gensenc.New(gensenc.WithVarints())},
+	{"big endian", gensenc.New(gensenc.WithBigEndian())},
+	{"array lengths", gensenc.New(gensenc.WithArrayLengths())},
+}
+
+type grid struct {
+	Rows  [][]int32
+	Cells [2][3]uint16
+	Row   *[4]int64
+	None  *[4]int64
+	Names [2][2]string
+}
+
+func roundTrip[T any](t *testing.T, c *gensenc.Codec, v T) T {
+	t.Helper()
+	b, err := c.Encode(v)
+	if err != nil {
+		t.Fatalf("encoding %T: %v", v, err)
+	}
+	var got T
+	err = c.Decode(b, &got)
+	if err != nil {
+		t.Fatalf("decoding %T: %v", v, err)
+	}
+	return got
+}
+
+func TestGridRoundTrip(t *testing.T) {
+	row := [4]int64{-1, 0, 1 << 40, -1 << 62}
+	for _, c := range gridCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			// Empty rows decode as nil ones.
+			slices := [][]int{{1, -2, 3}, nil, {}, {1 << 50}}
+			want := [][]int{{1, -2, 3}, nil, nil, {1 << 50}}
+			if got := roundTrip(t, c.codec, slices); !reflect.DeepEqual(got, want) {
+				t.Errorf("[][]int: got %#v, want %#v", got, want)
+			}
+			floats := [][]float64{{1.5, -2}, {3}}
+			if got := roundTrip(t, c.codec, floats); !reflect.DeepEqual(got, floats) {
+				t.Errorf("[][]float64: got %v, want %v", got, floats)
+			}
+			matrix := [3][4]int8{{1, 2, 3, 4}, {-5, -6, -7, -8}, {127, -128, 0, 1}}
+			if got := roundTrip(t, c.codec, matrix); got != matrix {
+				t.Errorf("[3][4]int8: got %v, want %v", got, matrix)
+			}
+			if got := roundTrip(t, c.codec, &row); got == nil || *got != row {
+				t.Errorf("*[4]int64: got %v, want %v", got, row)
+			}
+			g := grid{
+				Rows:  [][]int32{{1, 2}, {-3}},
+				Cells: [2][3]uint16{{1, 2, 3}, {4, 5, 65535}},
+				Row:   &row,
+				Names: [2][2]string{{"a", "b"}, {"", "d"}},
+			}
+			if got := roundTrip(t, c.codec, g); !reflect.DeepEqual(got, g) {
+				t.Errorf("grid: got %+v, want %+v", got, g)
+			}
+		})
+	}
+}
+
+// TestGridUnaddressable checks that arrays passed by value, which can't be
+// copied from memory directly, encode as those reached through a pointer.
+func TestGridUnaddressable(t *testing.T) {
+	matrix := [2][3]uint32{{1, 2, 3}, {4, 5, 6}}
+	for _, c := range gridCodecs {
+		t.Run(c.name, func(t *testing.T) {
+			byValue, err := c.codec.Encode(matrix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byPointer, err := c.codec.Encode(&matrix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(byValue, byPointer) {
+				t.Errorf("encoded by value as %x, by pointer as %x", byValue, byPointer)
+			}
+		})
+	}
+}
+
+func TestGridOverflow(t *testing.T) {
+	b, err := gensenc.Encode([][]int{{1}, {2, 300}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]int8
+	err = gensenc.Decode(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) {
+		t.Fatalf("decoding 300 into an int8 gave %v; want ErrOverflow", err)
+	}
+	if de.Path != "[1][1]" {
+		t.Errorf("error at %q, want [1][1]", de.Path)
+	}
+}
+
+type sample struct {
+	T     int64
+	Value float64
+	Range [2]uint64
+	Phase complex128
+}
+
+// TestMemLayout checks that values whose memory matches the wire, copied as
+// raw memory where the build allows, are written field by field little-
+// endian, and read back the same way, whole slices of them included.
+func TestMemLayout(t *testing.T) {
+	v := []sample{
+		{T: -1, Value: 1.5, Range: [2]uint64{1, 1 << 63}, Phase: complex(1, -2)},
+		{T: 1 << 40, Value: math.Inf(-1)},
+	}
+	want := binary.LittleEndian.AppendUint64(nil, uint64(len(v)))
+	for _, s := range v {
+		want = binary.LittleEndian.AppendUint64(want, uint64(s.T))
+		want = binary.LittleEndian.AppendUint64(want, math.Float64bits(s.Value))
+		want = binary.LittleEndian.AppendUint64(want, s.Range[0])
+		want = binary.LittleEndian.AppendUint64(want, s.Range[1])
+		want = binary.LittleEndian.AppendUint64(want, math.Float64bits(real(s.Phase)))
+		want = binary.LittleEndian.AppendUint64(want, math.Float64bits(imag(s.Phase)))
+	}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("encoded as %x, want %x", b, want)
+	}
+	if got := roundTrip(t, gensenc.New(), v); !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	// The slice is too long for the input left, as its elements have a
+	// fixed size.
+	var got []sample
+	err = gensenc.Decode(b[:len(b)-1], &got)
+	if !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding truncated input gave %v, want ErrInvalidLength", err)
+	}
+}
@@ -0,0 +1,92 @@
+package gensenc
+
+import (
+	"reflect"
+)
+
+// compressCodec encodes string and byte slice fields tagged compress=name
+// compressed with the Compressor registered under that name, such as gzip
+// or, once registered with RegisterCompressor, zstd. They are written as a
+// byte string holding the payload of EncodeCompressed, or the uncompressed
+// payload if compressing doesn't make it smaller, so decoding needs no
+// name, only the compressor being registered.
+type compressCodec struct {
+	name string
+}
+
+func newCompressCodec(t reflect.Type, name string) wireCodec {
+	if t.Kind() != reflect.String && (t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8) {
+		return invalidTag{}
+	}
+	return compressCodec{name}
+}
+
+func (c compressCodec) encode(e *encodeState, v reflect.Value) error {
+	compressors.RLock()
+	comp, ok := compressors.byName[c.name]
+	compressors.RUnlock()
+	if !ok {
+		return ErrUnknownCompressor
+	}
+	var b []byte
+	if v.Kind() == reflect.String {
+		b = []byte(v.String())
+	} else {
+		b = v.Bytes()
+	}
+	out, err := compress(comp, b)
+	if err != nil {
+		return err
+	}
+	if len(out) > len(b) {
+		out = append([]byte{0}, b...)
+	}
+	e.writeUint64(uint64(len(out)))
+	e.buf.Write(out)
+	return nil
+}
+
+func (c compressCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	b, err := d.readBytes()
+	if err != nil {
+		return err
+	}
+	b, err = decompress(b, d.decompressLimit())
+	if err == nil {
+		err = d.charge(uint64(len(b)), 1)
+	}
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(string(b))
+	} else {
+		bytesOf.unmarshal(v, b)
+	}
+	return nil
+}
+
+// decompressLimit returns the size past which compressed fields fail to
+// decompress: the length limit of WithMaxLength, what remains of the budget
+// of WithMaxDecodedBytes or, without either, maxDecompressed.
+func (d *decodeState) decompressLimit() int {
+	limit := maxDecompressed
+	if d.maxLength > 0 {
+		limit = min(limit, d.maxLength)
+	}
+	if d.maxDecoded > 0 {
+		limit = min(limit, d.maxDecoded-d.decoded)
+	}
+	return limit
+}
+
+func (compressCodec) skip(d *decodeState) error {
+	return bytesOf.skip(d)
+}
+
+func (compressCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
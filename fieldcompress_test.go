@@ -0,0 +1,77 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type document struct {
+	ID   int
+	Body string `gensenc:"compress=gzip"`
+	Blob []byte `gensenc:"compress=flate"`
+	Tiny string `gensenc:"compress=gzip"`
+}
+
+func TestFieldCompress(t *testing.T) {
+	d := document{1, strings.Repeat("hello ", 10000), bytes.Repeat([]byte("x"), 5000), "a"}
+	b, err := gensenc.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > 1000 {
+		t.Errorf("encoded %d bytes of repetitive fields as %d", len(d.Body)+len(d.Blob), len(b))
+	}
+	// Fields compressing to more bytes are stored.
+	if tiny := b[len(b)-10:]; string(tiny) != "\x02\x00\x00\x00\x00\x00\x00\x00\x00a" {
+		t.Errorf("stored the tiny field as %x", tiny)
+	}
+	if got := roundTrip(t, gensenc.New(), d); !reflect.DeepEqual(got, d) {
+		t.Error("value changed in a round trip")
+	}
+	if err := gensenc.Validate(b, reflect.TypeFor[document]()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFieldCompressErrors(t *testing.T) {
+	d := document{Body: strings.Repeat("hello ", 10000)}
+	b, err := gensenc.Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got document
+	err = gensenc.New(gensenc.WithMaxLength(1000)).Decode(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrLimitExceeded) || de.Path != "Body" {
+		t.Errorf("decompressing past the length limit gave %v, want ErrLimitExceeded at Body", err)
+	}
+	if err := gensenc.New(gensenc.WithMaxDecodedBytes(1000)).Decode(b, &got); !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decompressing past the budget gave %v, want ErrLimitExceeded", err)
+	}
+	// A payload of an unknown compressor.
+	unknown := "\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\xfea"
+	if err := gensenc.Decode([]byte(unknown), &got); !errors.Is(err, gensenc.ErrUnknownCompressor) {
+		t.Errorf("decoding an unknown compressor gave %v, want ErrUnknownCompressor", err)
+	}
+
+	for _, c := range []struct {
+		v    any
+		want error
+	}{
+		{struct {
+			N int `gensenc:"compress=gzip"`
+		}{}, gensenc.ErrInvalidTag},
+		{struct {
+			S string `gensenc:"compress=none"`
+		}{}, gensenc.ErrUnknownCompressor},
+	} {
+		if _, err := gensenc.Encode(c.v); !errors.Is(err, c.want) {
+			t.Errorf("encoding %T gave %v, want %v", c.v, err, c.want)
+		}
+	}
+}
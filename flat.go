@@ -0,0 +1,485 @@
+package gensenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrInvalidOffset error = errors.New("invalid offset")
+	ErrOutOfRange    error = errors.New("index out of range")
+	ErrNotIndexable  error = errors.New("not a slice, array or map")
+)
+
+// The flat format stores every struct as a table of field offsets followed
+// by the fields, and every slice, array and map as an element count, a
+// table of element offsets and the elements (maps alternate keys and
+// values). Offsets are relative to the start of the enclosing value.
+// Nested pointers are written as a presence byte followed, if not nil, by
+// the value they point to. Values of types with their own encoding, such
+// as time.Time, and fields with tag options changing theirs use the
+// regular encoding, as do all other values. This allows FlatValue to
+// reach any nested value without decoding what precedes it.
+
+func flatTable(header []byte, parts [][]byte) []byte {
+	off := uint64(len(header) + 8*len(parts))
+	b := append([]byte(nil), header...)
+	for _, p := range parts {
+		b = binary.LittleEndian.AppendUint64(b, off)
+		off += uint64(len(p))
+	}
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// flattened reports whether values of t are stored as tables in the flat
+// format.
+func flattened(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return infoOf(t).codec == nil
+	}
+	return false
+}
+
+// flatEncode returns the flat encoding of v. Every nested value counts
+// against the depth limit like in encode, so that cyclic values fail.
+func (e *encodeState) flatEncode(v reflect.Value) ([]byte, error) {
+	if e.depth >= e.depthLimit() {
+		return nil, ErrMaxDepth
+	}
+	e.depth++
+	b, err := e.flatEncodeValue(v)
+	e.depth--
+	return b, err
+}
+
+// encodeRegular returns the regular encoding of v, or of the field f of a
+// struct if f is not nil.
+func (e *encodeState) encodeRegular(f *fieldInfo, v reflect.Value) ([]byte, error) {
+	r := &encodeState{buf: bytes.NewBuffer(nil), options: e.options, depth: e.depth}
+	var err error
+	if f != nil {
+		err = r.encodeField(f, v)
+	} else {
+		err = r.encode(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.buf.Bytes(), nil
+}
+
+func (e *encodeState) flatEncodeValue(v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return []byte{0}, nil
+		}
+		b, err := e.flatEncode(v.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{1}, b...), nil
+	}
+	if !flattened(v.Type()) {
+		return e.encodeRegular(nil, v)
+	}
+	var parts [][]byte
+	var header []byte
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range infoOf(v.Type()).fields {
+			var b []byte
+			var err error
+			if f.codec != nil {
+				b, err = e.encodeRegular(&f, v.Field(f.index))
+			} else {
+				b, err = e.flatEncode(v.Field(f.index))
+			}
+			if err != nil {
+				return nil, e.at(err, "."+f.name)
+			}
+			parts = append(parts, b)
+		}
+		return flatTable(nil, parts), nil
+	case reflect.Slice, reflect.Array:
+		header = binary.LittleEndian.AppendUint64(nil, uint64(v.Len()))
+		for i := range v.Len() {
+			b, err := e.flatEncode(v.Index(i))
+			if err != nil {
+				return nil, e.at(err, index(i))
+			}
+			parts = append(parts, b)
+		}
+		return flatTable(header, parts), nil
+	}
+	if infoOf(v.Type()).invalidKeys {
+		return nil, ErrInvalidKey
+	}
+	header = binary.LittleEndian.AppendUint64(nil, uint64(v.Len()))
+	for _, key := range v.MapKeys() {
+		err := checkKey(infoOf(v.Type()), key)
+		if err != nil {
+			return nil, e.at(err, "["+fmt.Sprint(key)+"]")
+		}
+		b, err := e.flatEncode(key)
+		if err != nil {
+			return nil, e.at(err, "["+fmt.Sprint(key)+"]")
+		}
+		parts = append(parts, b)
+		b, err = e.flatEncode(v.MapIndex(key))
+		if err != nil {
+			return nil, e.at(err, "["+fmt.Sprint(key)+"]")
+		}
+		parts = append(parts, b)
+	}
+	return flatTable(header, parts), nil
+}
+
+// EncodeFlat encodes a in the flat format. If a is a pointer, the value it
+// points to is encoded, or its zero value if nil. Values nested deeper than
+// the depth limit, such as cyclic ones, fail with ErrMaxDepth.
+func EncodeFlat(a any) ([]byte, error) {
+	v := addressable(reflect.ValueOf(a))
+	if !v.IsValid() {
+		return nil, ErrNilPointer
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v = reflect.New(v.Type().Elem()).Elem()
+		} else {
+			v = v.Elem()
+		}
+	}
+	e := defaultCodec.newEncodeState()
+	b, err := e.flatEncode(v)
+	if err != nil {
+		return nil, joinPath(err)
+	}
+	return b, nil
+}
+
+// A FlatValue provides random access to a value of type t encoded by
+// EncodeFlat.
+type FlatValue struct {
+	b []byte
+	t reflect.Type
+	// off is the offset of b in the encoding the FlatValue was reached
+	// from, which errors report.
+	off int
+	// field is set for struct fields with a tag codec, which are in the
+	// regular encoding.
+	field *fieldInfo
+}
+
+func NewFlatValue(b []byte, t reflect.Type) FlatValue {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return FlatValue{b: b, t: t}
+}
+
+// nested returns the FlatValue for the nested value of type t found at
+// b[start:end], following pointers, which fail with ErrNilPointer if nil.
+func (f FlatValue) nested(start, end int, t reflect.Type) (FlatValue, error) {
+	for t.Kind() == reflect.Pointer {
+		if start == end {
+			return FlatValue{}, ErrInvalidOffset
+		}
+		if f.b[start] == 0 {
+			return FlatValue{}, ErrNilPointer
+		}
+		start, t = start+1, t.Elem()
+	}
+	return FlatValue{b: f.b[start:end], t: t, off: f.off + start}, nil
+}
+
+// flat reports whether f is stored as a table.
+func (f FlatValue) flat() bool {
+	return f.field == nil && flattened(f.t)
+}
+
+func (f FlatValue) Type() reflect.Type {
+	return f.t
+}
+
+func (f FlatValue) Bytes() []byte {
+	return f.b
+}
+
+// entry returns where the i'th of the n entries of the table starting at
+// base is.
+func (f FlatValue) entry(base, n, i int) (int, int, error) {
+	if n > (len(f.b)-base)/8 {
+		return 0, 0, ErrInvalidOffset
+	}
+	table := base + 8*n
+	start := binary.LittleEndian.Uint64(f.b[base+8*i:])
+	end := uint64(len(f.b))
+	if i+1 < n {
+		end = binary.LittleEndian.Uint64(f.b[base+8*(i+1):])
+	}
+	if start < uint64(table) || start > end || end > uint64(len(f.b)) {
+		return 0, 0, ErrInvalidOffset
+	}
+	return int(start), int(end), nil
+}
+
+// Field returns the exported field name of a struct value. Fields that are
+// nil pointers fail with ErrNilPointer.
+func (f FlatValue) Field(name string) (FlatValue, error) {
+	if f.t.Kind() != reflect.Struct || !f.flat() {
+		return FlatValue{}, ErrNotStruct
+	}
+	fields := infoOf(f.t).fields
+	for n, field := range fields {
+		if field.name == name {
+			return f.fieldAt(fields, n)
+		}
+	}
+	return FlatValue{}, ErrUnknownField
+}
+
+// fieldAt returns the n'th of the fields of a struct value.
+func (f FlatValue) fieldAt(fields []fieldInfo, n int) (FlatValue, error) {
+	start, end, err := f.entry(0, len(fields), n)
+	if err != nil {
+		return FlatValue{}, err
+	}
+	if fields[n].codec != nil {
+		return FlatValue{b: f.b[start:end], t: fields[n].typ, off: f.off + start, field: &fields[n]}, nil
+	}
+	return f.nested(start, end, fields[n].typ)
+}
+
+// Len returns the number of elements of a slice, array or map value.
+func (f FlatValue) Len() (int, error) {
+	switch f.t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return 0, ErrNotIndexable
+	}
+	if !f.flat() {
+		return 0, ErrNotIndexable
+	}
+	if len(f.b) < 8 {
+		return 0, ErrInvalidOffset
+	}
+	// Every element takes an entry of the table.
+	n := binary.LittleEndian.Uint64(f.b)
+	if n > uint64(len(f.b)-8)/8 {
+		return 0, ErrInvalidOffset
+	}
+	return int(n), nil
+}
+
+// Index returns the i'th element of a slice or array value. Elements that
+// are nil pointers fail with ErrNilPointer.
+func (f FlatValue) Index(i int) (FlatValue, error) {
+	if f.t.Kind() == reflect.Map {
+		return FlatValue{}, ErrNotIndexable
+	}
+	n, err := f.Len()
+	if err != nil {
+		return FlatValue{}, err
+	}
+	if i < 0 || i >= n {
+		return FlatValue{}, ErrOutOfRange
+	}
+	start, end, err := f.entry(8, n, i)
+	if err != nil {
+		return FlatValue{}, err
+	}
+	return f.nested(start, end, f.t.Elem())
+}
+
+// MapEntry returns the key and value of the i'th entry of a map value.
+// Keys and values that are nil pointers fail with ErrNilPointer.
+func (f FlatValue) MapEntry(i int) (FlatValue, FlatValue, error) {
+	if f.t.Kind() != reflect.Map {
+		return FlatValue{}, FlatValue{}, ErrNotIndexable
+	}
+	n, err := f.Len()
+	if err != nil {
+		return FlatValue{}, FlatValue{}, err
+	}
+	if i < 0 || i >= n {
+		return FlatValue{}, FlatValue{}, ErrOutOfRange
+	}
+	start, end, err := f.entry(8, 2*n, 2*i)
+	if err != nil {
+		return FlatValue{}, FlatValue{}, err
+	}
+	key, err := f.nested(start, end, f.t.Key())
+	if err != nil {
+		return FlatValue{}, FlatValue{}, err
+	}
+	start, end, err = f.entry(8, 2*n, 2*i+1)
+	if err != nil {
+		return FlatValue{}, FlatValue{}, err
+	}
+	value, err := f.nested(start, end, f.t.Elem())
+	if err != nil {
+		return FlatValue{}, FlatValue{}, err
+	}
+	return key, value, nil
+}
+
+// Decode decodes the value f refers to into a, which must be a pointer to
+// f's type. Like Decode, it fails with a *DecodeError, whose Offset
+// counts from the start of the encoding f was reached from, and with
+// ErrMaxDepth for values nested deeper than the depth limit.
+func (f FlatValue) Decode(a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Type() != f.t {
+		return ErrCantSet
+	}
+	d := defaultCodec.newDecodeState(nil, nil)
+	return d.guard(func() error {
+		return d.decodeFlat(f, v.Elem())
+	})
+}
+
+// decodeFlat decodes f into v, counting against the depth limit like
+// decode.
+func (d *decodeState) decodeFlat(f FlatValue, v reflect.Value) error {
+	if d.depth >= d.depthLimit() {
+		return flatError(ErrMaxDepth, f.off)
+	}
+	d.depth++
+	err := d.decodeFlatValue(f, v)
+	d.depth--
+	return err
+}
+
+// decodeNested decodes the nested value found at f.b[start:end] into v,
+// following pointers.
+func (d *decodeState) decodeNested(f FlatValue, start, end int, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer {
+		if start == end {
+			return flatError(ErrInvalidOffset, f.off+start)
+		}
+		if f.b[start] == 0 {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		start, v = start+1, v.Elem()
+	}
+	return d.decodeFlat(FlatValue{b: f.b[start:end], t: v.Type(), off: f.off + start}, v)
+}
+
+// flatError returns err as a *DecodeError at offset off.
+func flatError(err error, off int) error {
+	return &DecodeError{Err: err, Offset: int64(off)}
+}
+
+// decodeRegular decodes f, which is in the regular encoding, into v.
+func (d *decodeState) decodeRegular(f FlatValue, v reflect.Value) error {
+	r := &decodeState{b: f.b, base: int64(f.off), options: d.options, depth: d.depth}
+	var err error
+	if f.field != nil {
+		err = f.field.codec.decode(r, v)
+		if err == nil && r.off != len(r.b) {
+			err = ErrTrailingBytes
+		}
+	} else {
+		err = r.decode(v)
+	}
+	if _, ok := err.(*DecodeError); err != nil && !ok {
+		err = flatError(err, int(r.offset()))
+	}
+	return err
+}
+
+func (d *decodeState) decodeFlatValue(f FlatValue, v reflect.Value) error {
+	if !f.flat() {
+		return d.decodeRegular(f, v)
+	}
+	switch f.t.Kind() {
+	case reflect.Struct:
+		fields := infoOf(f.t).fields
+		for n, fi := range fields {
+			start, end, err := f.entry(0, len(fields), n)
+			if err != nil {
+				return flatError(err, f.off)
+			}
+			if fi.codec != nil {
+				field := FlatValue{b: f.b[start:end], t: fi.typ, off: f.off + start, field: &fields[n]}
+				err = d.decodeFlat(field, v.Field(fi.index))
+			} else {
+				err = d.decodeNested(f, start, end, v.Field(fi.index))
+			}
+			if err != nil {
+				return d.at(err, "."+fi.name)
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		n, err := f.Len()
+		if err != nil {
+			return flatError(err, f.off)
+		}
+		if f.t.Kind() == reflect.Slice {
+			err = d.checkLength(uint64(n), 0)
+			if err == nil {
+				err = d.charge(uint64(n), f.t.Elem().Size())
+			}
+			if err != nil {
+				return flatError(err, f.off)
+			}
+			v.Set(reflect.MakeSlice(f.t, n, n))
+		}
+		for i := range min(n, v.Len()) {
+			start, end, err := f.entry(8, n, i)
+			if err != nil {
+				return flatError(err, f.off)
+			}
+			err = d.decodeNested(f, start, end, v.Index(i))
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+		return nil
+	}
+	n, err := f.Len()
+	if err == nil {
+		err = d.checkLength(uint64(n), 0)
+	}
+	if err == nil {
+		err = d.charge(uint64(n), f.t.Key().Size()+f.t.Elem().Size())
+	}
+	if err != nil {
+		return flatError(err, f.off)
+	}
+	v.Set(reflect.MakeMapWithSize(f.t, n))
+	for i := range n {
+		start, end, err := f.entry(8, 2*n, 2*i)
+		if err != nil {
+			return flatError(err, f.off)
+		}
+		key := reflect.New(f.t.Key()).Elem()
+		err = d.decodeNested(f, start, end, key)
+		if err != nil {
+			return d.at(err, index(i))
+		}
+		start, end, err = f.entry(8, 2*n, 2*i+1)
+		if err != nil {
+			return flatError(err, f.off)
+		}
+		value := reflect.New(f.t.Elem()).Elem()
+		err = d.decodeNested(f, start, end, value)
+		if err != nil {
+			return d.at(err, "["+fmt.Sprint(key)+"]")
+		}
+		v.SetMapIndex(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type flatPoint struct {
+	X, Y int
+}
+
+type flatDoc struct {
+	Name   string
+	When   time.Time
+	Points []flatPoint
+	Tags   map[string]int
+	Parent *flatPoint
+	Child  *flatPoint
+	Deltas []int64 `gensenc:"delta"`
+	Any    any
+}
+
+type flatNode struct {
+	Value int
+	Next  *flatNode
+}
+
+func TestFlatRoundTrip(t *testing.T) {
+	in := flatDoc{
+		Name:   "doc",
+		When:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Points: []flatPoint{{1, 2}, {3, 4}},
+		Tags:   map[string]int{"a": 1},
+		Child:  &flatPoint{5, 6},
+		Deltas: []int64{10, 11, 13},
+		Any:    "any",
+	}
+	b, err := gensenc.EncodeFlat(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := gensenc.NewFlatValue(b, reflect.TypeFor[*flatDoc]())
+	var got flatDoc
+	err = v.Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("got %+v, want %+v", got, in)
+	}
+
+	points, err := v.Field("Points")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := points.Index(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := p.Field("Y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = y.Decode(&n)
+	if err != nil || n != 4 {
+		t.Errorf("Points[1].Y: got %d, %v; want 4", n, err)
+	}
+	tags, err := v.Field("Tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, value, err := tags.MapEntry(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var k string
+	err = key.Decode(&k)
+	if err == nil {
+		err = value.Decode(&n)
+	}
+	if err != nil || k != "a" || n != 1 {
+		t.Errorf("Tags entry: got %q: %d, %v", k, n, err)
+	}
+	deltas, err := v.Field("Deltas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ds []int64
+	err = deltas.Decode(&ds)
+	if err != nil || !reflect.DeepEqual(ds, in.Deltas) {
+		t.Errorf("Deltas: got %v, %v; want %v", ds, err, in.Deltas)
+	}
+
+	_, err = v.Field("Parent")
+	if !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("nil Parent: got %v, want ErrNilPointer", err)
+	}
+	_, err = v.Field("Missing")
+	if !errors.Is(err, gensenc.ErrUnknownField) {
+		t.Errorf("unknown field: got %v, want ErrUnknownField", err)
+	}
+	_, err = points.Index(2)
+	if !errors.Is(err, gensenc.ErrOutOfRange) {
+		t.Errorf("Points[2]: got %v, want ErrOutOfRange", err)
+	}
+	_, err = v.Len()
+	if !errors.Is(err, gensenc.ErrNotIndexable) {
+		t.Errorf("Len of a struct: got %v, want ErrNotIndexable", err)
+	}
+}
+
+func TestFlatCycle(t *testing.T) {
+	n := &flatNode{Value: 1}
+	n.Next = n
+	_, err := gensenc.EncodeFlat(n)
+	if !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("encoding a cycle gave %v, want ErrMaxDepth", err)
+	}
+}
+
+func TestFlatDeep(t *testing.T) {
+	var n *flatNode
+	for i := range 100 {
+		n = &flatNode{Value: i, Next: n}
+	}
+	b, err := gensenc.EncodeFlat(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got flatNode
+	err = gensenc.NewFlatValue(b, reflect.TypeFor[flatNode]()).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, n) {
+		t.Error("deep list changed in a round trip")
+	}
+}
+
+// TestFlatCorrupt decodes the encoding of a value with every byte in turn
+// replaced, which must fail with a *DecodeError or succeed, never panic.
+func TestFlatCorrupt(t *testing.T) {
+	in := flatDoc{
+		Name:   "doc",
+		Points: []flatPoint{{1, 2}, {3, 4}},
+		Tags:   map[string]int{"a": 1, "b": 2},
+		Child:  &flatPoint{5, 6},
+		Deltas: []int64{1, 2},
+	}
+	b, err := gensenc.EncodeFlat(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b {
+		for _, x := range []byte{0x00, 0x01, 0x7f, 0xff} {
+			c := append([]byte(nil), b...)
+			c[i] = x
+			var got flatDoc
+			err := gensenc.NewFlatValue(c, reflect.TypeFor[flatDoc]()).Decode(&got)
+			var de *gensenc.DecodeError
+			if err != nil && !errors.As(err, &de) {
+				t.Fatalf("byte %d set to %#x: got %v, want a *DecodeError", i, x, err)
+			}
+		}
+	}
+
+	// The table of the struct claims the first field starts past the end.
+	c := append([]byte(nil), b...)
+	c[0], c[1], c[2] = 0xff, 0xff, 0xff
+	var got flatDoc
+	err = gensenc.NewFlatValue(c, reflect.TypeFor[flatDoc]()).Decode(&got)
+	if !errors.Is(err, gensenc.ErrInvalidOffset) {
+		t.Errorf("corrupt table: got %v, want ErrInvalidOffset", err)
+	}
+}
@@ -0,0 +1,238 @@
+package gensenc
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// floatCodec stores float fields, or slices or arrays of floats, in
+// reduced precision. Fields tagged with float16 are written as IEEE 754
+// half-precision floats of two bytes. Fields tagged with fixed=N, for N
+// between 0 and 18, are written as the value rounded to N decimal places
+// in fixed point, as a zigzag varint; encoding fails with ErrOverflow for
+// values too large for that.
+type floatCodec struct {
+	t reflect.Type
+	// places is the number of decimal places of fixed point values, or
+	// -1 for float16.
+	places int
+}
+
+func newFloatCodec(t reflect.Type, opts tagOptions) wireCodec {
+	elem := t
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		elem = t.Elem()
+	}
+	if elem.Kind() != reflect.Float32 && elem.Kind() != reflect.Float64 {
+		return invalidTag{}
+	}
+	if opts.has("float16") {
+		return floatCodec{t, -1}
+	}
+	n, err := strconv.Atoi(opts["fixed"])
+	if err != nil || n < 0 || n > 18 {
+		return invalidTag{}
+	}
+	return floatCodec{t, n}
+}
+
+func (c floatCodec) scale() float64 {
+	return math.Pow10(c.places)
+}
+
+func (c floatCodec) put(e *encodeState, f float64) error {
+	if c.places < 0 {
+		e.order().PutUint16(e.l[:], toFloat16(f))
+		e.buf.Write(e.l[:2])
+		return nil
+	}
+	x := math.RoundToEven(f * c.scale())
+	// The largest int64 isn't representable as a float64, so the first
+	// float64 above it is the bound.
+	if !(x >= math.MinInt64 && x < math.MaxInt64) {
+		return ErrOverflow
+	}
+	i := int64(x)
+	e.writeUvarint(uint64(i<<1 ^ i>>63))
+	return nil
+}
+
+func (c floatCodec) get(d *decodeState) (float64, error) {
+	if c.places < 0 {
+		b, err := d.take(2)
+		if err != nil {
+			return 0, err
+		}
+		return fromFloat16(d.order().Uint16(b)), nil
+	}
+	x, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	return float64(int64(x>>1^-(x&1))) / c.scale(), nil
+}
+
+func (c floatCodec) skipOne(d *decodeState) error {
+	_, err := c.get(d)
+	return err
+}
+
+func (c floatCodec) encode(e *encodeState, v reflect.Value) error {
+	switch c.t.Kind() {
+	case reflect.Slice:
+		e.writeUint64(uint64(v.Len()))
+		fallthrough
+	case reflect.Array:
+		for i := range v.Len() {
+			err := c.put(e, v.Index(i).Float())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.put(e, v.Float())
+}
+
+func (c floatCodec) set(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	f, err := c.get(d)
+	if err != nil {
+		return err
+	}
+	v.SetFloat(f)
+	return nil
+}
+
+// minSize returns the least number of bytes a value takes.
+func (c floatCodec) minSize() int {
+	if c.places < 0 {
+		return 2
+	}
+	return 1
+}
+
+func (c floatCodec) decode(d *decodeState, v reflect.Value) error {
+	switch c.t.Kind() {
+	case reflect.Slice:
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		err = d.checkLength(length, c.minSize())
+		if err != nil {
+			return err
+		}
+		return d.decodeElems(v, int(length), func(_ int, elem reflect.Value) error {
+			return c.set(d, elem)
+		})
+	case reflect.Array:
+		for i := range v.Len() {
+			err := c.set(d, v.Index(i))
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+		return nil
+	}
+	return c.set(d, v)
+}
+
+func (c floatCodec) skip(d *decodeState) error {
+	n := 1
+	switch c.t.Kind() {
+	case reflect.Slice:
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		err = d.checkLength(length, c.minSize())
+		if err != nil {
+			return err
+		}
+		if c.places < 0 {
+			return d.discard(length * 2)
+		}
+		n = int(length)
+	case reflect.Array:
+		n = c.t.Len()
+	}
+	for range n {
+		err := c.skipOne(d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c floatCodec) wireSize(map[reflect.Type]bool) int {
+	if c.places >= 0 || c.t.Kind() == reflect.Slice {
+		return -1
+	}
+	if c.t.Kind() == reflect.Array {
+		return 2 * c.t.Len()
+	}
+	return 2
+}
+
+// toFloat16 returns the half-precision float nearest to f, rounding ties
+// to even.
+func toFloat16(f float64) uint16 {
+	bits := math.Float64bits(f)
+	sign := uint16(bits>>48) & 0x8000
+	exp := int(bits>>52) & 0x7ff
+	mant := bits & (1<<52 - 1)
+	switch {
+	case exp == 0x7ff && mant != 0:
+		return sign | 0x7e00
+	case exp == 0x7ff:
+		return sign | 0x7c00
+	}
+	e := exp - 1023 + 15
+	if e >= 31 {
+		return sign | 0x7c00
+	}
+	if e <= 0 {
+		if e < -10 {
+			return sign
+		}
+		// Subnormal, with the implicit leading bit made explicit.
+		return sign | uint16(roundShift(mant|1<<52, uint(43-e)))
+	}
+	// A mantissa rounding up carries into the exponent, up to infinity.
+	return sign | (uint16(e<<10) + uint16(roundShift(mant, 42)))
+}
+
+// roundShift returns x>>s rounded to nearest, ties to even.
+func roundShift(x uint64, s uint) uint64 {
+	q := x >> s
+	r := x & (1<<s - 1)
+	half := uint64(1) << (s - 1)
+	if r > half || r == half && q&1 == 1 {
+		q++
+	}
+	return q
+}
+
+func fromFloat16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 31:
+		if mant != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(1+mant/1024, exp-15)
+}
@@ -0,0 +1,115 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type half struct {
+	F float64 `gensenc:"float16"`
+}
+
+func TestFloat16(t *testing.T) {
+	for _, tt := range []struct {
+		f    float64
+		bits uint16
+		back float64
+	}{
+		{1, 0x3c00, 1},
+		{-2, 0xc000, -2},
+		{0.1, 0x2e66, 0.0999755859375},
+		{65504, 0x7bff, 65504},
+		{65520, 0x7c00, math.Inf(1)},
+		{math.Inf(-1), 0xfc00, math.Inf(-1)},
+		{5.960464477539063e-8, 0x0001, 5.960464477539063e-8},
+		{1e-9, 0x0000, 0},
+		{math.Copysign(0, -1), 0x8000, 0},
+		// Ties round to even.
+		{1 + 1.0/2048, 0x3c00, 1},
+		{1 + 3.0/2048, 0x3c02, 1 + 2.0/1024},
+	} {
+		b, err := gensenc.Encode(half{tt.f})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := binary.LittleEndian.Uint16(b); len(b) != 2 || got != tt.bits {
+			t.Errorf("%v: encoded as %x, want %04x", tt.f, b, tt.bits)
+		}
+		var h half
+		if err := gensenc.Decode(b, &h); err != nil || h.F != tt.back {
+			t.Errorf("%v: decoded %v, %v, want %v", tt.f, h.F, err, tt.back)
+		}
+	}
+	b, _ := gensenc.Encode(half{math.NaN()})
+	var h half
+	if err := gensenc.Decode(b, &h); err != nil || !math.IsNaN(h.F) {
+		t.Errorf("NaN decoded as %v, %v", h.F, err)
+	}
+}
+
+type telemetry struct {
+	Temp    float32    `gensenc:"fixed=2"`
+	Samples []float64  `gensenc:"fixed=1"`
+	Axes    [3]float64 `gensenc:"float16"`
+}
+
+func TestFixed(t *testing.T) {
+	v := telemetry{Temp: -3.14159, Samples: []float64{0.04, 0.05, 0.15, 12.25}, Axes: [3]float64{0.5, -1, 2}}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// -314 as a zigzag varint.
+	if !bytes.HasPrefix(b, []byte{0xf3, 0x04, 4, 0, 0, 0, 0, 0, 0, 0}) {
+		t.Errorf("encoded as %x", b)
+	}
+	got := roundTrip(t, gensenc.New(), v)
+	// Values are rounded to the nearest place, ties to even.
+	want := telemetry{Temp: -3.14, Samples: []float64{0, 0, 0.2, 12.2}, Axes: v.Axes}
+	if got.Temp != want.Temp || got.Axes != want.Axes {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	for i, s := range got.Samples {
+		if math.Abs(s-want.Samples[i]) > 1e-9 {
+			t.Errorf("sample %d: got %v, want %v", i, s, want.Samples[i])
+		}
+	}
+}
+
+func TestFixedErrors(t *testing.T) {
+	type precise struct {
+		F float64 `gensenc:"fixed=18"`
+	}
+	for _, f := range []float64{10, -10, math.NaN(), math.Inf(1)} {
+		if _, err := gensenc.Encode(precise{f}); !errors.Is(err, gensenc.ErrOverflow) {
+			t.Errorf("encoding %v with 18 places gave %v, want ErrOverflow", f, err)
+		}
+	}
+	if _, err := gensenc.Encode(precise{9.2}); err != nil {
+		t.Errorf("encoding 9.2 with 18 places: %v", err)
+	}
+	var v telemetry
+	if err := gensenc.Decode([]byte{0xf3}, &v); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a truncated varint gave %v, want ErrTruncated", err)
+	}
+	for _, v := range []any{
+		struct {
+			F float64 `gensenc:"fixed=19"`
+		}{},
+		struct {
+			F float64 `gensenc:"fixed=x"`
+		}{},
+		struct {
+			N int `gensenc:"float16"`
+		}{},
+	} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package gensenc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// A Format is an alternative, self-describing wire format. EncodeFormat
+// and DecodeFormat walk values with the same reflection machinery as
+// Encode and Decode but emit and consume the format's primitives instead
+// of the native encoding. Structs are written as maps from field name to
+// field value, and types with an encoding of their own, such as time.Time,
+// big.Int and netip.Addr, as strings in their text form.
+type Format interface {
+	NewWriter(w io.Writer) FormatWriter
+	NewReader(r io.Reader) FormatReader
+}
+
+type FormatWriter interface {
+	WriteNil() error
+	WriteBool(b bool) error
+	WriteInt(i int64) error
+	WriteUint(u uint64) error
+	WriteFloat32(f float32) error
+	WriteFloat64(f float64) error
+	WriteString(s string) error
+	WriteBytes(b []byte) error
+	WriteArrayHeader(n int) error
+	WriteMapHeader(n int) error
+}
+
+type FormatReader interface {
+	// ReadNil reports whether the next value is nil, consuming it if so.
+	ReadNil() (bool, error)
+	ReadBool() (bool, error)
+	ReadInt() (int64, error)
+	ReadUint() (uint64, error)
+	ReadFloat64() (float64, error)
+	ReadString() (string, error)
+	ReadBytes() ([]byte, error)
+	ReadArrayHeader() (int, error)
+	ReadMapHeader() (int, error)
+	// ReadAny reads the next value, whatever its type, for decoding into
+	// interfaces. Scalars are returned as nil, bool, int64, uint64 (for
+	// integers beyond the range of int64), float64, string or []byte.
+	// Arrays and maps are returned as an ArrayHeader or MapHeader, having
+	// consumed only their header.
+	ReadAny() (any, error)
+	// Skip consumes the next value, whatever its type.
+	Skip() error
+}
+
+// ArrayHeader and MapHeader are returned by FormatReader.ReadAny for
+// arrays and maps, holding the number of elements or entries that follow.
+type (
+	ArrayHeader int
+	MapHeader   int
+)
+
+// A formatCodec is a wireCodec with a form of its own in self-describing
+// formats. Values of types with a codec that isn't a formatCodec, and of
+// fields tagged with one, are written according to their kind.
+type formatCodec interface {
+	writeFormat(e *encodeState, w FormatWriter, v reflect.Value) error
+	readFormat(d *decodeState, r FormatReader, v reflect.Value) error
+}
+
+func isBytes(t reflect.Type) bool {
+	return t.Elem().Kind() == reflect.Uint8
+}
+
+func (e *encodeState) encodeFormat(w FormatWriter, v reflect.Value) error {
+	if e.depth >= e.depthLimit() {
+		return ErrMaxDepth
+	}
+	e.depth++
+	err := e.encodeFormatValue(w, v)
+	e.depth--
+	return err
+}
+
+func (e *encodeState) encodeFormatField(w FormatWriter, f *fieldInfo, v reflect.Value) error {
+	if c, ok := f.codec.(formatCodec); ok {
+		return c.writeFormat(e, w, v)
+	}
+	return e.encodeFormat(w, v)
+}
+
+func (e *encodeState) encodeFormatValue(w FormatWriter, v reflect.Value) error {
+	if v.IsValid() {
+		if c, ok := infoOf(v.Type()).codec.(formatCodec); ok {
+			return c.writeFormat(e, w, v)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return w.WriteBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return w.WriteInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return w.WriteUint(v.Uint())
+	case reflect.Float32:
+		return w.WriteFloat32(float32(v.Float()))
+	case reflect.Float64:
+		return w.WriteFloat64(v.Float())
+	case reflect.String:
+		return w.WriteString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		if isBytes(v.Type()) {
+			return w.WriteBytes(v.Bytes())
+		}
+		fallthrough
+	case reflect.Array:
+		err := w.WriteArrayHeader(v.Len())
+		if err != nil {
+			return err
+		}
+		for i := range v.Len() {
+			err = e.encodeFormat(w, v.Index(i))
+			if err != nil {
+				return e.at(err, index(i))
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		err := w.WriteMapHeader(v.Len())
+		if err != nil {
+			return err
+		}
+		for _, key := range v.MapKeys() {
+			err = e.encodeFormat(w, key)
+			if err == nil {
+				err = e.encodeFormat(w, v.MapIndex(key))
+			}
+			if err != nil {
+				return e.at(err, "["+fmt.Sprint(key)+"]")
+			}
+		}
+	case reflect.Struct:
+		fields := infoOf(v.Type()).fields
+		err := w.WriteMapHeader(len(fields))
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			err = w.WriteString(f.name)
+			if err == nil {
+				err = e.encodeFormatField(w, &f, v.Field(f.index))
+			}
+			if err != nil {
+				return e.at(err, "."+f.name)
+			}
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return w.WriteNil()
+		}
+		return e.encodeFormat(w, v.Elem())
+	default:
+		return ErrUnsupportedKind
+	}
+	return nil
+}
+
+func (d *decodeState) decodeFormat(r FormatReader, v reflect.Value) error {
+	if d.depth >= d.depthLimit() {
+		return ErrMaxDepth
+	}
+	d.depth++
+	err := d.decodeFormatValue(r, v)
+	d.depth--
+	return err
+}
+
+func (d *decodeState) decodeFormatField(r FormatReader, f *fieldInfo, v reflect.Value) error {
+	if c, ok := f.codec.(formatCodec); ok {
+		return c.readFormat(d, r, v)
+	}
+	return d.decodeFormat(r, v)
+}
+
+func (d *decodeState) decodeFormatValue(r FormatReader, v reflect.Value) error {
+	if c, ok := infoOf(v.Type()).codec.(formatCodec); ok {
+		return c.readFormat(d, r, v)
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		null, err := r.ReadNil()
+		if err != nil {
+			return err
+		}
+		if null {
+			v.SetZero()
+			return nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := r.ReadBool()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := r.ReadInt()
+		if err != nil {
+			return err
+		}
+		if !d.truncate && v.OverflowInt(i) {
+			return ErrOverflow
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := r.ReadUint()
+		if err != nil {
+			return err
+		}
+		if !d.truncate && v.OverflowUint(u) {
+			return ErrOverflow
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := r.ReadFloat64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowFloat(f) {
+			return ErrOverflow
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		s, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if isBytes(v.Type()) {
+			b, err := r.ReadBytes()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		n, err := r.ReadArrayHeader()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), 0, min(n, 1024)))
+		for i := 0; i < n; i++ {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err = d.decodeFormat(r, elem)
+			if err != nil {
+				return d.at(err, index(i))
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+	case reflect.Array:
+		n, err := r.ReadArrayHeader()
+		if err != nil {
+			return err
+		}
+		v.SetZero()
+		for i := 0; i < n; i++ {
+			if i >= v.Len() {
+				err = r.Skip()
+			} else {
+				err = d.decodeFormat(r, v.Index(i))
+			}
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+	case reflect.Map:
+		n, err := r.ReadMapHeader()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeMap(v.Type()))
+		for i := range n {
+			key := reflect.New(v.Type().Key()).Elem()
+			err = d.decodeFormat(r, key)
+			if err != nil {
+				return d.at(err, index(i))
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			err = d.decodeFormat(r, value)
+			if err != nil {
+				return d.at(err, "["+fmt.Sprint(key)+"]")
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		n, err := r.ReadMapHeader()
+		if err != nil {
+			return err
+		}
+		fields := infoOf(v.Type()).fields
+		for range n {
+			name, err := r.ReadString()
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, f := range fields {
+				if f.name != name {
+					continue
+				}
+				err = d.decodeFormatField(r, &f, v.Field(f.index))
+				if err != nil {
+					return d.at(err, "."+f.name)
+				}
+				found = true
+				break
+			}
+			if !found {
+				err = r.Skip()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeFormat(r, v.Elem())
+	case reflect.Interface:
+		x, err := d.readAny(r)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.SetZero()
+			return nil
+		}
+		xv := reflect.ValueOf(x)
+		if !xv.Type().AssignableTo(v.Type()) {
+			return ErrNotAssignable
+		}
+		v.Set(xv)
+	default:
+		return ErrUnsupportedKind
+	}
+	return nil
+}
+
+// readAny reads the next value for an interface: arrays become []any and
+// maps map[string]any if all their keys are strings, as those of structs
+// are, or map[any]any otherwise.
+func (d *decodeState) readAny(r FormatReader) (any, error) {
+	if d.depth >= d.depthLimit() {
+		return nil, ErrMaxDepth
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+	x, err := r.ReadAny()
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case ArrayHeader:
+		l := make([]any, 0, min(int(x), 1024))
+		for i := range int(x) {
+			elem, err := d.readAny(r)
+			if err != nil {
+				return nil, d.at(err, index(i))
+			}
+			l = append(l, elem)
+		}
+		return l, nil
+	case MapHeader:
+		m := make(map[any]any, min(int(x), 1024))
+		strings := true
+		for i := range int(x) {
+			key, err := d.readAny(r)
+			if err != nil {
+				return nil, d.at(err, index(i))
+			}
+			if key == nil || !reflect.TypeOf(key).Comparable() {
+				return nil, d.at(ErrInvalidKey, index(i))
+			}
+			_, ok := key.(string)
+			strings = strings && ok
+			m[key], err = d.readAny(r)
+			if err != nil {
+				return nil, d.at(err, "["+fmt.Sprint(key)+"]")
+			}
+		}
+		if !strings {
+			return m, nil
+		}
+		sm := make(map[string]any, len(m))
+		for k, v := range m {
+			sm[k.(string)] = v
+		}
+		return sm, nil
+	}
+	return x, nil
+}
+
+func EncodeFormat(f Format, a any) ([]byte, error) {
+	return defaultCodec.EncodeFormat(f, a)
+}
+
+// DecodeFormat decodes b, encoded in format f, into the value a points to.
+// Struct fields missing from b keep their value and unknown keys are
+// skipped. Interfaces receive values of the types FormatReader.ReadAny
+// returns, with arrays as []any and maps as map[string]any or, if not all
+// keys are strings, map[any]any.
+func DecodeFormat(f Format, b []byte, a any) error {
+	return defaultCodec.DecodeFormat(f, b, a)
+}
+
+// EncodeFormat is like the package-level EncodeFormat, with the options of
+// c that apply to formats, such as WithMaxDepth.
+func (c *Codec) EncodeFormat(f Format, a any) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	e := c.newEncodeState()
+	err := e.encodeFormat(f.NewWriter(buf), reflect.ValueOf(a))
+	if err != nil {
+		return nil, joinPath(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeFormat is like the package-level DecodeFormat, with the options of
+// c that apply to formats, such as WithMaxDepth and WithTruncateIntegers.
+func (c *Codec) DecodeFormat(f Format, b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrCantSet
+	}
+	br := bytes.NewReader(b)
+	r := f.NewReader(br)
+	d := c.newDecodeState(nil, nil)
+	return d.guard(func() error {
+		err := d.decodeFormat(r, v.Elem())
+		// Errors are reported at the offset the format read up to.
+		d.off = len(b) - br.Len()
+		if de, ok := err.(*DecodeError); ok {
+			de.Offset = d.offset()
+		}
+		return err
+	})
+}
@@ -0,0 +1,158 @@
+package gensenc_test
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/cbor"
+	"github.com/CodeSpoof/gogenericencoder/msgpack"
+)
+
+var formats = []struct {
+	name   string
+	format gensenc.Format
+}{
+	{"msgpack", msgpack.Format},
+	{"cbor", cbor.Format},
+}
+
+type level int
+
+const (
+	debug level = iota + 1
+	info
+)
+
+func init() {
+	gensenc.RegisterEnum(map[string]level{"debug": debug, "info": info})
+}
+
+type shape struct {
+	Circle *float64
+	Square *float64
+}
+
+type tagged struct {
+	Level    level   `gensenc:"enum"`
+	Password string  `gensenc:"redact=***"`
+	Token    []byte  `gensenc:"redact"`
+	Shape    shape   `gensenc:"oneof"`
+	Flags    [3]bool `gensenc:"bits"`
+	Small    int8    `gensenc:"bits=4"`
+	ID       string  `gensenc:"uuid"`
+}
+
+func roundTripFormat[T any](t *testing.T, f gensenc.Format, v T) T {
+	t.Helper()
+	b, err := gensenc.EncodeFormat(f, v)
+	if err != nil {
+		t.Fatalf("encoding %T: %v", v, err)
+	}
+	var got T
+	err = gensenc.DecodeFormat(f, b, &got)
+	if err != nil {
+		t.Fatalf("decoding %T: %v", v, err)
+	}
+	return got
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	n := new(big.Int)
+	n.SetString("-123456789012345678901234567890", 10)
+	side := 2.5
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+			if got := roundTripFormat(t, f.format, now); !got.Equal(now) {
+				t.Errorf("time.Time: got %v, want %v", got, now)
+			}
+			if got := roundTripFormat(t, f.format, *n); got.Cmp(n) != 0 {
+				t.Errorf("big.Int: got %v, want %v", &got, n)
+			}
+			m := map[string]any{
+				"int":    int64(-3),
+				"string": "x",
+				"list":   []any{true, nil, 1.5},
+				"map":    map[string]any{"bytes": []byte{1, 2}},
+			}
+			if got := roundTripFormat(t, f.format, m); !reflect.DeepEqual(got, m) {
+				t.Errorf("map[string]any: got %v, want %v", got, m)
+			}
+
+			v := tagged{
+				Level:    info,
+				Password: "hunter2",
+				Token:    []byte("secret"),
+				Shape:    shape{Square: &side},
+				Flags:    [3]bool{true, false, true},
+				Small:    -8,
+				ID:       "6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+			}
+			want := v
+			want.Password = "***"
+			want.Token = nil
+			want.ID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+			if got := roundTripFormat(t, f.format, v); !reflect.DeepEqual(got, want) {
+				t.Errorf("tagged struct: got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatTaggedFieldForms(t *testing.T) {
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			b, err := gensenc.EncodeFormat(f.format, tagged{Level: debug})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m map[string]any
+			err = gensenc.DecodeFormat(f.format, b, &m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m["Level"] != "debug" {
+				t.Errorf("enum field written as %v, want its name", m["Level"])
+			}
+			if m["Password"] != "***" {
+				t.Errorf("redacted field written as %v, want the placeholder", m["Password"])
+			}
+			if shape, ok := m["Shape"].(map[string]any); !ok || len(shape) != 0 {
+				t.Errorf("oneof field written as %v, want an empty map", m["Shape"])
+			}
+
+			_, err = gensenc.EncodeFormat(f.format, tagged{Level: 7})
+			if err == nil {
+				t.Error("encoding an unregistered enum value succeeded")
+			}
+			_, err = gensenc.EncodeFormat(f.format, tagged{Level: debug, Small: 8})
+			if err == nil {
+				t.Error("encoding a value that overflows its bits succeeded")
+			}
+		})
+	}
+}
+
+func TestFormatOverflow(t *testing.T) {
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			b, err := gensenc.EncodeFormat(f.format, 300)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var i int8
+			err = gensenc.DecodeFormat(f.format, b, &i)
+			if !errors.Is(err, gensenc.ErrOverflow) {
+				t.Fatalf("decoding 300 into an int8 gave %d, %v; want ErrOverflow", i, err)
+			}
+			err = gensenc.New(gensenc.WithTruncateIntegers()).DecodeFormat(f.format, b, &i)
+			if err != nil || i != 44 {
+				t.Fatalf("decoding truncated gave %d, %v; want 44", i, err)
+			}
+		})
+	}
+}
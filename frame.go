@@ -0,0 +1,285 @@
+package gensenc
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+)
+
+const frameVersion = 1
+
+// A Header precedes the body of a frame written by WriteFrame. Reading it
+// first with PeekHeader lets servers route or reject a frame by its type
+// before decoding anything else.
+type Header struct {
+	// Version is the version of the frame format.
+	Version uint64
+	// Fingerprint is the Fingerprint of the type of the body.
+	Fingerprint uint64
+	// Length is the number of bytes of the body.
+	Length uint64
+}
+
+// Is reports whether the body of the frame holds a value of type t.
+func (h Header) Is(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return h.Fingerprint == Fingerprint(t)
+}
+
+// WriteFrame writes a to w as a frame, a Header followed by the encoding
+// of a as its body.
+func WriteFrame(w io.Writer, a any) error {
+	v := addressable(reflect.ValueOf(a))
+	body, err := EncodeValue(v)
+	if err != nil {
+		return err
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	buf := bytes.NewBuffer(nil)
+	err = NewEncoder(buf).Encode(Header{frameVersion, Fingerprint(t), uint64(len(body))})
+	if err != nil {
+		return err
+	}
+	buf.Write(body)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// PeekHeader reads the Header of a frame from r, leaving r at the start of
+// the body. Decode the body with DecodeBody, or skip it by discarding
+// Length bytes.
+func PeekHeader(r io.Reader) (Header, error) {
+	var h Header
+	err := DecodeValue(r, reflect.ValueOf(&h))
+	if err != nil {
+		return h, err
+	}
+	if h.Version != frameVersion {
+		return h, ErrUnsupportedVersion
+	}
+	return h, nil
+}
+
+// DecodeBody decodes the body of the frame with header h from r into the
+// value a points to, failing with ErrFingerprintMismatch without reading
+// anything if its type isn't the one of the body, and with
+// ErrTrailingBytes if the value doesn't span the whole body.
+func DecodeBody(r io.Reader, h Header, a any) error {
+	v := reflect.ValueOf(a)
+	if !v.IsValid() {
+		return ErrNilPointer
+	}
+	if !h.Is(v.Type()) {
+		return ErrFingerprintMismatch
+	}
+	lr := &io.LimitedReader{R: r, N: int64(h.Length)}
+	err := DecodeValue(lr, v)
+	if err != nil {
+		return err
+	}
+	if lr.N != 0 {
+		return &DecodeError{Err: ErrTrailingBytes, Offset: int64(h.Length) - lr.N}
+	}
+	return nil
+}
+
+// WriteTagged writes a to w preceded by the name its type was registered
+// under with Register or RegisterName, and the length of its encoding, so
+// that values of different types can share a stream read by ReadTagged.
+func WriteTagged(w io.Writer, a any) error {
+	return defaultCodec.WriteTagged(w, a)
+}
+
+// EncodeTagged returns what WriteTagged writes for a, for DecodeTagged.
+func EncodeTagged(a any) ([]byte, error) {
+	return defaultCodec.EncodeTagged(a)
+}
+
+// DecodeTagged decodes a value written by EncodeTagged or WriteTagged into
+// the variable a points to, typically of an interface type the registered
+// types of the values implement:
+//
+//	var s Shape
+//	err := gensenc.DecodeTagged(b, &s) // s holds a Circle, a Square, ...
+//
+// It fails with ErrUnknownType if the type isn't registered and with
+// ErrNotAssignable if it can't be assigned to the variable.
+func DecodeTagged(b []byte, a any) error {
+	return defaultCodec.DecodeTagged(b, a)
+}
+
+// ReadTagged reads a value written by WriteTagged from r and returns it as
+// its registered type. Values of types not registered are skipped,
+// failing with ErrUnknownType, so that reading can go on with the next
+// one.
+func ReadTagged(r io.Reader) (any, error) {
+	return defaultCodec.ReadTagged(r)
+}
+
+// WriteTagged is like the package-level WriteTagged, with the configuration
+// of c.
+func (c *Codec) WriteTagged(w io.Writer, a any) error {
+	b, err := c.EncodeTagged(a)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// EncodeTagged is like the package-level EncodeTagged, with the
+// configuration of c. An Unknown is written back as it was read.
+func (c *Codec) EncodeTagged(a any) ([]byte, error) {
+	var name string
+	var body []byte
+	if u, ok := a.(Unknown); ok && u.Name != "" {
+		name, body = u.Name, u.Data
+	} else {
+		name, ok = registeredName(reflect.TypeOf(a))
+		if !ok {
+			return nil, ErrUnregisteredType
+		}
+		var err error
+		body, err = c.Encode(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+	e := &encodeState{buf: bytes.NewBuffer(nil), options: c.opts}
+	e.writeString(name)
+	e.writeUint64(uint64(len(body)))
+	e.buf.Write(body)
+	return e.buf.Bytes(), nil
+}
+
+// readTag reads the type name and length preceding a tagged value.
+func (d *decodeState) readTag() (string, uint64, error) {
+	name, err := d.readString()
+	if err != nil {
+		return "", 0, err
+	}
+	length, err := d.readUint64()
+	if err == nil {
+		err = d.checkLength(length, 1)
+	}
+	if err == nil && name == "" {
+		err = ErrMalformed
+	}
+	return name, length, err
+}
+
+// DecodeTagged is like the package-level DecodeTagged, with the
+// configuration of c. Values of types not registered are handled as the
+// UnknownPolicy of c says.
+func (c *Codec) DecodeTagged(b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrNilPointer
+	}
+	v = v.Elem()
+	d := c.newDecodeState(b, nil)
+	return d.guard(func() error {
+		name, length, err := d.readTag()
+		if err != nil {
+			return err
+		}
+		t, ok := registeredType(name)
+		if !ok {
+			switch c.opts.unknown {
+			case SkipUnknown:
+				return d.discard(length)
+			case CaptureUnknown:
+				data, err := d.take(length)
+				if err != nil {
+					return err
+				}
+				u := reflect.ValueOf(Unknown{name, bytes.Clone(data)})
+				if !u.Type().AssignableTo(v.Type()) {
+					return ErrNotAssignable
+				}
+				v.Set(u)
+				return nil
+			}
+			return ErrUnknownType
+		}
+		if !t.AssignableTo(v.Type()) {
+			return ErrNotAssignable
+		}
+		end := d.off + int(length)
+		d.b = d.b[:end]
+		// Values of registered pointer types are encoded as top-level
+		// pointers, without a presence byte.
+		elem := reflect.New(t).Elem()
+		err = d.decodeRoot(elem)
+		if err != nil {
+			return err
+		}
+		if d.off != end {
+			return ErrTrailingBytes
+		}
+		v.Set(elem)
+		return nil
+	})
+}
+
+// ReadTagged is like the package-level ReadTagged, with the configuration
+// of c. Values of types not registered are handled as the UnknownPolicy of
+// c says.
+func (c *Codec) ReadTagged(r io.Reader) (any, error) {
+	for {
+		d := c.newDecodeState(nil, r)
+		var t reflect.Type
+		var length uint64
+		var unknown any
+		err := d.guard(func() error {
+			name, n, err := d.readTag()
+			if err != nil {
+				return err
+			}
+			length = n
+			var ok bool
+			t, ok = registeredType(name)
+			if ok {
+				return nil
+			}
+			if c.opts.unknown == CaptureUnknown {
+				data, err := d.take(length)
+				if err != nil {
+					return err
+				}
+				unknown = Unknown{name, bytes.Clone(data)}
+				return nil
+			}
+			err = d.discard(length)
+			if err != nil || c.opts.unknown == SkipUnknown {
+				return err
+			}
+			return ErrUnknownType
+		})
+		if err != nil {
+			return nil, err
+		}
+		if unknown != nil {
+			return unknown, nil
+		}
+		if t == nil {
+			// Skipped.
+			continue
+		}
+		v := reflect.New(t)
+		lr := &io.LimitedReader{R: r, N: int64(length)}
+		err = c.DecodeValue(lr, v)
+		if err != nil {
+			return nil, err
+		}
+		if lr.N != 0 {
+			return nil, &DecodeError{Err: ErrTrailingBytes, Offset: int64(length) - lr.N}
+		}
+		return v.Elem().Interface(), nil
+	}
+}
@@ -0,0 +1,239 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	ping struct{ Seq uint64 }
+	pong struct {
+		Seq  uint64
+		Note string
+	}
+)
+
+func init() {
+	gensenc.RegisterName("ping", ping{})
+	gensenc.RegisterName("pong", pong{})
+}
+
+// TestFrames routes frames of different types by their header, skipping
+// those of a type not wanted.
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	for _, v := range []any{ping{1}, &pong{2, "a"}, order{ID: 3}, ping{4}} {
+		if err := gensenc.WriteFrame(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []any
+	for {
+		h, err := gensenc.PeekHeader(&buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case h.Is(reflect.TypeFor[ping]()):
+			var p ping
+			err = gensenc.DecodeBody(&buf, h, &p)
+			got = append(got, p)
+		case h.Is(reflect.TypeFor[*pong]()):
+			var p pong
+			err = gensenc.DecodeBody(&buf, h, &p)
+			got = append(got, p)
+		default:
+			_, err = io.CopyN(io.Discard, &buf, int64(h.Length))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []any{ping{1}, pong{2, "a"}, ping{4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFramesErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gensenc.WriteFrame(&buf, pong{1, "a"}); err != nil {
+		t.Fatal(err)
+	}
+	frame := bytes.Clone(buf.Bytes())
+
+	// A body of another type is left unread.
+	h, err := gensenc.PeekHeader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := buf.Len()
+	var p ping
+	if err := gensenc.DecodeBody(&buf, h, &p); !errors.Is(err, gensenc.ErrFingerprintMismatch) || buf.Len() != n {
+		t.Errorf("decoding a pong as a ping gave %v after reading %d bytes, want ErrFingerprintMismatch and none", err, n-buf.Len())
+	}
+
+	// A header claiming a longer body than its value.
+	h.Length++
+	var q pong
+	err = gensenc.DecodeBody(bytes.NewReader(append(buf.Bytes(), 0)), h, &q)
+	if !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("decoding a body with a byte too many gave %v, want ErrTrailingBytes", err)
+	}
+	// And a shorter one.
+	h.Length -= 2
+	if err := gensenc.DecodeBody(bytes.NewReader(buf.Bytes()), h, &q); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a body cut short gave %v, want ErrTruncated", err)
+	}
+
+	bad := bytes.Clone(frame)
+	bad[0] = 2
+	if _, err := gensenc.PeekHeader(bytes.NewReader(bad)); !errors.Is(err, gensenc.ErrUnsupportedVersion) {
+		t.Errorf("reading a version 2 header gave %v, want ErrUnsupportedVersion", err)
+	}
+	if _, err := gensenc.PeekHeader(bytes.NewReader(frame[:10])); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("reading a truncated header gave %v, want ErrTruncated", err)
+	}
+}
+
+// taggedAs returns body tagged with the type name, registered or not.
+func taggedAs(name string, body []byte) []byte {
+	b, _ := gensenc.Encode(name)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(body)))
+	return append(b, body...)
+}
+
+func TestTagged(t *testing.T) {
+	var buf bytes.Buffer
+	for _, v := range []any{ping{1}, pong{2, "a"}} {
+		if err := gensenc.WriteTagged(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	body, _ := gensenc.Encode(ping{3})
+	buf.Write(taggedAs("unknown", body))
+	if err := gensenc.WriteTagged(&buf, ping{4}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []any
+	for {
+		v, err := gensenc.ReadTagged(&buf)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, gensenc.ErrUnknownType) {
+			got = append(got, nil)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if want := []any{ping{1}, pong{2, "a"}, nil, ping{4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	b, err := gensenc.EncodeTagged(pong{5, "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v any
+	if err := gensenc.DecodeTagged(b, &v); err != nil || v != (pong{5, "b"}) {
+		t.Errorf("decoded %v, %v, want %v", v, err, pong{5, "b"})
+	}
+}
+
+func TestTaggedErrors(t *testing.T) {
+	if err := gensenc.WriteTagged(io.Discard, order{}); !errors.Is(err, gensenc.ErrUnregisteredType) {
+		t.Errorf("writing an unregistered type gave %v, want ErrUnregisteredType", err)
+	}
+	b, err := gensenc.EncodeTagged(pong{1, "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p ping
+	if err := gensenc.DecodeTagged(b, &p); !errors.Is(err, gensenc.ErrNotAssignable) {
+		t.Errorf("decoding a pong into a ping gave %v, want ErrNotAssignable", err)
+	}
+	var v any
+	if err := gensenc.DecodeTagged(taggedAs("unknown", nil), &v); !errors.Is(err, gensenc.ErrUnknownType) {
+		t.Errorf("decoding an unknown type gave %v, want ErrUnknownType", err)
+	}
+
+	// A length longer than the value.
+	body, _ := gensenc.Encode(ping{1})
+	long := taggedAs("ping", append(body, 0))
+	if err := gensenc.DecodeTagged(long, &v); !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("decoding a byte too many gave %v, want ErrTrailingBytes", err)
+	}
+	if _, err := gensenc.ReadTagged(bytes.NewReader(long)); !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("reading a byte too many gave %v, want ErrTrailingBytes", err)
+	}
+	if _, err := gensenc.ReadTagged(bytes.NewReader(b[:len(b)-1])); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("reading a truncated value gave %v, want ErrTruncated", err)
+	}
+}
+
+type (
+	figure interface{ Area() float64 }
+	circle struct{ R float64 }
+	square struct{ S float64 }
+)
+
+func (c circle) Area() float64 { return 3 * c.R * c.R }
+func (s square) Area() float64 { return s.S * s.S }
+
+func init() {
+	gensenc.RegisterName("circle", circle{})
+	gensenc.RegisterName("square", &square{})
+}
+
+// TestTaggedInterface decodes tagged values into an interface their types
+// implement.
+func TestTaggedInterface(t *testing.T) {
+	var figures []figure
+	for _, v := range []any{circle{1}, &square{2}} {
+		b, err := gensenc.EncodeTagged(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s figure
+		if err := gensenc.DecodeTagged(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		figures = append(figures, s)
+	}
+	if want := []figure{circle{1}, &square{2}}; !reflect.DeepEqual(figures, want) {
+		t.Errorf("got %v, want %v", figures, want)
+	}
+
+	b, err := gensenc.EncodeTagged(circle{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c circle
+	if err := gensenc.DecodeTagged(b, &c); err != nil || c.R != 1 {
+		t.Errorf("decoding into the type itself gave %v, %v", c, err)
+	}
+	var str interface{ String() string }
+	if err := gensenc.DecodeTagged(b, &str); !errors.Is(err, gensenc.ErrNotAssignable) {
+		t.Errorf("decoding into an interface not implemented gave %v, want ErrNotAssignable", err)
+	}
+	var s figure
+	if err := gensenc.DecodeTagged(b, s); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("decoding into a nil interface gave %v, want ErrNilPointer", err)
+	}
+	if err := gensenc.DecodeTagged(b[:len(b)-1], &s); !errors.Is(err, gensenc.ErrInvalidLength) || s != nil {
+		t.Errorf("decoding a truncated value gave %v into %v, want ErrInvalidLength and nothing", err, s)
+	}
+}
@@ -0,0 +1,101 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type fuzzDoc struct {
+	ID     uint32
+	Name   string
+	Parent *fuzzDoc
+	Tags   map[string][]int16
+	Attrs  map[int]*fuzzDoc
+	Value  any
+	Grid   [2][2]uint8
+	Title  string   `gensenc:"section=head"`
+	Score  float64  `gensenc:"section=head"`
+	Notes  []string `gensenc:"body"`
+	Footer *string
+}
+
+// fuzzSeeds returns encodings of fuzzDoc values taking every path of
+// decoding: nested structs and pointers, maps of slices and of pointers,
+// interface values, arrays and sections.
+func fuzzSeeds(f *testing.F) [][]byte {
+	footer := "end"
+	docs := []fuzzDoc{
+		{},
+		{ID: 1, Name: "root", Value: int64(-7), Title: "t", Score: 0.5},
+		{
+			ID:     2,
+			Parent: &fuzzDoc{ID: 3, Parent: &fuzzDoc{Name: "grandparent"}},
+			Tags:   map[string][]int16{"a": {1, -2}, "": nil},
+			Attrs:  map[int]*fuzzDoc{1: {Name: "child"}, 2: nil},
+			Value:  map[string]any{"k": []any{"v", 1.5, true}},
+			Grid:   [2][2]uint8{{1, 2}, {3, 255}},
+			Notes:  []string{"x", "yz"},
+			Footer: &footer,
+		},
+	}
+	seeds := make([][]byte, len(docs))
+	for i, d := range docs {
+		b, err := gensenc.Encode(d)
+		if err != nil {
+			f.Fatal(err)
+		}
+		seeds[i] = b
+	}
+	return seeds
+}
+
+// FuzzDecodeValue checks that decoding arbitrary input never panics and
+// fails only with a *DecodeError, or io.EOF for empty input, and that what it decodes encodes again.
+func FuzzDecodeValue(f *testing.F) {
+	for _, b := range fuzzSeeds(f) {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var doc fuzzDoc
+		err := gensenc.DecodeValue(bytes.NewReader(b), reflect.ValueOf(&doc))
+		// Empty input is the end of a stream of values.
+		if err == io.EOF && len(b) == 0 {
+			return
+		}
+		if err != nil {
+			var de *gensenc.DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("decoding failed with %T %v, want a *DecodeError", err, err)
+			}
+			return
+		}
+		if _, err := gensenc.Encode(doc); err != nil {
+			t.Fatalf("encoding a decoded value: %v", err)
+		}
+	})
+}
+
+// FuzzValidate checks that Validate never panics and accepts only input
+// that decodes, but for integers too large for their field.
+func FuzzValidate(f *testing.F) {
+	for _, b := range fuzzSeeds(f) {
+		f.Add(b)
+	}
+	t := reflect.TypeFor[fuzzDoc]()
+	f.Fuzz(func(tt *testing.T, b []byte) {
+		if gensenc.Validate(b, t) != nil {
+			return
+		}
+		// Validate checks the structure of the input, not whether integers
+		// fit their fields.
+		var doc fuzzDoc
+		if err := gensenc.Decode(b, &doc); err != nil && !errors.Is(err, gensenc.ErrOverflow) {
+			tt.Fatalf("Validate accepted input Decode rejects: %v", err)
+		}
+	})
+}
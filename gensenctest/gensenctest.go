@@ -0,0 +1,188 @@
+// Package gensenctest helps testing types against gensenc. Generate builds
+// random values of a type, RoundTrip checks that a value survives encoding
+// and decoding, and Check and Fuzz combine the two:
+//
+//	func TestOrderEncoding(t *testing.T) {
+//		gensenctest.Check[Order](t, 100)
+//	}
+//
+//	func FuzzOrderDecoding(f *testing.F) {
+//		gensenctest.Fuzz(f, Order{ID: 1, Items: []Item{{Name: "a"}}})
+//	}
+//
+// Values are compared by their encoding, as with gensenc.EqualEncoded, so
+// that unexported fields, redacted fields and the difference between nil
+// and empty slices and maps don't count.
+package gensenctest
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// maxDepth bounds the nesting of generated values, past which slices and
+// maps are empty and pointers nil, so that recursive types end.
+const maxDepth = 5
+
+// maxLen bounds the length of generated strings, slices and maps.
+const maxLen = 6
+
+// Generate returns a random value of type t. Exported struct fields are
+// filled in, others left zero, as are channels, functions and interfaces.
+// Integers are small or anywhere in their range, floats finite or
+// infinite but never NaN, and strings a mix of ASCII and other runes.
+//
+// Values don't respect constraints of struct tags such as enum or bits,
+// so types with such fields may need values built by hand.
+func Generate(t reflect.Type, r *rand.Rand) reflect.Value {
+	v := reflect.New(t).Elem()
+	generate(v, r, 0)
+	return v
+}
+
+// Value is Generate for the type T.
+func Value[T any](r *rand.Rand) T {
+	return Generate(reflect.TypeFor[T](), r).Interface().(T)
+}
+
+func generate(v reflect.Value, r *rand.Rand, depth int) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.IntN(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// SetInt truncates to the size of the kind.
+		if r.IntN(2) == 0 {
+			v.SetInt(r.Int64N(256) - 128)
+		} else {
+			v.SetInt(int64(r.Uint64()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if r.IntN(2) == 0 {
+			v.SetUint(r.Uint64N(256))
+		} else {
+			v.SetUint(r.Uint64())
+		}
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float(r))
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(float(r), float(r)))
+	case reflect.String:
+		runes := make([]rune, r.IntN(maxLen+1))
+		for i := range runes {
+			if r.IntN(4) == 0 {
+				runes[i] = rune(0x80 + r.IntN(0x3000))
+			} else {
+				runes[i] = rune(' ' + r.IntN(95))
+			}
+		}
+		v.SetString(string(runes))
+	case reflect.Slice:
+		n := length(r, depth)
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := range n {
+			generate(v.Index(i), r, depth+1)
+		}
+	case reflect.Array:
+		for i := range v.Len() {
+			generate(v.Index(i), r, depth+1)
+		}
+	case reflect.Map:
+		n := length(r, depth)
+		v.Set(reflect.MakeMapWithSize(v.Type(), n))
+		for range n {
+			key := reflect.New(v.Type().Key()).Elem()
+			generate(key, r, depth+1)
+			elem := reflect.New(v.Type().Elem()).Elem()
+			generate(elem, r, depth+1)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Pointer:
+		if depth < maxDepth && r.IntN(4) != 0 {
+			v.Set(reflect.New(v.Type().Elem()))
+			generate(v.Elem(), r, depth+1)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				generate(v.Field(i), r, depth+1)
+			}
+		}
+	}
+}
+
+func length(r *rand.Rand, depth int) int {
+	if depth >= maxDepth {
+		return 0
+	}
+	return r.IntN(maxLen + 1)
+}
+
+func float(r *rand.Rand) float64 {
+	switch r.IntN(8) {
+	case 0:
+		return 0
+	case 1:
+		return math.Inf(1 - 2*r.IntN(2))
+	case 2:
+		return float64(r.IntN(1000)) / 4
+	}
+	return r.NormFloat64() * math.Pow(10, float64(r.IntN(40)-20))
+}
+
+// RoundTrip encodes v with a codec configured by opts, decodes the result
+// into a new T and fails t unless both succeed and the decoded value equals
+// v.
+func RoundTrip[T any](t testing.TB, v T, opts ...gensenc.Option) {
+	t.Helper()
+	c := gensenc.New(opts...)
+	b, err := c.Encode(v)
+	if err != nil {
+		t.Fatalf("encoding %T: %v", v, err)
+	}
+	var got T
+	err = c.Decode(b, &got)
+	if err != nil {
+		t.Fatalf("decoding %T: %v", v, err)
+	}
+	equal, err := gensenc.EqualEncoded(v, got)
+	if err != nil {
+		t.Fatalf("comparing %T: %v", v, err)
+	}
+	if !equal {
+		t.Fatalf("round trip of %T:\ngot  %+v\nwant %+v", v, got, v)
+	}
+}
+
+// Check runs RoundTrip with n values of T from Generate, seeded so that
+// failures can be reproduced.
+func Check[T any](t testing.TB, n int, opts ...gensenc.Option) {
+	t.Helper()
+	r := rand.New(rand.NewPCG(1, 2))
+	for range n {
+		RoundTrip(t, Value[T](r), opts...)
+	}
+}
+
+// Fuzz fuzzes decoding into T with the encodings of seeds as the seed
+// corpus. Decoding arbitrary input must not panic, and whatever decodes
+// without error must pass RoundTrip.
+func Fuzz[T any](f *testing.F, seeds ...T) {
+	f.Helper()
+	for _, s := range seeds {
+		b, err := gensenc.Encode(s)
+		if err != nil {
+			f.Fatalf("encoding seed %T: %v", s, err)
+		}
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v T
+		if gensenc.Decode(b, &v) == nil {
+			RoundTrip(t, v)
+		}
+	})
+}
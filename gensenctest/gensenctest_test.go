@@ -0,0 +1,90 @@
+package gensenctest_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/CodeSpoof/gogenericencoder/gensenctest"
+)
+
+type item struct {
+	Name  string
+	Qty   uint16
+	Price float32
+	Tags  map[string][]byte
+}
+
+type record struct {
+	ID     int64
+	Items  []item
+	Next   *record
+	Arr    [3]complex64
+	Ok     bool
+	M      map[int8]*item
+	hidden int
+}
+
+func TestCheck(t *testing.T) {
+	gensenctest.Check[record](t, 300)
+	gensenctest.Check[[]map[string]float64](t, 100)
+}
+
+func TestGenerate(t *testing.T) {
+	a := gensenctest.Value[record](rand.New(rand.NewPCG(1, 2)))
+	b := gensenctest.Value[record](rand.New(rand.NewPCG(1, 2)))
+	if !reflect.DeepEqual(a, b) {
+		t.Error("values from the same seed differ")
+	}
+	// Recursive types end, and unexported fields stay zero.
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 100 {
+		v := gensenctest.Value[record](r)
+		depth := 0
+		for p := &v; p != nil; p = p.Next {
+			depth++
+			if p.hidden != 0 {
+				t.Fatal("unexported field set")
+			}
+		}
+		if depth > 6 {
+			t.Fatalf("records nested %d deep", depth)
+		}
+	}
+	if v := gensenctest.Generate(reflect.TypeFor[chan int](), r); !v.IsNil() {
+		t.Error("generated a channel")
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	gensenctest.Fuzz(f, record{ID: 1, Items: []item{{Name: "a"}}})
+}
+
+// fatalRecorder is a testing.TB recording why it was failed.
+type fatalRecorder struct {
+	testing.TB
+	msg string
+}
+
+func (r *fatalRecorder) Helper() {}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestRoundTripFails(t *testing.T) {
+	r := &fatalRecorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gensenctest.RoundTrip(r, struct{ C chan int }{})
+	}()
+	<-done
+	if !strings.HasPrefix(r.msg, "encoding struct { C chan int }") {
+		t.Errorf("failed with %q, want an encoding failure", r.msg)
+	}
+}
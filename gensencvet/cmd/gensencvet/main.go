@@ -0,0 +1,12 @@
+// Command gensencvet runs the gensencvet analyzer, standalone or as a vet
+// tool through go vet -vettool.
+package main
+
+import (
+	"github.com/CodeSpoof/gogenericencoder/gensencvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(gensencvet.Analyzer)
+}
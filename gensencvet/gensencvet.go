@@ -0,0 +1,393 @@
+// Package gensencvet defines an analyzer reporting values passed to gensenc
+// that can't be encoded, so that they are found when vetting instead of
+// failing at run time.
+//
+// It lives in a module of its own so that gensenc itself doesn't depend on
+// golang.org/x/tools.
+package gensencvet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const gensencPath = "github.com/CodeSpoof/gogenericencoder"
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "gensenc",
+	Doc:       "report values passed to gensenc that can't be encoded",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{(*registered)(nil)},
+}
+
+// valueArgs maps the functions and methods of gensenc taking a value to
+// encode or decode to the indices of those arguments. Generic functions
+// whose value is given by their type argument, such as DecodeBatch, map to
+// typeArg instead, and arguments calling reflect.ValueOf are looked into.
+var valueArgs = map[string][]int{
+	"Encode":              {0},
+	"EncodeInterned":      {0},
+	"EncodeChecked":       {0},
+	"EncodeColumnar":      {0},
+	"EncodeCompressed":    {1},
+	"EncodeContext":       {2},
+	"EncodeFlat":          {0},
+	"EncodeFormat":        {1},
+	"EncodeParallel":      {0},
+	"EncodeProto":         {0},
+	"EncodeSealed":        {1},
+	"EncodeSigned":        {1},
+	"EncodeBatch":         {0},
+	"EncodeSeq":           {typeArg},
+	"EncodeTagged":        {0},
+	"EncodePatch":         {0, 1},
+	"EncodeToWriterAt":    {2},
+	"EncodeValue":         {0},
+	"EncodeValueTo":       {1},
+	"Hash":                {0},
+	"EqualEncoded":        {0, 1},
+	"Decode":              {1},
+	"DecodeInterned":      {1},
+	"DecodeAt":            {2},
+	"DecodeAlias":         {1},
+	"DecodeArena":         {1},
+	"DecodeBatch":         {typeArg},
+	"DecodeChecked":       {1},
+	"DecodeChunked":       {1},
+	"DecodeColumnar":      {1},
+	"DecodeCompressed":    {1},
+	"DecodeContext":       {2},
+	"DecodeFields":        {1},
+	"DecodeFormat":        {2},
+	"DecodeProto":         {1},
+	"DecodeSealed":        {2},
+	"DecodeSigned":        {2},
+	"DecodeTagged":        {1},
+	"DecodeValue":         {1},
+	"ApplyPatch":          {1},
+	"Encoder.Encode":      {0},
+	"Encoder.EncodeValue": {0},
+	"Decoder.Decode":      {0},
+	"Decoder.DecodeValue": {0},
+	"Codec.Encode":        {0},
+	"Codec.EncodeValue":   {0},
+	"Codec.EncodeValueTo": {1},
+	"Codec.EncodeTagged":  {0},
+	"Codec.Decode":        {1},
+	"Codec.DecodeValue":   {1},
+	"Codec.DecodeTagged":  {1},
+	"FlatValue.Decode":    {0},
+	"Raw.Decode":          {0},
+}
+
+const typeArg = -1
+
+// codecTypes lists the types of other packages that gensenc encodes with a
+// dedicated codec rather than as structs of their exported fields.
+var codecTypes = map[string]bool{
+	"math/big.Int":             true,
+	"math/big.Float":           true,
+	"math/big.Rat":             true,
+	"net/netip.Addr":           true,
+	"net/netip.AddrPort":       true,
+	"net/netip.Prefix":         true,
+	"net.IP":                   true,
+	"net.IPMask":               true,
+	"database/sql.NullBool":    true,
+	"database/sql.NullByte":    true,
+	"database/sql.NullFloat64": true,
+	"database/sql.NullInt16":   true,
+	"database/sql.NullInt32":   true,
+	"database/sql.NullInt64":   true,
+	"database/sql.NullString":  true,
+	"database/sql.NullTime":    true,
+	"time.Time":                true,
+}
+
+// registered is a fact of packages calling gensenc.Register or
+// gensenc.RegisterName, naming the types they register for interface
+// values, so that packages importing them know those types are registered.
+type registered struct {
+	Types []registeredType
+}
+
+type registeredType struct {
+	Path, Name string
+	Pointer    bool
+}
+
+func (*registered) AFact() {}
+
+func (r *registered) String() string {
+	names := make([]string, len(r.Types))
+	for i, t := range r.Types {
+		names[i] = t.Path + "." + t.Name
+		if t.Pointer {
+			names[i] = "*" + names[i]
+		}
+	}
+	return "registered(" + strings.Join(names, ", ") + ")"
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	c := &checker{visiting: map[types.Type]bool{}}
+	var fact registered
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		var i int
+		switch gensencFunc(pass, call) {
+		case "Register":
+			i = 0
+		case "RegisterName":
+			i = 1
+		default:
+			return
+		}
+		if i >= len(call.Args) {
+			return
+		}
+		t := pass.TypesInfo.TypeOf(call.Args[i])
+		if r, ok := newRegisteredType(t); ok {
+			fact.Types = append(fact.Types, r)
+			c.registered = append(c.registered, t)
+		}
+	})
+	if len(fact.Types) > 0 {
+		pass.ExportPackageFact(&fact)
+	}
+	for _, f := range pass.AllPackageFacts() {
+		if f.Package == pass.Pkg {
+			continue
+		}
+		for _, r := range f.Fact.(*registered).Types {
+			if t := r.lookup(f.Package); t != nil {
+				c.registered = append(c.registered, t)
+			}
+		}
+	}
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		for _, i := range valueArgs[gensencFunc(pass, call)] {
+			pos, t := valueArg(pass, call, i)
+			// Interface values passed to be encoded are encoded as the
+			// value they hold.
+			if t == nil || types.IsInterface(t) {
+				continue
+			}
+			if why := c.check(t); why != "" {
+				pass.Reportf(pos, "gensenc can't encode %s: %s", t, why)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// gensencFunc returns the name of the function or method of gensenc that
+// call calls, prefixed with the name of its receiver type for methods, or
+// "" if call calls something else.
+func gensencFunc(pass *analysis.Pass, call *ast.CallExpr) string {
+	id := callee(call)
+	if id == nil {
+		return ""
+	}
+	fn, ok := pass.TypesInfo.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != gensencPath {
+		return ""
+	}
+	name := fn.Name()
+	if recv := fn.Signature().Recv(); recv != nil {
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		named, ok := t.(*types.Named)
+		if !ok {
+			return ""
+		}
+		name = named.Obj().Name() + "." + name
+	}
+	return name
+}
+
+// callee returns the identifier naming the function call calls, looking
+// through explicit instantiations such as DecodeBatch[T].
+func callee(call *ast.CallExpr) *ast.Ident {
+	fun := ast.Unparen(call.Fun)
+	switch x := fun.(type) {
+	case *ast.IndexExpr:
+		fun = x.X
+	case *ast.IndexListExpr:
+		fun = x.X
+	}
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	}
+	return nil
+}
+
+// valueArg returns the position and type of the value that the argument i
+// of call, or its type argument for typeArg, holds to encode or decode.
+// Arguments calling reflect.ValueOf stand for the argument of that call.
+func valueArg(pass *analysis.Pass, call *ast.CallExpr, i int) (token.Pos, types.Type) {
+	if i == typeArg {
+		inst := pass.TypesInfo.Instances[callee(call)]
+		if inst.TypeArgs == nil || inst.TypeArgs.Len() == 0 {
+			return token.NoPos, nil
+		}
+		return call.Pos(), inst.TypeArgs.At(0)
+	}
+	if i >= len(call.Args) {
+		return token.NoPos, nil
+	}
+	arg := call.Args[i]
+	if inner, ok := ast.Unparen(arg).(*ast.CallExpr); ok && len(inner.Args) == 1 {
+		if fn, ok := typeutil.Callee(pass.TypesInfo, inner).(*types.Func); ok && fn.FullName() == "reflect.ValueOf" {
+			arg = inner.Args[0]
+		}
+	}
+	return arg.Pos(), pass.TypesInfo.TypeOf(arg)
+}
+
+// newRegisteredType returns the fact recording that values of type t are
+// registered, if t is a named type or a pointer to one.
+func newRegisteredType(t types.Type) (registeredType, bool) {
+	var r registeredType
+	if p, ok := types.Unalias(t).(*types.Pointer); ok {
+		r.Pointer = true
+		t = p.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.TypeArgs() != nil {
+		return r, false
+	}
+	r.Path, r.Name = named.Obj().Pkg().Path(), named.Obj().Name()
+	return r, true
+}
+
+// lookup returns the type r records, found in pkg or the packages it
+// imports, or nil if it can't be found.
+func (r registeredType) lookup(pkg *types.Package) types.Type {
+	seen := map[*types.Package]bool{}
+	var find func(*types.Package) types.Type
+	find = func(p *types.Package) types.Type {
+		if seen[p] {
+			return nil
+		}
+		seen[p] = true
+		if p.Path() == r.Path {
+			obj, ok := p.Scope().Lookup(r.Name).(*types.TypeName)
+			if !ok {
+				return nil
+			}
+			if r.Pointer {
+				return types.NewPointer(obj.Type())
+			}
+			return obj.Type()
+		}
+		for _, imp := range p.Imports() {
+			if t := find(imp); t != nil {
+				return t
+			}
+		}
+		return nil
+	}
+	return find(pkg)
+}
+
+// A checker finds why values of a type can't be encoded.
+type checker struct {
+	// registered holds the types registered for interface values by the
+	// package being checked and the packages it imports.
+	registered []types.Type
+	visiting   map[types.Type]bool
+}
+
+// check returns why values of type t can't be encoded, or "" if they can.
+func (c *checker) check(t types.Type) string {
+	if c.visiting[t] {
+		return ""
+	}
+	c.visiting[t] = true
+	defer delete(c.visiting, t)
+	if named, ok := types.Unalias(t).(*types.Named); ok && named.Obj().Pkg() != nil {
+		if codecTypes[named.Obj().Pkg().Path()+"."+named.Obj().Name()] {
+			return ""
+		}
+	}
+	// The types of type parameters are only known when instantiated.
+	if _, ok := types.Unalias(t).(*types.TypeParam); ok {
+		return ""
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Uintptr, types.UnsafePointer:
+			return "unsupported kind " + u.Name()
+		}
+	case *types.Chan:
+		return "unsupported kind chan"
+	case *types.Signature:
+		return "unsupported kind func"
+	case *types.Interface:
+		// Empty interfaces hold the types registered by default.
+		if u.Empty() {
+			return ""
+		}
+		for _, r := range c.registered {
+			if types.Implements(r, u) {
+				return ""
+			}
+		}
+		return "no registered type implements " + t.String()
+	case *types.Pointer:
+		return c.check(u.Elem())
+	case *types.Slice:
+		return elem(c.check(u.Elem()))
+	case *types.Array:
+		return elem(c.check(u.Elem()))
+	case *types.Map:
+		if why := c.check(u.Key()); why != "" {
+			return "key: " + why
+		}
+		return elem(c.check(u.Elem()))
+	case *types.Struct:
+		exported := false
+		for i := range u.NumFields() {
+			f := u.Field(i)
+			if !f.Exported() {
+				continue
+			}
+			exported = true
+			if reflect.StructTag(u.Tag(i)).Get("gensenc") == "-" {
+				continue
+			}
+			if why := c.check(f.Type()); why != "" {
+				return "field " + f.Name() + ": " + why
+			}
+		}
+		if !exported && u.NumFields() > 0 {
+			return "only unexported fields, which are not encoded"
+		}
+	}
+	return ""
+}
+
+func elem(why string) string {
+	if why == "" || strings.HasPrefix(why, "element: ") {
+		return why
+	}
+	return "element: " + why
+}
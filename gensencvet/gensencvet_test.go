@@ -0,0 +1,12 @@
+package gensencvet_test
+
+import (
+	"testing"
+
+	"github.com/CodeSpoof/gogenericencoder/gensencvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), gensencvet.Analyzer, "a")
+}
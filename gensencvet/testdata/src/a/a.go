@@ -0,0 +1,75 @@
+package a // want package:`registered\(\*a\.user\)`
+
+import (
+	"reflect"
+	"slices"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"shapes"
+)
+
+type Job struct {
+	ID   int
+	Done chan bool
+}
+
+type Hook struct {
+	Name string
+	Run  func()
+}
+
+type private struct {
+	id   int
+	name string
+}
+
+type Skipped struct {
+	ID   int
+	Done chan bool `gensenc:"-"`
+}
+
+type Drawing struct {
+	Shapes []shapes.Shape
+	Sides  shapes.Polygon
+}
+
+type Named interface{ Name() string }
+
+type user struct{ N string }
+
+func (u *user) Name() string { return u.N }
+
+func init() {
+	gensenc.RegisterName("user", &user{})
+}
+
+type Account struct {
+	Owner Named
+}
+
+func calls(b []byte, c *gensenc.Codec, s shapes.Shape) {
+	gensenc.Encode(Job{})                          // want `gensenc can't encode a.Job: field Done: unsupported kind chan`
+	gensenc.Decode(b, &Hook{})                     // want `gensenc can't encode \*a.Hook: field Run: unsupported kind func`
+	gensenc.Encode([]private{})                    // want `gensenc can't encode \[\]a.private: element: only unexported fields, which are not encoded`
+	gensenc.Encode(Drawing{})                      // want `gensenc can't encode a.Drawing: field Sides: no registered type implements shapes.Polygon`
+	gensenc.EncodeValue(reflect.ValueOf(Job{}))    // want `gensenc can't encode a.Job: field Done`
+	c.EncodeValue(reflect.ValueOf(&Hook{}))        // want `gensenc can't encode \*a.Hook: field Run`
+	gensenc.Raw(b).Decode(&Job{})                  // want `gensenc can't encode \*a.Job`
+	gensenc.DecodeBatch[Hook](b)                   // want `gensenc can't encode a.Hook`
+	gensenc.EncodeSeq(nil, slices.Values([]Job{})) // want `gensenc can't encode a.Job`
+	gensenc.EncodeBatch([]Job{})                   // want `gensenc can't encode \[\]a.Job`
+	gensenc.EqualEncoded(Job{}, Hook{})            // want `can't encode a.Job` `can't encode a.Hook`
+	gensenc.EncodePatch(&Job{}, &Job{})            // want `can't encode \*a.Job` `can't encode \*a.Job`
+
+	gensenc.Encode(Skipped{})
+	gensenc.Encode(Account{})
+	gensenc.Encode([]shapes.Shape{})
+	gensenc.Encode(map[string]any{})
+	gensenc.Encode(s)
+	gensenc.Decode(b, &s)
+}
+
+func generic[T any](v T) {
+	gensenc.Encode(v)
+	gensenc.DecodeBatch[T](nil)
+}
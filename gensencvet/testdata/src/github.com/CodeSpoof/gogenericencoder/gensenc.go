@@ -0,0 +1,27 @@
+// Package gensenc stubs the functions of gensenc the analyzer looks at.
+package gensenc
+
+import (
+	"io"
+	"iter"
+	"reflect"
+)
+
+func Encode(a any) ([]byte, error)                        { return nil, nil }
+func Decode(b []byte, a any) error                        { return nil }
+func EncodeValue(v reflect.Value) ([]byte, error)         { return nil, nil }
+func EncodeBatch[T any](s []T) ([]byte, error)            { return nil, nil }
+func DecodeBatch[T any](b []byte) ([]T, error)            { return nil, nil }
+func EncodeSeq[T any](w io.Writer, seq iter.Seq[T]) error { return nil }
+func EqualEncoded(a, b any) (bool, error)                 { return false, nil }
+func EncodePatch(old, new any) ([]byte, error)            { return nil, nil }
+func Register(value any)                                  {}
+func RegisterName(name string, value any)                 {}
+
+type Codec struct{}
+
+func (c *Codec) EncodeValue(v reflect.Value) ([]byte, error) { return nil, nil }
+
+type Raw []byte
+
+func (r Raw) Decode(a any) error { return nil }
@@ -0,0 +1,17 @@
+// Package shapes registers a type for an interface in another package.
+package shapes
+
+import gensenc "github.com/CodeSpoof/gogenericencoder"
+
+type Shape interface{ Area() float64 }
+
+type Circle struct{ R float64 }
+
+func (c Circle) Area() float64 { return 3 * c.R * c.R }
+
+// Polygon is implemented by no registered type.
+type Polygon interface{ Sides() int }
+
+func init() {
+	gensenc.Register(Circle{})
+}
@@ -0,0 +1,68 @@
+// Package gobcompat exposes gensenc through the API of encoding/gob, so
+// that code written against gob can switch encoders by changing an import.
+//
+// Unlike gob, the stream carries no type information: values must be
+// decoded into the same types they were encoded from. Only interface values
+// carry the name their concrete type was registered under.
+package gobcompat
+
+import (
+	"errors"
+	"io"
+	"reflect"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var ErrNilValue error = errors.New("gobcompat: cannot decode into nil value")
+
+type Encoder struct {
+	enc *gensenc.Encoder
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: gensenc.NewEncoder(w)}
+}
+
+func (enc *Encoder) Encode(e any) error {
+	return enc.enc.Encode(e)
+}
+
+func (enc *Encoder) EncodeValue(value reflect.Value) error {
+	return enc.enc.EncodeValue(value)
+}
+
+type Decoder struct {
+	dec *gensenc.Decoder
+}
+
+// NewDecoder returns a new decoder that reads from r. As with gob, if r
+// does not also implement io.ByteReader, it will be wrapped in a
+// bufio.Reader.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: gensenc.NewDecoder(r)}
+}
+
+func (dec *Decoder) Decode(e any) error {
+	if e == nil {
+		return ErrNilValue
+	}
+	return dec.dec.Decode(e)
+}
+
+func (dec *Decoder) DecodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return ErrNilValue
+	}
+	return dec.dec.DecodeValue(v)
+}
+
+// Register records a type for interface values, like gob.Register.
+func Register(value any) {
+	gensenc.Register(value)
+}
+
+// RegisterName is like gob.RegisterName.
+func RegisterName(name string, value any) {
+	gensenc.RegisterName(name, value)
+}
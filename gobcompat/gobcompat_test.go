@@ -0,0 +1,82 @@
+package gobcompat_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/CodeSpoof/gogenericencoder/gobcompat"
+)
+
+type shape interface{ Area() float64 }
+
+type square struct{ Side float64 }
+
+func (s square) Area() float64 { return s.Side * s.Side }
+
+type drawing struct {
+	Name   string
+	Shapes []shape
+}
+
+func init() {
+	gobcompat.RegisterName("square", square{})
+}
+
+func TestStream(t *testing.T) {
+	values := []drawing{
+		{Name: "a", Shapes: []shape{square{2}, nil}},
+		{Name: "b"},
+	}
+	var buf bytes.Buffer
+	enc := gobcompat.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.EncodeValue(reflect.ValueOf(42)); err != nil {
+		t.Fatal(err)
+	}
+	// A reader without ReadByte is buffered, as by gob.
+	dec := gobcompat.NewDecoder(struct{ io.Reader }{&buf})
+	for _, want := range values {
+		var got drawing
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	var n int
+	if err := dec.DecodeValue(reflect.ValueOf(&n)); err != nil || n != 42 {
+		t.Errorf("decoded %d, %v, want 42", n, err)
+	}
+	if err := dec.Decode(&n); err != io.EOF {
+		t.Errorf("decoding past the end gave %v, want io.EOF", err)
+	}
+}
+
+func TestNilValue(t *testing.T) {
+	dec := gobcompat.NewDecoder(bytes.NewReader(make([]byte, 8)))
+	if err := dec.Decode(nil); !errors.Is(err, gobcompat.ErrNilValue) {
+		t.Errorf("decoding into nil gave %v, want ErrNilValue", err)
+	}
+	if err := dec.DecodeValue(reflect.Value{}); !errors.Is(err, gobcompat.ErrNilValue) {
+		t.Errorf("decoding into the zero Value gave %v, want ErrNilValue", err)
+	}
+}
+
+type circle struct{ R float64 }
+
+func (c circle) Area() float64 { return 3 * c.R * c.R }
+
+func TestUnregistered(t *testing.T) {
+	err := gobcompat.NewEncoder(io.Discard).Encode(drawing{Shapes: []shape{circle{1}}})
+	if err == nil {
+		t.Error("encoding an unregistered type succeeded")
+	}
+}
@@ -0,0 +1,39 @@
+package gensenc
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"reflect"
+)
+
+// streamThreshold is the number of buffered bytes past which encoding to a
+// writer hands them on.
+const streamThreshold = 32 << 10
+
+func (e *encodeState) flush() error {
+	_, err := e.w.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+// encodeStream encodes v with the given options to w, writing the
+// encoding in pieces as it is produced instead of holding all of it.
+func encodeStream(w io.Writer, opts options, v reflect.Value) error {
+	e := &encodeState{buf: bytes.NewBuffer(nil), options: opts, w: w}
+	if opts.intern {
+		e.strings = map[string]uint64{}
+	}
+	err := e.encodeRoot(v)
+	if err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// Hash writes the canonical encoding of a, as with WithCanonical, to h,
+// in pieces as it is produced, so that large values can be hashed without
+// holding their whole encoding.
+func Hash(a any, h hash.Hash) error {
+	return encodeStream(h, options{canonical: true}, addressable(reflect.ValueOf(a)))
+}
@@ -0,0 +1,75 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// pieceHash counts the writes to a hash.
+type pieceHash struct {
+	hash.Hash
+	writes int
+}
+
+func (h *pieceHash) Write(p []byte) (int, error) {
+	h.writes++
+	return h.Hash.Write(p)
+}
+
+type hashed struct {
+	M map[string]int
+	S []string
+	N sectionNew
+}
+
+func TestHash(t *testing.T) {
+	a := hashed{M: map[string]int{}, N: sectionNew{A: 1, B: "b"}}
+	b := hashed{M: map[string]int{}, N: a.N}
+	for i := range 20000 {
+		k := string(rune('a'+i%26)) + string(rune(i))
+		a.M[k] = i
+		a.S = append(a.S, "hello world")
+		a.N.E = append(a.N.E, i)
+	}
+	// The same entries in another order.
+	for k, v := range a.M {
+		b.M[k] = v
+	}
+	b.S, b.N.E = a.S, a.N.E
+
+	ha := &pieceHash{Hash: sha256.New()}
+	if err := gensenc.Hash(a, ha); err != nil {
+		t.Fatal(err)
+	}
+	if ha.writes < 2 {
+		t.Errorf("hashed in %d writes, want pieces", ha.writes)
+	}
+	enc, err := gensenc.New(gensenc.WithCanonical()).Encode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(enc)
+	if !bytes.Equal(ha.Sum(nil), want[:]) {
+		t.Error("hash differs from that of the canonical encoding")
+	}
+	hb := sha256.New()
+	if err := gensenc.Hash(&b, hb); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hb.Sum(nil), want[:]) {
+		t.Error("hash differs for a pointer to an equal value")
+	}
+}
+
+func TestHashError(t *testing.T) {
+	err := gensenc.Hash(struct{ C chan int }{}, sha256.New())
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnsupportedKind) || ee.Path != "C" {
+		t.Errorf("got %v, want ErrUnsupportedKind at C", err)
+	}
+}
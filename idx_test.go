@@ -0,0 +1,91 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	idxDeclared struct {
+		X int64  `gensenc:"idx=1"`
+		Y string `gensenc:"idx=2"`
+		Z int64  `gensenc:"idx=3"`
+	}
+	idxReordered struct {
+		Z int64  `gensenc:"idx=3"`
+		Y string `gensenc:"idx=2"`
+		X int64  `gensenc:"idx=1"`
+	}
+	idxFixed struct {
+		B int64 `gensenc:"idx=20"`
+		A int64 `gensenc:"idx=10"`
+		s int64
+	}
+)
+
+func TestIdx(t *testing.T) {
+	b, err := gensenc.Encode(idxReordered{3, "y", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := gensenc.Encode(struct {
+		X int64
+		Y string
+		Z int64
+	}{1, "y", 3})
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x in idx order", b, want)
+	}
+	var d idxDeclared
+	if err := gensenc.Decode(b, &d); err != nil || d != (idxDeclared{1, "y", 3}) {
+		t.Errorf("got %+v, %v", d, err)
+	}
+	found, err := gensenc.CheckCompatible(gensenc.DescribeType(reflect.TypeFor[idxDeclared]()), gensenc.DescribeType(reflect.TypeFor[idxReordered]()))
+	if err != nil || len(found) != 0 {
+		t.Errorf("reordering declarations is incompatible: %v, %v", found, err)
+	}
+
+	// Fields of a fixed size are laid out in idx order too, gaps between
+	// positions included.
+	f := []idxFixed{{B: 2, A: 1}}
+	b, err = gensenc.Encode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[8] != 1 || b[16] != 2 {
+		t.Errorf("encoded as %x, want A before B", b)
+	}
+	if got := roundTrip(t, gensenc.New(), f); !reflect.DeepEqual(got, f) {
+		t.Errorf("got %+v, want %+v", got, f)
+	}
+}
+
+func TestIdxInvalid(t *testing.T) {
+	for _, v := range []any{
+		struct {
+			X int64 `gensenc:"idx=1"`
+			Y int64
+		}{},
+		struct {
+			X int64 `gensenc:"idx=1"`
+			Y int64 `gensenc:"idx=1"`
+		}{},
+		struct {
+			X int64 `gensenc:"idx=-1"`
+		}{},
+		struct {
+			X int64 `gensenc:"idx=a"`
+		}{},
+	} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+		if err := gensenc.Decode(make([]byte, 16), reflect.New(reflect.TypeOf(v)).Interface()); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("decoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+	}
+}
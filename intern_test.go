@@ -0,0 +1,76 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestInterned(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	v := map[string][]string{long: {long, "a", long, "a", ""}, "b": {long}}
+	plain, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		encode func(any) ([]byte, error)
+		decode func([]byte, any) error
+	}{
+		{"functions", gensenc.EncodeInterned, gensenc.DecodeInterned},
+		{"codec", gensenc.New(gensenc.WithInterning()).Encode, gensenc.New(gensenc.WithInterning()).Decode},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.encode(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The long string is written once instead of four times, at the cost
+			// of a reference or marker for every string.
+			if len(b) > len(plain)-2*len(long) {
+				t.Errorf("encoded as %d bytes, %d without interning", len(b), len(plain))
+			}
+			var got map[string][]string
+			err = tt.decode(b, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("got %v, want %v", got, v)
+			}
+		})
+	}
+}
+
+func TestInternedInvalidRef(t *testing.T) {
+	// A slice of two strings, the first new and the second referring to
+	// the second string read, which doesn't exist.
+	var b []byte
+	for _, n := range []uint64{2, 0, 1} {
+		b = binary.LittleEndian.AppendUint64(b, n)
+	}
+	b = append(b, 'a')
+	b = binary.LittleEndian.AppendUint64(b, 2)
+	var got []string
+	err := gensenc.DecodeInterned(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrInvalidStringRef) {
+		t.Fatalf("decoding a dangling reference gave %v, want ErrInvalidStringRef", err)
+	}
+	if de.Path != "[1]" {
+		t.Errorf("error at %q, want [1]", de.Path)
+	}
+	b[len(b)-8] = 1
+	err = gensenc.DecodeInterned(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+package gensenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// ToJSON decodes encoded as a value of type t and returns its JSON form.
+func ToJSON(encoded []byte, t reflect.Type) ([]byte, error) {
+	v := reflect.New(t)
+	err := Decode(encoded, v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v.Interface())
+}
+
+// FromJSON parses jsonBytes as a value of type t and returns its encoding.
+// Unknown JSON object keys are rejected so that typos in hand-edited
+// documents do not silently drop data.
+func FromJSON(jsonBytes []byte, t reflect.Type) ([]byte, error) {
+	v := reflect.New(t)
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return Encode(v.Interface())
+}
@@ -0,0 +1,62 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestJSON(t *testing.T) {
+	typ := reflect.TypeFor[order]()
+	note := "fragile"
+	v := order{ID: 7, Items: []string{"a", "b"}, Tags: map[string]int{"x": 1}, Note: &note}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := gensenc.ToJSON(b, typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ID":7,"Items":["a","b"],"Tags":{"x":1},"Note":"fragile"}`
+	if string(j) != want {
+		t.Errorf("got %s, want %s", j, want)
+	}
+	// Hand-edit the document and take it back.
+	b, err = gensenc.FromJSON([]byte(`{"ID":8,"Items":["c"],"Tags":{"y":2}}`), typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	err = gensenc.Decode(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := (order{ID: 8, Items: []string{"c"}, Tags: map[string]int{"y": 2}}); !reflect.DeepEqual(got, w) {
+		t.Errorf("got %+v, want %+v", got, w)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	typ := reflect.TypeFor[order]()
+	b, err := gensenc.Encode(order{ID: 1, Tags: map[string]int{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = gensenc.ToJSON(b[:len(b)-1], typ)
+	if !errors.Is(err, gensenc.ErrTruncated) && !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("converting truncated input gave %v", err)
+	}
+	// Typos in keys are errors rather than dropped data.
+	if _, err = gensenc.FromJSON([]byte(`{"ID":1,"Notes":"x"}`), typ); err == nil {
+		t.Error("an unknown key was accepted")
+	}
+	if _, err = gensenc.FromJSON([]byte(`{"ID":"1"}`), typ); err == nil {
+		t.Error("a string for an integer was accepted")
+	}
+	if _, err = gensenc.FromJSON([]byte(`{"ID":1`), typ); err == nil {
+		t.Error("a truncated document was accepted")
+	}
+}
@@ -0,0 +1,86 @@
+package gensenc
+
+import (
+	"errors"
+	"math"
+	"reflect"
+)
+
+// ErrInvalidKey is returned for map keys that would not decode back to the
+// same entry: pointers, which decode to new pointers equal to no key,
+// structs with unexported fields, which aren't encoded, and NaN floats,
+// which equal no key, not even themselves. Maps with keys of such types
+// fail to encode and decode as a whole; NaN keys fail when they occur.
+var ErrInvalidKey error = errors.New("invalid map key")
+
+// computeKeyType reports whether values of t are valid map keys by their
+// type and whether their values may still be invalid.
+func computeKeyType(t reflect.Type, visiting map[reflect.Type]bool) (valid, checkValues bool) {
+	if typeCodecs[t] != nil || visiting[t] {
+		return true, false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return false, false
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Interface:
+		return true, true
+	case reflect.Array:
+		return computeKeyType(t.Elem(), visiting)
+	case reflect.Struct:
+		valid = true
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !encoded(f) {
+				return false, false
+			}
+			v, c := computeKeyType(f.Type, visiting)
+			valid = valid && v
+			checkValues = checkValues || c
+		}
+		return valid, checkValues
+	}
+	return true, false
+}
+
+// validKey reports whether the map key k is valid, given that its type is.
+func validKey(k reflect.Value) bool {
+	switch k.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return !math.IsNaN(k.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := k.Complex()
+		return !math.IsNaN(real(c)) && !math.IsNaN(imag(c))
+	case reflect.Interface:
+		if k.IsNil() {
+			return true
+		}
+		valid, check := computeKeyType(k.Elem().Type(), map[reflect.Type]bool{})
+		return valid && (!check || validKey(k.Elem()))
+	case reflect.Array:
+		for i := range k.Len() {
+			if !validKey(k.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		if typeCodecs[k.Type()] != nil {
+			return true
+		}
+		for i := range k.NumField() {
+			if !validKey(k.Field(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkKey checks a key of the map type described by info.
+func checkKey(info *typeInfo, k reflect.Value) error {
+	if info.checkKeys && !validKey(k) {
+		return ErrInvalidKey
+	}
+	return nil
+}
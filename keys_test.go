@@ -0,0 +1,71 @@
+package gensenc_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type keyPoint struct {
+	X, Y int32
+}
+
+type keyHidden struct {
+	X int32
+	y int32
+}
+
+func TestMapKeys(t *testing.T) {
+	ints := map[[2]int]string{{1, 2}: "a", {-1, 0}: "b"}
+	if got := roundTrip(t, gensenc.New(), ints); !reflect.DeepEqual(got, ints) {
+		t.Errorf("got %v, want %v", got, ints)
+	}
+	points := map[keyPoint]float64{{1, 2}: 0.5, {3, 4}: math.Inf(1)}
+	if got := roundTrip(t, gensenc.New(), points); !reflect.DeepEqual(got, points) {
+		t.Errorf("got %v, want %v", got, points)
+	}
+	floats := map[float64]int{-0.5: 1, math.Inf(-1): 2}
+	if got := roundTrip(t, gensenc.New(), floats); !reflect.DeepEqual(got, floats) {
+		t.Errorf("got %v, want %v", got, floats)
+	}
+}
+
+func TestMapKeysInvalid(t *testing.T) {
+	x := 1
+	for _, v := range []any{
+		map[float64]int{1: 1, math.NaN(): 2},
+		map[[2]float32]int{{0, float32(math.NaN())}: 1},
+		map[complex128]int{complex(1, math.NaN()): 1},
+		map[any]int{math.NaN(): 1},
+		map[*int]int{&x: 1},
+		map[keyHidden]int{{X: 1}: 1},
+		// Nesting the maps fails the same.
+		[]map[*int]int{nil},
+	} {
+		_, err := gensenc.Encode(v)
+		if !errors.Is(err, gensenc.ErrInvalidKey) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidKey", v, err)
+		}
+	}
+
+	// NaN keys from the wire and keys of invalid types fail to decode.
+	b, err := gensenc.Encode(map[uint64]int{math.Float64bits(math.NaN()): 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var floats map[float64]int
+	if err := gensenc.Decode(b, &floats); !errors.Is(err, gensenc.ErrInvalidKey) {
+		t.Errorf("decoding a NaN key gave %v, want ErrInvalidKey", err)
+	}
+	b, err = gensenc.Encode(map[int]int{1: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pointers map[*int]int
+	if err := gensenc.Decode(b, &pointers); !errors.Is(err, gensenc.ErrInvalidKey) {
+		t.Errorf("decoding pointer keys gave %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,69 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestWithMaxDecodedBytes(t *testing.T) {
+	v := []string{strings.Repeat("a", 100), strings.Repeat("b", 100), strings.Repeat("c", 100)}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The slice takes its elements' headers and the strings their bytes.
+	size := 3*int(reflect.TypeFor[string]().Size()) + 300
+	var got []string
+	if err := gensenc.New(gensenc.WithMaxDecodedBytes(size)).Decode(b, &got); err != nil {
+		t.Fatalf("decoding %d bytes within a budget of as many failed: %v", size, err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %v, want %v", got, v)
+	}
+	err = gensenc.New(gensenc.WithMaxDecodedBytes(size-1)).Decode(b, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Fatalf("decoding past the budget gave %v, want ErrLimitExceeded", err)
+	}
+	if de.Path != "[2]" {
+		t.Errorf("failed at %q, want the last string", de.Path)
+	}
+
+	// Maps count their keys and elements.
+	m := map[int64]int64{1: 1, 2: 2}
+	b, err = gensenc.Encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mg map[int64]int64
+	if err := gensenc.New(gensenc.WithMaxDecodedBytes(31)).Decode(b, &mg); !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decoding a 32-byte map within 31 gave %v, want ErrLimitExceeded", err)
+	}
+	if err := gensenc.New(gensenc.WithMaxDecodedBytes(32)).Decode(b, &mg); err != nil {
+		t.Errorf("decoding a 32-byte map within 32 failed: %v", err)
+	}
+}
+
+// TestWithMaxDecodedBytesPerValue checks that the budget applies to each
+// value of a stream rather than to all of them.
+func TestWithMaxDecodedBytesPerValue(t *testing.T) {
+	var buf bytes.Buffer
+	enc := gensenc.NewEncoder(&buf)
+	for range 3 {
+		if err := enc.Encode([]int64{1, 2, 3, 4}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := gensenc.New(gensenc.WithMaxDecodedBytes(32)).NewDecoder(&buf)
+	for i := range 3 {
+		var got []int64
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("value %d: %v", i, err)
+		}
+	}
+}
@@ -2,203 +2,1043 @@ package gensenc
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"reflect"
 )
 
-var ErrCantSet error = errors.New("cannot set")
+var ErrInvalidStringRef error = errors.New("invalid string reference")
 
-func EncodeValue(v reflect.Value) ([]byte, error) {
-	buf := bytes.NewBuffer(nil)
-	l := make([]byte, 8)
+// growStep bounds the memory allocated ahead of input that has not been
+// read yet.
+const growStep = 64 << 10
+
+type encodeState struct {
+	buf *bytes.Buffer
+	l   [8]byte
+
+	// strings maps already written strings to their reference when string
+	// interning is enabled.
+	strings map[string]uint64
+
+	columnar bool
+
+	options
+
+	// stats, if not nil, accumulates the bytes written per field path.
+	stats map[string]int64
+	tracker
+	depth int
+
+	ctx   context.Context
+	ticks int
+
+	// w, if not nil, takes what has been encoded so far whenever the buffer
+	// grows past streamThreshold and nothing that is still to be patched is
+	// in it, as counted by pinned.
+	w      io.Writer
+	pinned int
+}
+
+// checkInterval is the number of container elements processed between
+// checks for cancellation of the context.
+const checkInterval = 1024
+
+func (e *encodeState) tick() error {
+	if e.w != nil && e.pinned == 0 && e.buf.Len() >= streamThreshold {
+		err := e.flush()
+		if err != nil {
+			return err
+		}
+	}
+	if e.ctx == nil {
+		return nil
+	}
+	e.ticks++
+	if e.ticks%checkInterval != 0 {
+		return nil
+	}
+	return e.ctx.Err()
+}
+
+func (e *encodeState) writeUint64(x uint64) {
+	e.order().PutUint64(e.l[:], x)
+	e.buf.Write(e.l[:])
+}
+
+// writeInteger writes an integer value, given as its bits and whether it
+// is signed.
+func (e *encodeState) writeInteger(x uint64, signed bool) {
+	if !e.varint {
+		e.writeUint64(x)
+	} else if signed {
+		e.writeUvarint(x<<1 ^ uint64(int64(x)>>63))
+	} else {
+		e.writeUvarint(x)
+	}
+}
+
+func (e *encodeState) writeUvarint(x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	e.buf.Write(b[:n])
+}
+
+func (e *encodeState) writeString(s string) {
+	if e.strings != nil {
+		ref, ok := e.strings[s]
+		if ok {
+			e.writeUint64(ref)
+			return
+		}
+		e.strings[s] = uint64(len(e.strings) + 1)
+		e.writeUint64(0)
+	}
+	e.writeUint64(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *encodeState) encodeField(f *fieldInfo, v reflect.Value) error {
+	if f.codec != nil {
+		return f.codec.encode(e, v)
+	}
+	return e.encode(v)
+}
+
+// encodeFields encodes the given fields of the struct v in order.
+func (e *encodeState) encodeFields(fields []fieldInfo, v reflect.Value) error {
+	for _, f := range fields {
+		var err error
+		if e.tracking() {
+			err = e.encodeTracked(&f, v.Field(f.index))
+		} else {
+			err = e.encodeField(&f, v.Field(f.index))
+		}
+		if err != nil {
+			return e.at(err, "."+f.name)
+		}
+	}
+	return nil
+}
+
+func (e *encodeState) encodeValue(v reflect.Value) error {
 	switch v.Type().Kind() {
 	case reflect.String:
-		val := v.String()
-		binary.LittleEndian.PutUint64(l, uint64(len([]byte(val))))
-		buf.Write(l)
-		buf.Write([]byte(val))
+		e.writeString(v.String())
 	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			if !v.Field(i).CanInterface() {
-				continue
-			}
-			b, err := EncodeValue(v.Field(i))
-			if err != nil {
-				return nil, err
-			}
-			buf.Write(b)
+		info := infoOf(v.Type())
+		if e.named && namedStruct(info) {
+			return e.encodeNamed(info.fields, v)
 		}
+		if info.codec != nil {
+			return info.codec.encode(e, v)
+		}
+		if e.raw(info) && v.CanAddr() {
+			e.buf.Write(rawBytes(v))
+			return nil
+		}
+		return e.encodeFields(info.fields, v)
 	case reflect.Slice:
-		binary.LittleEndian.PutUint64(l, uint64(v.Len()))
-		buf.Write(l)
+		if c := infoOf(v.Type()).codec; c != nil {
+			return c.encode(e, v)
+		}
+		e.writeUint64(uint64(v.Len()))
+		if e.columnar && columnar(v.Type().Elem()) {
+			return e.encodeColumns(v)
+		}
+		if e.raw(infoOf(v.Type().Elem())) {
+			e.buf.Write(rawSliceBytes(v))
+			return nil
+		}
+		if e.tracking() {
+			defer e.enter("[]")()
+		}
+		if intElems(v.Type()) {
+			return e.encodeInts(v)
+		}
 		for i := 0; i < v.Len(); i++ {
-			b, err := EncodeValue(v.Index(i))
+			err := e.tick()
+			if err != nil {
+				return err
+			}
+			err = e.encode(v.Index(i))
 			if err != nil {
-				return nil, err
+				return e.at(err, index(i))
 			}
-			buf.Write(b)
 		}
 	case reflect.Array:
+		if c := infoOf(v.Type()).codec; c != nil {
+			return c.encode(e, v)
+		}
+		if e.raw(infoOf(v.Type())) {
+			e.buf.Write(rawBytes(addressable(v)))
+			return nil
+		}
+		if e.arrayLens {
+			e.writeUint64(uint64(v.Len()))
+		}
+		if e.tracking() {
+			defer e.enter("[]")()
+		}
+		if intElems(v.Type()) {
+			return e.encodeInts(v)
+		}
 		for i := range v.Len() {
-			b, err := EncodeValue(v.Index(i))
+			err := e.tick()
 			if err != nil {
-				return nil, err
+				return err
+			}
+			err = e.encode(v.Index(i))
+			if err != nil {
+				return e.at(err, index(i))
 			}
-			buf.Write(b)
 		}
 	case reflect.Map:
-		binary.LittleEndian.PutUint64(l, uint64(v.Len()))
-		buf.Write(l)
-		for _, key := range v.MapKeys() {
-			b, err := EncodeValue(key)
+		info := infoOf(v.Type())
+		if info.invalidKeys {
+			return ErrInvalidKey
+		}
+		e.writeUint64(uint64(v.Len()))
+		keys := v.MapKeys()
+		if e.canonical {
+			err := e.sortKeys(keys)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			buf.Write(b)
-			b, err = EncodeValue(v.MapIndex(key))
+		}
+		if e.tracking() {
+			defer e.enter("[]")()
+		}
+		for _, key := range keys {
+			err := e.tick()
 			if err != nil {
-				return nil, err
+				return err
+			}
+			err = checkKey(info, key)
+			if err == nil {
+				err = e.encode(key)
+			}
+			if err == nil {
+				err = e.encode(v.MapIndex(key))
+			}
+			if err != nil {
+				return e.at(err, "["+fmt.Sprint(key)+"]")
 			}
-			buf.Write(b)
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		binary.LittleEndian.PutUint64(l, uint64(v.Int()))
-		buf.Write(l)
+		e.writeInteger(uint64(v.Int()), true)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		binary.LittleEndian.PutUint64(l, v.Uint())
-		buf.Write(l)
+		e.writeInteger(v.Uint(), false)
+	case reflect.Pointer:
+		if v.IsNil() {
+			e.buf.WriteByte(0)
+			return nil
+		}
+		e.buf.WriteByte(1)
+		return e.encode(v.Elem())
+	case reflect.Interface:
+		return e.encodeInterface(v)
+	case reflect.Chan, reflect.Func:
+		return ErrUnsupportedKind
+	case reflect.Uintptr, reflect.UnsafePointer:
+		return ErrAddress
 	default:
 		if v.CanInterface() {
-			err := binary.Write(buf, binary.LittleEndian, v.Interface())
+			err := binary.Write(e.buf, e.order(), v.Interface())
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
-	return buf.Bytes(), nil
+	return nil
 }
 
-func DecodeValue(r io.Reader, v reflect.Value) error {
-	l := make([]byte, 8)
+// encodeRoot encodes a top-level value. Pointers nested in other values
+// are preceded by a presence byte, one if the pointer is set and zero if it
+// is nil, but top-level pointers are followed, nil ones encoding the zero
+// value, so that a value and a pointer to it encode alike.
+func (e *encodeState) encodeRoot(v reflect.Value) error {
+	if !v.IsValid() {
+		return ErrNilPointer
+	}
+	e.depth = 0
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v = reflect.New(v.Type().Elem()).Elem()
+		} else {
+			v = v.Elem()
+		}
+	}
+	var err error
+	if e.observer != nil {
+		err = e.encodeObserved(v)
+	} else {
+		err = e.encode(v)
+	}
+	return joinPath(err)
+}
+
+// encodeElem encodes v as an element of a slice written piecemeal, such as
+// those of EncodeSeq and ChunkWriter. Unlike top-level values, pointers are
+// preceded by a presence byte, so that nil elements decode as nil.
+func (e *encodeState) encodeElem(v reflect.Value) error {
+	if !v.IsValid() {
+		return ErrNilPointer
+	}
+	e.depth = 0
+	return joinPath(e.encode(v))
+}
+
+func EncodeValue(v reflect.Value) ([]byte, error) {
+	return defaultCodec.EncodeValue(v)
+}
+
+// EncodeValueTo writes the encoding of v to w with a single Write and
+// returns the number of bytes written. Nothing is written if encoding
+// fails.
+func EncodeValueTo(w io.Writer, v reflect.Value) (int, error) {
+	return defaultCodec.EncodeValueTo(w, v)
+}
+
+type decodeState struct {
+	// The input is read from r or, if r is nil, taken from b starting at
+	// off.
+	r   io.Reader
+	b   []byte
+	off int
+	l   [8]byte
+
+	// n counts the bytes read from r. Values decoded from memory that was
+	// read from r in one piece start at base.
+	n    int64
+	base int64
+
+	// alias makes strings decoded from b share its memory.
+	alias bool
+	// region buffers fixed-size values read from r in one piece.
+	region []byte
+	// scratch holds the buffers allocated while decoding, for scrub.
+	scratch [][]byte
+
+	// strings holds the strings read so far in reference order when string
+	// interning is enabled.
+	strings []string
+
+	columnar bool
+
+	options
+	tracker
+	depth int
+
+	ctx   context.Context
+	ticks int
+
+	// decoded is the number of bytes charged to the current value.
+	decoded int
+}
+
+func (d *decodeState) tick() error {
+	if d.ctx == nil {
+		return nil
+	}
+	d.ticks++
+	if d.ticks%checkInterval != 0 {
+		return nil
+	}
+	return d.ctx.Err()
+}
+
+// take returns the next n bytes of input. The result aliases the input
+// when decoding from a byte slice and is only valid until the next read
+// otherwise.
+func (d *decodeState) take(n uint64) ([]byte, error) {
+	if d.r != nil {
+		if n <= growStep {
+			b := d.l[:]
+			if n > uint64(len(d.l)) {
+				b = make([]byte, n)
+				d.keep(b)
+			}
+			err := d.read(b[:n])
+			return b[:n], err
+		}
+		buf := bytes.NewBuffer(nil)
+		m, err := io.CopyN(buf, d.r, int64(n))
+		d.n += m
+		d.keep(buf.Bytes())
+		if uint64(m) < n {
+			if err == io.EOF && m > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	rest := uint64(len(d.b) - d.off)
+	if n > rest {
+		d.off = len(d.b)
+		if rest == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.b[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// consumed returns the number of bytes of input decoded so far.
+func (d *decodeState) consumed() int64 {
+	if d.r != nil {
+		return d.n
+	}
+	return int64(d.off)
+}
+
+// read fills p with the next len(p) bytes of input.
+func (d *decodeState) read(p []byte) error {
+	if d.r != nil {
+		m, err := io.ReadFull(d.r, p)
+		d.n += int64(m)
+		return err
+	}
+	b, err := d.take(uint64(len(p)))
+	if err != nil {
+		return err
+	}
+	copy(p, b)
+	return nil
+}
+
+// offset returns the number of input bytes consumed so far.
+func (d *decodeState) offset() int64 {
+	if d.r != nil {
+		return d.n
+	}
+	return d.base + int64(d.off)
+}
+
+// checkLength rejects a length prefix announcing n values of at least size
+// bytes each when decoding from a byte slice too short to hold them, so
+// corrupt input fails before anything is allocated for it.
+func (d *decodeState) checkLength(n uint64, size int) error {
+	if n > math.MaxInt {
+		return ErrInvalidLength
+	}
+	if d.maxLength > 0 && n > uint64(d.maxLength) {
+		return ErrLimitExceeded
+	}
+	if d.r != nil || size == 0 {
+		return nil
+	}
+	if n > uint64(len(d.b)-d.off)/uint64(size) {
+		return ErrInvalidLength
+	}
+	return nil
+}
+
+// charge accounts n values of size bytes about to be decoded against the
+// budget set by WithMaxDecodedBytes.
+func (d *decodeState) charge(n uint64, size uintptr) error {
+	if d.maxDecoded <= 0 {
+		return nil
+	}
+	if size > 0 && n > uint64(d.maxDecoded-d.decoded)/uint64(size) {
+		return ErrLimitExceeded
+	}
+	d.decoded += int(n * uint64(size))
+	return nil
+}
+
+// readPresence reads the byte preceding values that may be absent.
+func (d *decodeState) readPresence() (bool, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return false, err
+	}
+	switch b[0] {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, ErrMalformed
+}
+
+func (d *decodeState) readUint64() (uint64, error) {
+	b, err := d.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return d.order().Uint64(b), nil
+}
+
+// readInteger reads an integer value written by writeInteger.
+func (d *decodeState) readInteger(signed bool) (uint64, error) {
+	if !d.varint {
+		return d.readUint64()
+	}
+	x, err := d.readUvarint()
+	if signed {
+		x = x>>1 ^ -(x & 1)
+	}
+	return x, err
+}
+
+// readUvarint reads an unsigned varint as written by writeUvarint.
+func (d *decodeState) readUvarint() (uint64, error) {
+	var x uint64
+	for i := 0; ; i++ {
+		b, err := d.take(1)
+		if err != nil {
+			return 0, err
+		}
+		if i == binary.MaxVarintLen64-1 && b[0] > 1 {
+			return 0, ErrMalformed
+		}
+		x |= uint64(b[0]&0x7f) << (7 * i)
+		if b[0] < 0x80 {
+			return x, nil
+		}
+	}
+}
+
+// setInteger stores the integer x, as read from the wire, in v, which must
+// be of an integer kind.
+func (d *decodeState) setInteger(v reflect.Value, x uint64) error {
+	if v.CanInt() {
+		if !d.truncate && v.OverflowInt(int64(x)) {
+			return ErrOverflow
+		}
+		v.SetInt(int64(x))
+		return nil
+	}
+	if !d.truncate && v.OverflowUint(x) {
+		return ErrOverflow
+	}
+	v.SetUint(x)
+	return nil
+}
+
+func (d *decodeState) readString() (string, error) {
+	if d.intern {
+		ref, err := d.readUint64()
+		if err != nil {
+			return "", err
+		}
+		if ref != 0 {
+			if ref > uint64(len(d.strings)) {
+				return "", ErrInvalidStringRef
+			}
+			return d.strings[ref-1], nil
+		}
+	}
+	length, err := d.readUint64()
+	if err != nil {
+		return "", err
+	}
+	err = d.checkLength(length, 1)
+	if err == nil {
+		err = d.charge(length, 1)
+	}
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if d.allocator != nil {
+		b := d.allocator.Alloc(int(length))
+		err = d.read(b)
+		if err != nil {
+			return "", err
+		}
+		s = unsafeString(b)
+	} else {
+		b, err := d.take(length)
+		if err != nil {
+			return "", err
+		}
+		if d.alias && d.r == nil {
+			s = unsafeString(b)
+		} else {
+			s = string(b)
+		}
+	}
+	if d.intern {
+		d.strings = append(d.strings, s)
+	}
+	return s, nil
+}
+
+func (d *decodeState) decodeField(f *fieldInfo, v reflect.Value) error {
+	if f.codec != nil {
+		return f.codec.decode(d, v)
+	}
+	return d.decode(v)
+}
+
+func (d *decodeState) decodeFields(fields []fieldInfo, v reflect.Value) error {
+	for i := range fields {
+		err := d.decodeStructField(&fields[i], v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeStructField decodes the field f of the struct v.
+func (d *decodeState) decodeStructField(f *fieldInfo, v reflect.Value) error {
+	var err error
+	if d.observer != nil {
+		err = d.decodeTracked(f, v.Field(f.index))
+	} else {
+		err = d.decodeField(f, v.Field(f.index))
+	}
+	if err != nil {
+		return d.at(err, "."+f.name)
+	}
+	return nil
+}
+
+func (d *decodeState) decodeValue(v reflect.Value) error {
 	switch v.Type().Kind() {
 	case reflect.String:
 		if !v.CanSet() {
 			return ErrCantSet
 		}
-		_, err := io.ReadFull(r, l)
+		s, err := d.readString()
 		if err != nil {
 			return err
 		}
-		length := binary.LittleEndian.Uint64(l)
-		b := make([]byte, length)
-		_, err = io.ReadFull(r, b)
+		v.SetString(s)
+	case reflect.Struct:
+		info := infoOf(v.Type())
+		if d.named && namedStruct(info) {
+			return d.decodeNamed(info.fields, v)
+		}
+		if info.codec != nil {
+			return info.codec.decode(d, v)
+		}
+		if d.raw(info) && v.CanSet() {
+			return d.read(rawBytes(v))
+		}
+		if size := d.size(info); d.r != nil && size > 8 {
+			return d.decodeRegion(v, size)
+		}
+		return d.decodeFields(info.fields, v)
+	case reflect.Slice:
+		if c := infoOf(v.Type()).codec; c != nil {
+			return c.decode(d, v)
+		}
+		length, err := d.readUint64()
 		if err != nil {
 			return err
 		}
-		v.SetString(string(b))
-	case reflect.Struct:
-		for i := 0; i < v.NumField(); i++ {
-			if !v.Field(i).CanInterface() {
-				continue
-			}
-			err := DecodeValue(r, v.Field(i))
-			if err != nil {
-				return err
-			}
+		err = d.checkLength(length, d.minSize(v.Type().Elem()))
+		if err == nil {
+			err = d.charge(length, v.Type().Elem().Size())
 		}
-	case reflect.Slice:
-		_, err := io.ReadFull(r, l)
 		if err != nil {
 			return err
 		}
-		length := binary.LittleEndian.Uint64(l)
+		if !v.CanSet() {
+			return ErrCantSet
+		}
+		n := int(length)
+		elem := v.Type().Elem()
 		v.Clear()
-		v.Grow(int(length))
-		v.SetLen(int(length))
-		for i := 0; i < int(length); i++ {
-			err = DecodeValue(r, v.Index(i))
-			if err != nil {
-				return err
+		if d.allocator != nil && v.Cap() < n {
+			v.Set(d.allocator.MakeSlice(v.Type(), n))
+		}
+		v.SetLen(0)
+		if d.columnar && columnar(elem) {
+			v.Grow(n)
+			v.SetLen(n)
+			return d.decodeColumns(v)
+		}
+		if d.size(infoOf(elem)) == 0 {
+			v.Grow(n)
+			v.SetLen(n)
+			return nil
+		}
+		// A stream can't vouch for the length up front, so the slice grows
+		// in bounded steps as its elements arrive.
+		step := n
+		if d.r != nil && v.Cap() < n {
+			step = max(1, growStep/max(1, int(elem.Size())))
+		}
+		raw, ints := d.raw(infoOf(elem)), intElems(v.Type())
+		if d.observer != nil {
+			defer d.enter("[]")()
+		}
+		for i := 0; i < n; i += step {
+			m := min(step, n-i)
+			v.Grow(m)
+			v.SetLen(i + m)
+			part := v.Slice(i, i+m)
+			if raw {
+				err = d.read(rawSliceBytes(part))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if ints {
+				err = d.decodeInts(part, i)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			for j := range m {
+				err = d.tick()
+				if err != nil {
+					return err
+				}
+				err = d.decode(part.Index(j))
+				if err != nil {
+					return d.at(err, index(i+j))
+				}
 			}
 		}
 	case reflect.Array:
+		info := infoOf(v.Type())
+		if info.codec != nil {
+			return info.codec.decode(d, v)
+		}
+		if d.raw(info) && v.CanSet() {
+			return d.read(rawBytes(v))
+		}
+		if size := d.size(info); d.r != nil && size > 8 {
+			return d.decodeRegion(v, size)
+		}
+		if d.observer != nil {
+			defer d.enter("[]")()
+		}
+		if d.arrayLens {
+			return d.decodeArrayLen(v)
+		}
+		if intElems(v.Type()) {
+			return d.decodeInts(v, 0)
+		}
 		for i := range v.Len() {
-			err := DecodeValue(r, v.Index(i))
+			err := d.tick()
 			if err != nil {
 				return err
 			}
+			err = d.decode(v.Index(i))
+			if err != nil {
+				return d.at(err, index(i))
+			}
 		}
 	case reflect.Map:
-		_, err := io.ReadFull(r, l)
+		info := infoOf(v.Type())
+		if info.invalidKeys {
+			return ErrInvalidKey
+		}
+		length, err := d.readUint64()
 		if err != nil {
 			return err
 		}
-		length := binary.LittleEndian.Uint64(l)
+		size := d.minSize(v.Type().Key()) + d.minSize(v.Type().Elem())
+		err = d.checkLength(length, size)
+		if err == nil {
+			err = d.charge(length, v.Type().Key().Size()+v.Type().Elem().Size())
+		}
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			// All entries are equal.
+			length = min(length, 1)
+		}
 		if v.IsNil() {
+			if !v.CanSet() {
+				return ErrCantSet
+			}
 			v.Set(reflect.MakeMap(v.Type()))
+		} else if !d.mergeMaps {
+			v.Clear()
 		}
-		v.Clear()
-		for range length {
-			key := reflect.New(v.Type().Key())
-			err = DecodeValue(r, key)
+		if d.observer != nil {
+			defer d.enter("[]")()
+		}
+		for i := range length {
+			err = d.tick()
 			if err != nil {
 				return err
 			}
-			value := reflect.New(v.Type().Elem())
-			err = DecodeValue(r, value)
+			key := reflect.New(v.Type().Key()).Elem()
+			err = d.decode(key)
+			if err == nil {
+				err = checkKey(info, key)
+			}
 			if err != nil {
-				return err
+				// The key is unknown, so refer to the entry by position.
+				return d.at(err, index(int(i)))
 			}
-			v.SetMapIndex(key.Elem(), value.Elem())
+			value := reflect.New(v.Type().Elem()).Elem()
+			if d.mergeMaps {
+				if old := v.MapIndex(key); old.IsValid() {
+					value.Set(old)
+				}
+			}
+			err = d.decode(value)
+			if err != nil {
+				return d.at(err, "["+fmt.Sprint(key)+"]")
+			}
+			v.SetMapIndex(key, value)
 		}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if !v.CanSet() {
 			return ErrCantSet
 		}
-		_, err := io.ReadFull(r, l)
+		x, err := d.readInteger(v.CanInt())
 		if err != nil {
 			return err
 		}
-		v.SetInt(int64(binary.LittleEndian.Uint64(l)))
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return d.setInteger(v, x)
+	case reflect.Pointer:
+		present, err := d.readPresence()
+		if err != nil {
+			return err
+		}
+		if !present {
+			if !v.CanSet() {
+				return ErrCantSet
+			}
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			if !v.CanSet() {
+				return ErrNilPointer
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		return d.decodeInterface(v)
+	default:
+		switch v.Kind() {
+		case reflect.Bool, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		case reflect.Uintptr, reflect.UnsafePointer:
+			return ErrAddress
+		default:
+			return ErrUnsupportedKind
+		}
 		if !v.CanSet() {
 			return ErrCantSet
 		}
-		_, err := io.ReadFull(r, l)
+		b, err := d.take(uint64(v.Type().Size()))
 		if err != nil {
 			return err
 		}
-		v.SetUint(binary.LittleEndian.Uint64(l))
-	case reflect.Pointer:
-		err := DecodeValue(r, v.Elem())
+		if d.strict && v.Kind() == reflect.Bool && b[0] > 1 {
+			return ErrMalformed
+		}
+		_, err = binary.Decode(b, d.order(), v.Addr().Interface())
 		if err != nil {
 			return err
 		}
-	default:
-		if !v.CanSet() {
-			return ErrCantSet
+	}
+	return nil
+}
+
+// decodeRoot decodes a top-level value, following pointers like
+// encodeRoot and allocating nil ones.
+func (d *decodeState) decodeRoot(v reflect.Value) error {
+	d.decoded, d.depth = 0, 0
+	if d.secrets {
+		defer d.scrub()
+	}
+	return d.guard(func() error {
+		if !v.IsValid() {
+			return ErrNilPointer
 		}
-		if v.CanInterface() {
-			inter := v.Interface()
-			err := binary.Read(r, binary.LittleEndian, inter)
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return ErrNilPointer
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if d.observer != nil {
+			return d.decodeObserved(v)
+		}
+		return d.decode(v)
+	})
+}
+
+// decodeElem decodes an element written by encodeElem into v.
+func (d *decodeState) decodeElem(v reflect.Value) error {
+	d.decoded, d.depth = 0, 0
+	if d.secrets {
+		defer d.scrub()
+	}
+	return d.guard(func() error {
+		return d.decode(v)
+	})
+}
+
+// decodeElems sets the slice v to n elements, decoding the i-th with f. Like
+// the default slice decoding, it grows v in bounded steps when decoding
+// from a stream.
+func (d *decodeState) decodeElems(v reflect.Value, n int, f func(i int, elem reflect.Value) error) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	err := d.charge(uint64(n), v.Type().Elem().Size())
+	if err != nil {
+		return err
+	}
+	v.Clear()
+	if d.allocator != nil && v.Cap() < n {
+		v.Set(d.allocator.MakeSlice(v.Type(), n))
+	}
+	v.SetLen(0)
+	step := n
+	if d.r != nil && v.Cap() < n {
+		step = max(1, growStep/max(1, int(v.Type().Elem().Size())))
+	}
+	for i := 0; i < n; i += step {
+		m := min(step, n-i)
+		v.Grow(m)
+		v.SetLen(i + m)
+		for j := i; j < i+m; j++ {
+			err := d.tick()
 			if err != nil {
 				return err
 			}
-			v.Set(reflect.ValueOf(inter))
+			err = f(j, v.Index(j))
+			if err != nil {
+				return d.at(err, index(j))
+			}
 		}
 	}
 	return nil
 }
 
+// decodeRegion reads the n bytes of a fixed-size value with a single read
+// and decodes it from memory, instead of issuing a read per field.
+func (d *decodeState) decodeRegion(v reflect.Value, n int) error {
+	if cap(d.region) < n {
+		if d.secrets {
+			clear(d.region)
+		}
+		d.region = make([]byte, n)
+	}
+	b := d.region[:n]
+	err := d.read(b)
+	if err != nil {
+		return err
+	}
+	r := d.r
+	d.r, d.b, d.off, d.base = nil, b, 0, d.n-int64(n)
+	err = d.decode(v)
+	d.r, d.b, d.off, d.base = r, nil, 0, 0
+	return err
+}
+
+// DecodeValue decodes one value from r into v. It never reads past the end
+// of the value, which takes a read per integer, string and variable-length
+// region; only values of a fixed wire size are read at once. Wrap
+// unbuffered readers such as network connections or files in a
+// bufio.Reader, or use a Decoder, to avoid a system call per field.
+func DecodeValue(r io.Reader, v reflect.Value) error {
+	return defaultCodec.DecodeValue(r, v)
+}
+
+// DecodeValueN is like DecodeValue but also returns the number of bytes
+// read from r, which is the size of the value unless decoding failed, so
+// that framing following the value can be parsed right after it.
+func DecodeValueN(r io.Reader, v reflect.Value) (int64, error) {
+	return defaultCodec.DecodeValueN(r, v)
+}
+
+func addressable(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// Encode returns the encoding of a, which may be a value of any supported
+// type, including slices, maps, arrays and primitive types, or a pointer to
+// one. Pointers are followed, so a value and a pointer to it encode alike,
+// and a nil pointer encodes the zero value of its element type. Encoding
+// an untyped nil fails with ErrNilPointer. Structs are encoded as their
+// exported fields but those tagged `gensenc:"-"`. Channels and functions
+// can't be encoded; within a value they fail with an *EncodeError wrapping
+// ErrUnsupportedKind that locates them. Neither can uintptr and
+// unsafe.Pointer values, failing with ErrAddress, so fields holding them
+// need the "-" tag.
 func Encode(a any) ([]byte, error) {
-	v := reflect.ValueOf(a)
-	return EncodeValue(v)
+	return defaultCodec.Encode(a)
 }
 
+// Decode decodes b into the value a points to, which may be of any type
+// Encode accepts. Nil pointers and maps on the way are allocated, so a
+// pointer to a nil slice, map or pointer can be passed; values encoded by
+// way of a pointer decode into the pointed-to type directly as well. A nil
+// a fails with ErrNilPointer and an a that is not a pointer with
+// ErrCantSet, maps excepted, which are decoded into after being cleared.
 func Decode(b []byte, a any) error {
-	v := reflect.ValueOf(a)
-	r := bytes.NewReader(b)
-	return DecodeValue(r, v)
+	return defaultCodec.Decode(b, a)
+}
+
+// DecodeAt decodes the value starting at b[off] into a and returns the
+// offset just after it, so records stored back to back, for example in a
+// memory-mapped file, can be read without touching the preceding data.
+func DecodeAt(b []byte, off int, a any) (int, error) {
+	if off < 0 || off > len(b) {
+		return off, io.ErrUnexpectedEOF
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	d.off = off
+	err := defaultCodec.decode(d, reflect.ValueOf(a))
+	return d.off, err
+}
+
+// DecodeAlias is like Decode but decoded strings share memory with b
+// instead of being copied. b must not be modified as long as any string
+// decoded from it is in use.
+func DecodeAlias(b []byte, a any) error {
+	d := defaultCodec.newDecodeState(b, nil)
+	d.alias = true
+	return defaultCodec.decode(d, reflect.ValueOf(a))
+}
+
+// EncodeInterned is like Encode but writes every distinct string only once;
+// repeated strings are replaced by a reference to their first occurrence.
+// The result must be decoded with DecodeInterned.
+func EncodeInterned(a any) ([]byte, error) {
+	e := defaultCodec.newEncodeState()
+	e.strings = map[string]uint64{}
+	err := defaultCodec.encode(e, addressable(reflect.ValueOf(a)))
+	if err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+func DecodeInterned(b []byte, a any) error {
+	d := defaultCodec.newDecodeState(b, nil)
+	d.intern = true
+	return defaultCodec.decode(d, reflect.ValueOf(a))
 }
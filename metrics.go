@@ -0,0 +1,143 @@
+package gensenc
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// WithMetrics makes the Codec count the values it encodes and decodes,
+// reported by Stats. Values encoded and decoded by its Encoders and
+// Decoders are not counted.
+func WithMetrics() Option {
+	return func(o *options) { o.metrics = true }
+}
+
+// CodecStats is a snapshot of the counters of a Codec. It marshals to JSON
+// as is, so it can be published with expvar:
+//
+//	expvar.Publish("gensenc", expvar.Func(func() any { return codec.Stats() }))
+type CodecStats struct {
+	// Encoded and Decoded count the values encoded and decoded
+	// successfully, and EncodedBytes and DecodedBytes their size.
+	Encoded      int64
+	Decoded      int64
+	EncodedBytes int64
+	DecodedBytes int64
+	// Errors counts the values that failed to encode or decode by the
+	// message of the underlying error, such as "malformed input", without
+	// the path and offset of EncodeError and DecodeError.
+	Errors map[string]int64
+	// PlanHits and PlanMisses count the values whose type had been
+	// analyzed before, by this or any other Codec or by Precompile, and
+	// those whose type had to be analyzed first.
+	PlanHits   int64
+	PlanMisses int64
+}
+
+// PlanHitRate returns the share of values whose type had been analyzed
+// before, or 0 if there were none.
+func (s CodecStats) PlanHitRate() float64 {
+	n := s.PlanHits + s.PlanMisses
+	if n == 0 {
+		return 0
+	}
+	return float64(s.PlanHits) / float64(n)
+}
+
+// Stats returns the counters of a Codec created WithMetrics, or all zeros
+// for other Codecs.
+func (c *Codec) Stats() CodecStats {
+	m := c.metrics
+	if m == nil {
+		return CodecStats{}
+	}
+	m.mu.Lock()
+	errs := maps.Clone(m.errors)
+	m.mu.Unlock()
+	return CodecStats{
+		Encoded:      m.encoded.Load(),
+		Decoded:      m.decoded.Load(),
+		EncodedBytes: m.encodedBytes.Load(),
+		DecodedBytes: m.decodedBytes.Load(),
+		Errors:       errs,
+		PlanHits:     m.planHits.Load(),
+		PlanMisses:   m.planMisses.Load(),
+	}
+}
+
+// metrics holds the counters of a Codec. Its methods do nothing on a nil
+// metrics, as Codecs without WithMetrics have.
+type metrics struct {
+	encoded, decoded           atomic.Int64
+	encodedBytes, decodedBytes atomic.Int64
+	planHits, planMisses       atomic.Int64
+
+	mu     sync.Mutex
+	errors map[string]int64
+}
+
+// plan counts whether the type of the value v, or of the value it points
+// to, has been analyzed before.
+func (m *metrics) plan(v reflect.Value) {
+	if m == nil || !v.IsValid() {
+		return
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if _, ok := typeInfos.Load(t); ok {
+		m.planHits.Add(1)
+	} else {
+		m.planMisses.Add(1)
+	}
+}
+
+func (m *metrics) encode(n int, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	m.encoded.Add(1)
+	m.encodedBytes.Add(int64(n))
+}
+
+func (m *metrics) decode(n int64, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	m.decoded.Add(1)
+	m.decodedBytes.Add(n)
+}
+
+func (m *metrics) fail(err error) {
+	var de *DecodeError
+	var ee *EncodeError
+	switch {
+	case errors.As(err, &de):
+		err = de.Err
+	case errors.As(err, &ee):
+		err = ee.Err
+	}
+	// Panic values would make for as many counters as panics.
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		err = ErrMalformed
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.errors == nil {
+		m.errors = map[string]int64{}
+	}
+	m.errors[err.Error()]++
+}
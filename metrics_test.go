@@ -0,0 +1,77 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type metered struct {
+	A int
+	S string
+}
+
+func TestCodecStats(t *testing.T) {
+	c := gensenc.New(gensenc.WithMetrics())
+	b, err := c.Encode(metered{1, "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Encode(&metered{2, "yz"}); err != nil {
+		t.Fatal(err)
+	}
+	var v metered
+	if err := c.Decode(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DecodeValue(bytes.NewReader(b), reflect.ValueOf(&v)); err != nil {
+		t.Fatal(err)
+	}
+	c.Decode(b[:5], &v)
+	c.Encode(make(chan int))
+	// Encoders and Decoders aren't counted.
+	c.NewEncoder(io.Discard).Encode(v)
+
+	s := c.Stats()
+	want := gensenc.CodecStats{
+		Encoded:      2,
+		Decoded:      2,
+		EncodedBytes: int64(2*len(b) + 1),
+		DecodedBytes: int64(2 * len(b)),
+		Errors:       map[string]int64{gensenc.ErrTruncated.Error(): 1, gensenc.ErrUnsupportedKind.Error(): 1},
+		PlanHits:     s.PlanHits,
+		PlanMisses:   s.PlanMisses,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+	// metered is analyzed when first encoded, as chan int may be if no
+	// other test used it.
+	if n := s.PlanHits + s.PlanMisses; n != 6 || s.PlanMisses < 1 || s.PlanMisses > 2 || s.PlanHitRate() != float64(s.PlanHits)/6 {
+		t.Errorf("counted %d plan hits and %d misses at a rate of %v", s.PlanHits, s.PlanMisses, s.PlanHitRate())
+	}
+
+	j, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(j, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"Encoded", "Decoded", "EncodedBytes", "DecodedBytes", "Errors", "PlanHits", "PlanMisses"} {
+		if _, ok := fields[k]; !ok {
+			t.Errorf("JSON %s lacks %s", j, k)
+		}
+	}
+
+	plain := gensenc.New()
+	plain.Encode(v)
+	if s := plain.Stats(); !reflect.DeepEqual(s, gensenc.CodecStats{}) || s.PlanHitRate() != 0 {
+		t.Errorf("a Codec without metrics counted %+v", s)
+	}
+}
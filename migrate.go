@@ -0,0 +1,135 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var ErrNoMigration error = errors.New("no migration to destination type")
+
+type migration struct {
+	to reflect.Type
+	fn func(reflect.Value) (reflect.Value, error)
+}
+
+var migrations = struct {
+	sync.RWMutex
+	from map[reflect.Type][]migration
+	// byFingerprint maps the fingerprints of the types migrations convert
+	// between to the types.
+	byFingerprint map[uint64]reflect.Type
+}{from: map[reflect.Type][]migration{}, byFingerprint: map[uint64]reflect.Type{}}
+
+// RegisterMigration registers fn as the conversion of values of type From
+// to type To, which DecodeAny applies to values encoded as From, possibly
+// chained with other migrations. Registering a migration between the same
+// types again replaces it.
+func RegisterMigration[From, To any](fn func(From) (To, error)) {
+	from, to := reflect.TypeFor[From](), reflect.TypeFor[To]()
+	m := migration{to, func(v reflect.Value) (reflect.Value, error) {
+		r, err := fn(v.Interface().(From))
+		return reflect.ValueOf(&r).Elem(), err
+	}}
+	migrations.Lock()
+	defer migrations.Unlock()
+	ms := migrations.from[from]
+	for i := range ms {
+		if ms[i].to == to {
+			ms[i] = m
+			return
+		}
+	}
+	migrations.from[from] = append(ms, m)
+	migrations.byFingerprint[Fingerprint(from)] = from
+	migrations.byFingerprint[Fingerprint(to)] = to
+}
+
+// migrationPath returns the shortest chain of migrations from the type with
+// fingerprint fp to t.
+func migrationPath(fp uint64, t reflect.Type) (reflect.Type, []migration, bool) {
+	migrations.RLock()
+	defer migrations.RUnlock()
+	from, ok := migrations.byFingerprint[fp]
+	if !ok {
+		return nil, nil, false
+	}
+	paths := map[reflect.Type][]migration{from: nil}
+	queue := []reflect.Type{from}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if u == t {
+			return from, paths[u], true
+		}
+		for _, m := range migrations.from[u] {
+			if _, ok := paths[m.to]; !ok {
+				paths[m.to] = append(paths[u][:len(paths[u]):len(paths[u])], m)
+				queue = append(queue, m.to)
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// EncodeVersioned encodes a preceded by the Fingerprint of its type, for
+// decoding with DecodeAny.
+func EncodeVersioned(a any) ([]byte, error) {
+	t := reflect.TypeOf(a)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	b, err := Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint64(nil, Fingerprint(t)), b...), nil
+}
+
+// DecodeAny decodes b, written by EncodeVersioned, into the value a points
+// to. Values encoded as another type are decoded as that type and converted
+// by the shortest chain of registered migrations, failing with
+// ErrNoMigration if there is none.
+func DecodeAny(b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrNilPointer
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	var src reflect.Value
+	var path []migration
+	err := d.guard(func() error {
+		fp, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if fp == Fingerprint(v.Type()) {
+			src = v
+			return d.decode(v)
+		}
+		from, p, ok := migrationPath(fp, v.Type())
+		if !ok {
+			return ErrNoMigration
+		}
+		src, path = reflect.New(from).Elem(), p
+		return d.decode(src)
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range path {
+		src, err = m.fn(src)
+		if err != nil {
+			return err
+		}
+	}
+	v.Set(src)
+	return nil
+}
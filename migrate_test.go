@@ -0,0 +1,105 @@
+package gensenc_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type userV1 struct{ Name string }
+
+type userV2 struct{ First, Last string }
+
+type userV3 struct {
+	Full string
+	Age  int
+}
+
+type userV4 struct{ Full string }
+
+var errNoName = errors.New("no name")
+
+func init() {
+	gensenc.RegisterMigration(func(u userV1) (userV2, error) {
+		if u.Name == "" {
+			return userV2{}, errNoName
+		}
+		first, last, _ := strings.Cut(u.Name, " ")
+		return userV2{first, last}, nil
+	})
+	gensenc.RegisterMigration(func(u userV2) (userV3, error) {
+		return userV3{Full: u.First + " " + u.Last}, nil
+	})
+	gensenc.RegisterMigration(func(u userV3) (userV4, error) {
+		return userV4{u.Full}, nil
+	})
+	gensenc.RegisterMigration(func(u userV2) (userV4, error) {
+		return userV4{"shortcut " + u.First}, nil
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	b, err := gensenc.EncodeVersioned(&userV1{"ann lee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v3 userV3
+	if err := gensenc.DecodeAny(b, &v3); err != nil || v3 != (userV3{Full: "ann lee"}) {
+		t.Errorf("migrating to v3 gave %+v, %v", v3, err)
+	}
+	// The shortest chain is taken.
+	var v4 userV4
+	if err := gensenc.DecodeAny(b, &v4); err != nil || v4.Full != "shortcut ann" {
+		t.Errorf("migrating to v4 gave %+v, %v", v4, err)
+	}
+	// Values of the destination type need no migration.
+	var v1 *userV1
+	if err := gensenc.DecodeAny(b, &v1); err != nil || v1 == nil || v1.Name != "ann lee" {
+		t.Errorf("decoding as v1 gave %+v, %v", v1, err)
+	}
+	b, err = gensenc.EncodeVersioned(userV3{"x", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gensenc.DecodeAny(b, &v4); err != nil || v4.Full != "x" {
+		t.Errorf("migrating v3 to v4 gave %+v, %v", v4, err)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	b, err := gensenc.EncodeVersioned(userV3{"x", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v1 userV1
+	if err := gensenc.DecodeAny(b, &v1); !errors.Is(err, gensenc.ErrNoMigration) {
+		t.Errorf("migrating back gave %v, want ErrNoMigration", err)
+	}
+	// A payload encoded as a type no migration involves.
+	b, err = gensenc.EncodeVersioned(point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gensenc.DecodeAny(b, &v1); !errors.Is(err, gensenc.ErrNoMigration) {
+		t.Errorf("decoding an unrelated type gave %v, want ErrNoMigration", err)
+	}
+	b, err = gensenc.EncodeVersioned(userV1{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v3 userV3
+	if err := gensenc.DecodeAny(b, &v3); err != errNoName {
+		t.Errorf("a failing migration gave %v, want its error", err)
+	}
+	if err := gensenc.DecodeAny(b[:len(b)-1], &v3); !errors.Is(err, gensenc.ErrTruncated) && !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding truncated input gave %v", err)
+	}
+	if err := gensenc.DecodeAny(b[:4], &v3); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a truncated fingerprint gave %v, want ErrTruncated", err)
+	}
+	if err := gensenc.DecodeAny(b, v3); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("decoding into a non-pointer gave %v, want ErrNilPointer", err)
+	}
+}
@@ -0,0 +1,507 @@
+// Package msgpack implements the MessagePack format as a gensenc.Format,
+// for exchanging gensenc values with services written in other languages.
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var (
+	ErrTypeMismatch error = errors.New("msgpack: unexpected type")
+	ErrOverflow     error = errors.New("msgpack: integer overflow")
+)
+
+// Format is the MessagePack format.
+var Format gensenc.Format = format{}
+
+type format struct{}
+
+func (format) NewWriter(w io.Writer) gensenc.FormatWriter {
+	return &writer{w: w}
+}
+
+func (format) NewReader(r io.Reader) gensenc.FormatReader {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &reader{r: br}
+}
+
+type writer struct {
+	w io.Writer
+	b [9]byte
+}
+
+func (w *writer) write(b []byte) error {
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *writer) head(code byte, n uint64, size int) error {
+	w.b[0] = code
+	switch size {
+	case 1:
+		w.b[1] = byte(n)
+	case 2:
+		binary.BigEndian.PutUint16(w.b[1:], uint16(n))
+	case 4:
+		binary.BigEndian.PutUint32(w.b[1:], uint32(n))
+	case 8:
+		binary.BigEndian.PutUint64(w.b[1:], n)
+	}
+	return w.write(w.b[:1+size])
+}
+
+// length writes a header for a length n, using fix when the length fits
+// into the fixed format with at most max elements.
+func (w *writer) length(n int, fix byte, max int, code8, code16, code32 byte) error {
+	switch {
+	case n <= max:
+		return w.head(fix|byte(n), 0, 0)
+	case n <= math.MaxUint8 && code8 != 0:
+		return w.head(code8, uint64(n), 1)
+	case n <= math.MaxUint16:
+		return w.head(code16, uint64(n), 2)
+	}
+	return w.head(code32, uint64(n), 4)
+}
+
+func (w *writer) WriteNil() error {
+	return w.head(0xc0, 0, 0)
+}
+
+func (w *writer) WriteBool(b bool) error {
+	if b {
+		return w.head(0xc3, 0, 0)
+	}
+	return w.head(0xc2, 0, 0)
+}
+
+func (w *writer) WriteInt(i int64) error {
+	switch {
+	case i >= 0:
+		return w.WriteUint(uint64(i))
+	case i >= -32:
+		return w.head(byte(i), 0, 0)
+	case i >= math.MinInt8:
+		return w.head(0xd0, uint64(i), 1)
+	case i >= math.MinInt16:
+		return w.head(0xd1, uint64(i), 2)
+	case i >= math.MinInt32:
+		return w.head(0xd2, uint64(i), 4)
+	}
+	return w.head(0xd3, uint64(i), 8)
+}
+
+func (w *writer) WriteUint(u uint64) error {
+	switch {
+	case u < 0x80:
+		return w.head(byte(u), 0, 0)
+	case u <= math.MaxUint8:
+		return w.head(0xcc, u, 1)
+	case u <= math.MaxUint16:
+		return w.head(0xcd, u, 2)
+	case u <= math.MaxUint32:
+		return w.head(0xce, u, 4)
+	}
+	return w.head(0xcf, u, 8)
+}
+
+func (w *writer) WriteFloat32(f float32) error {
+	return w.head(0xca, uint64(math.Float32bits(f)), 4)
+}
+
+func (w *writer) WriteFloat64(f float64) error {
+	return w.head(0xcb, math.Float64bits(f), 8)
+}
+
+func (w *writer) WriteString(s string) error {
+	err := w.length(len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.w, s)
+	return err
+}
+
+func (w *writer) WriteBytes(b []byte) error {
+	var err error
+	switch {
+	case len(b) <= math.MaxUint8:
+		err = w.head(0xc4, uint64(len(b)), 1)
+	case len(b) <= math.MaxUint16:
+		err = w.head(0xc5, uint64(len(b)), 2)
+	default:
+		err = w.head(0xc6, uint64(len(b)), 4)
+	}
+	if err != nil {
+		return err
+	}
+	return w.write(b)
+}
+
+func (w *writer) WriteArrayHeader(n int) error {
+	return w.length(n, 0x90, 15, 0, 0xdc, 0xdd)
+}
+
+func (w *writer) WriteMapHeader(n int) error {
+	return w.length(n, 0x80, 15, 0, 0xde, 0xdf)
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteScanner
+}
+
+type reader struct {
+	r byteReader
+	b [8]byte
+}
+
+func (r *reader) uint(size int) (uint64, error) {
+	_, err := io.ReadFull(r.r, r.b[:size])
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	switch size {
+	case 1:
+		return uint64(r.b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(r.b[:])), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(r.b[:])), nil
+	}
+	return binary.BigEndian.Uint64(r.b[:]), nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// bytes reads n bytes without trusting n for the initial allocation.
+func (r *reader) bytes(n uint64) ([]byte, error) {
+	if n <= 64<<10 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r.r, b)
+		return b, unexpected(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	m, err := io.CopyN(buf, r.r, int64(n))
+	if uint64(m) < n {
+		return nil, unexpected(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *reader) ReadNil() (bool, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if c == 0xc0 {
+		return true, nil
+	}
+	return false, r.r.UnreadByte()
+}
+
+func (r *reader) ReadBool() (bool, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	}
+	return false, ErrTypeMismatch
+}
+
+// number reads any integer, returning it as a uint64 and whether it was
+// encoded as a signed negative value.
+func (r *reader) number(c byte) (uint64, bool, error) {
+	switch {
+	case c < 0x80:
+		return uint64(c), false, nil
+	case c >= 0xe0:
+		return uint64(int64(int8(c))), true, nil
+	}
+	switch c {
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := r.uint(1 << (c - 0xcc))
+		return u, false, err
+	case 0xd0:
+		u, err := r.uint(1)
+		return uint64(int64(int8(u))), int8(u) < 0, err
+	case 0xd1:
+		u, err := r.uint(2)
+		return uint64(int64(int16(u))), int16(u) < 0, err
+	case 0xd2:
+		u, err := r.uint(4)
+		return uint64(int64(int32(u))), int32(u) < 0, err
+	case 0xd3:
+		u, err := r.uint(8)
+		return u, int64(u) < 0, err
+	}
+	return 0, false, ErrTypeMismatch
+}
+
+func (r *reader) ReadInt() (int64, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	u, neg, err := r.number(c)
+	if err != nil {
+		return 0, err
+	}
+	if !neg && u > math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+	return int64(u), nil
+}
+
+func (r *reader) ReadUint() (uint64, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	u, neg, err := r.number(c)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return 0, ErrOverflow
+	}
+	return u, nil
+}
+
+func (r *reader) ReadFloat64() (float64, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch c {
+	case 0xca:
+		u, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := r.uint(8)
+		return math.Float64frombits(u), err
+	}
+	u, neg, err := r.number(c)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return float64(int64(u)), nil
+	}
+	return float64(u), nil
+}
+
+// rawLength reads the header of a string or binary value.
+func (r *reader) rawLength() (uint64, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c >= 0xa0 && c <= 0xbf:
+		return uint64(c & 0x1f), nil
+	case c == 0xd9 || c == 0xc4:
+		return r.uint(1)
+	case c == 0xda || c == 0xc5:
+		return r.uint(2)
+	case c == 0xdb || c == 0xc6:
+		return r.uint(4)
+	}
+	return 0, ErrTypeMismatch
+}
+
+func (r *reader) ReadString() (string, error) {
+	n, err := r.rawLength()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+func (r *reader) ReadBytes() ([]byte, error) {
+	n, err := r.rawLength()
+	if err != nil {
+		return nil, err
+	}
+	return r.bytes(n)
+}
+
+func (r *reader) header(fix byte, code16, code32 byte) (int, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == fix:
+		n = uint64(c & 0x0f)
+	case c == code16:
+		n, err = r.uint(2)
+	case c == code32:
+		n, err = r.uint(4)
+	default:
+		return 0, ErrTypeMismatch
+	}
+	return int(n), err
+}
+
+func (r *reader) ReadArrayHeader() (int, error) {
+	return r.header(0x90, 0xdc, 0xdd)
+}
+
+func (r *reader) ReadMapHeader() (int, error) {
+	return r.header(0x80, 0xde, 0xdf)
+}
+
+func (r *reader) ReadAny() (any, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	err = r.r.UnreadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c == 0xc0:
+		_, err = r.r.ReadByte()
+		return nil, err
+	case c == 0xc2 || c == 0xc3:
+		return r.ReadBool()
+	case c == 0xca || c == 0xcb:
+		return r.ReadFloat64()
+	case c >= 0xa0 && c <= 0xbf || c >= 0xd9 && c <= 0xdb:
+		return r.ReadString()
+	case c >= 0xc4 && c <= 0xc6:
+		return r.ReadBytes()
+	case c >= 0x90 && c <= 0x9f || c == 0xdc || c == 0xdd:
+		n, err := r.ReadArrayHeader()
+		return gensenc.ArrayHeader(n), err
+	case c >= 0x80 && c <= 0x8f || c == 0xde || c == 0xdf:
+		n, err := r.ReadMapHeader()
+		return gensenc.MapHeader(n), err
+	}
+	_, err = r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	u, neg, err := r.number(c)
+	if err != nil {
+		return nil, err
+	}
+	if !neg && u > math.MaxInt64 {
+		return u, nil
+	}
+	return int64(u), nil
+}
+
+func (r *reader) discard(n uint64) error {
+	m, err := io.CopyN(io.Discard, r.r, int64(n))
+	if uint64(m) < n {
+		return unexpected(err)
+	}
+	return nil
+}
+
+// Skip skips the next value. The items of arrays and maps are counted
+// rather than skipped recursively, so that deeply nested input can't
+// overflow the stack.
+func (r *reader) Skip() error {
+	for left, top := uint64(1), true; left > 0; left, top = left-1, false {
+		items, err := r.skipItem()
+		if err != nil {
+			if top {
+				return err
+			}
+			return unexpected(err)
+		}
+		left += items
+	}
+	return nil
+}
+
+// skipItem skips the next value but for the items of arrays and maps,
+// the number of which it returns.
+func (r *reader) skipItem() (uint64, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	items := uint64(0)
+	switch {
+	case c < 0x80 || c >= 0xe0 || c == 0xc0 || c == 0xc2 || c == 0xc3:
+		return 0, nil
+	case c >= 0x80 && c <= 0x8f:
+		items = 2 * uint64(c&0x0f)
+	case c >= 0x90 && c <= 0x9f:
+		items = uint64(c & 0x0f)
+	case c >= 0xa0 && c <= 0xbf:
+		return 0, r.discard(uint64(c & 0x1f))
+	case c == 0xc4 || c == 0xd9:
+		n, err = r.uint(1)
+	case c == 0xc5 || c == 0xda:
+		n, err = r.uint(2)
+	case c == 0xc6 || c == 0xdb:
+		n, err = r.uint(4)
+	case c == 0xc7:
+		n, err = r.uint(1)
+		n++
+	case c == 0xc8:
+		n, err = r.uint(2)
+		n++
+	case c == 0xc9:
+		n, err = r.uint(4)
+		n++
+	case c == 0xca:
+		n = 4
+	case c == 0xcb:
+		n = 8
+	case c >= 0xcc && c <= 0xcf:
+		n = 1 << (c - 0xcc)
+	case c >= 0xd0 && c <= 0xd3:
+		n = 1 << (c - 0xd0)
+	case c >= 0xd4 && c <= 0xd8:
+		n = 1 + 1<<(c-0xd4)
+	case c == 0xdc:
+		items, err = r.uint(2)
+	case c == 0xdd:
+		items, err = r.uint(4)
+	case c == 0xde:
+		items, err = r.uint(2)
+		items *= 2
+	case c == 0xdf:
+		items, err = r.uint(4)
+		items *= 2
+	default:
+		return 0, ErrTypeMismatch
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		return 0, r.discard(n)
+	}
+	return items, nil
+}
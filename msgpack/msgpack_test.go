@@ -0,0 +1,160 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/msgpack"
+)
+
+// TestWriter checks the writer against encodings from the MessagePack
+// specification, always taking the shortest form.
+func TestWriter(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		write func(gensenc.FormatWriter) error
+		want  string
+	}{
+		{"nil", func(w gensenc.FormatWriter) error { return w.WriteNil() }, "c0"},
+		{"false", func(w gensenc.FormatWriter) error { return w.WriteBool(false) }, "c2"},
+		{"true", func(w gensenc.FormatWriter) error { return w.WriteBool(true) }, "c3"},
+		{"positive fixint", func(w gensenc.FormatWriter) error { return w.WriteInt(127) }, "7f"},
+		{"negative fixint", func(w gensenc.FormatWriter) error { return w.WriteInt(-32) }, "e0"},
+		{"int8", func(w gensenc.FormatWriter) error { return w.WriteInt(-33) }, "d0df"},
+		{"int16", func(w gensenc.FormatWriter) error { return w.WriteInt(-129) }, "d1ff7f"},
+		{"int32", func(w gensenc.FormatWriter) error { return w.WriteInt(math.MinInt32) }, "d280000000"},
+		{"int64", func(w gensenc.FormatWriter) error { return w.WriteInt(math.MinInt64) }, "d38000000000000000"},
+		{"uint8", func(w gensenc.FormatWriter) error { return w.WriteInt(200) }, "ccc8"},
+		{"uint16", func(w gensenc.FormatWriter) error { return w.WriteUint(256) }, "cd0100"},
+		{"uint32", func(w gensenc.FormatWriter) error { return w.WriteUint(1 << 16) }, "ce00010000"},
+		{"uint64", func(w gensenc.FormatWriter) error { return w.WriteUint(math.MaxUint64) }, "cfffffffffffffffff"},
+		{"float32", func(w gensenc.FormatWriter) error { return w.WriteFloat32(1.5) }, "ca3fc00000"},
+		{"float64", func(w gensenc.FormatWriter) error { return w.WriteFloat64(1.5) }, "cb3ff8000000000000"},
+		{"fixstr", func(w gensenc.FormatWriter) error { return w.WriteString("ab") }, "a26162"},
+		{"str8", func(w gensenc.FormatWriter) error { return w.WriteString(strings.Repeat("a", 32)) }, "d920" + strings.Repeat("61", 32)},
+		{"bin8", func(w gensenc.FormatWriter) error { return w.WriteBytes([]byte{1, 2}) }, "c4020102"},
+		{"fixarray", func(w gensenc.FormatWriter) error { return w.WriteArrayHeader(15) }, "9f"},
+		{"array16", func(w gensenc.FormatWriter) error { return w.WriteArrayHeader(16) }, "dc0010"},
+		{"array32", func(w gensenc.FormatWriter) error { return w.WriteArrayHeader(1 << 16) }, "dd00010000"},
+		{"fixmap", func(w gensenc.FormatWriter) error { return w.WriteMapHeader(1) }, "81"},
+		{"map16", func(w gensenc.FormatWriter) error { return w.WriteMapHeader(16) }, "de0010"},
+	} {
+		var buf bytes.Buffer
+		if err := tt.write(msgpack.Format.NewWriter(&buf)); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+			t.Errorf("%s: wrote %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadAny(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want any
+	}{
+		{"c0", nil},
+		{"c3", true},
+		{"05", int64(5)},
+		{"ff", int64(-1)},
+		{"d1ff7f", int64(-129)},
+		{"cd0100", int64(256)},
+		{"cfffffffffffffffff", uint64(math.MaxUint64)},
+		{"ca3fc00000", 1.5},
+		{"a26162", "ab"},
+		{"c4020102", []byte{1, 2}},
+		{"92", gensenc.ArrayHeader(2)},
+		{"de0010", gensenc.MapHeader(16)},
+	} {
+		b, _ := hex.DecodeString(tt.in)
+		got, err := msgpack.Format.NewReader(bytes.NewReader(b)).ReadAny()
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: read %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	reader := func(in string) gensenc.FormatReader {
+		b, _ := hex.DecodeString(in)
+		return msgpack.Format.NewReader(bytes.NewReader(b))
+	}
+	if _, err := reader("a1").ReadString(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated string gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := reader("").ReadInt(); err != io.EOF {
+		t.Errorf("reading no input gave %v, want io.EOF", err)
+	}
+	if _, err := reader("a1").ReadInt(); !errors.Is(err, msgpack.ErrTypeMismatch) {
+		t.Errorf("reading a string as an integer gave %v, want ErrTypeMismatch", err)
+	}
+	if _, err := reader("ff").ReadUint(); !errors.Is(err, msgpack.ErrOverflow) {
+		t.Errorf("reading -1 as unsigned gave %v, want ErrOverflow", err)
+	}
+	if _, err := reader("cfffffffffffffffff").ReadInt(); !errors.Is(err, msgpack.ErrOverflow) {
+		t.Errorf("reading MaxUint64 as signed gave %v, want ErrOverflow", err)
+	}
+	if _, err := reader("c1").ReadAny(); !errors.Is(err, msgpack.ErrTypeMismatch) {
+		t.Errorf("reading the unused code c1 gave %v, want ErrTypeMismatch", err)
+	}
+	if err := reader("92a1").Skip(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("skipping a truncated array gave %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// A map with an array, a string, an ext value and a float, then 1.
+	b, _ := hex.DecodeString("82" + "a161" + "93c0c3dc0000" + "a162" + "d40102" + "01")
+	r := msgpack.Format.NewReader(bytes.NewReader(b))
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.ReadInt(); err != nil || n != 1 {
+		t.Errorf("read %d, %v after the skipped value, want 1", n, err)
+	}
+	deep := append(bytes.Repeat([]byte{0x91}, 1_000_000), 0xc0)
+	if err := msgpack.Format.NewReader(bytes.NewReader(deep)).Skip(); err != nil {
+		t.Errorf("skipping deeply nested arrays: %v", err)
+	}
+}
+
+type record struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Attrs map[string]float64
+	Data  []byte
+	Next  *record
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := record{ID: -1, Name: "a", Tags: []string{"x"}, Attrs: map[string]float64{"k": 0.5}, Data: []byte{1}, Next: &record{ID: 2, Tags: []string{"y", ""}, Attrs: map[string]float64{}, Data: []byte{2}}}
+	b, err := gensenc.EncodeFormat(msgpack.Format, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Structs are maps from field names.
+	if b[0] != 0x86 || !bytes.HasPrefix(b[1:], []byte("\xa2ID\xff")) {
+		t.Errorf("encoded as %x", b)
+	}
+	var got record
+	err = gensenc.DecodeFormat(msgpack.Format, b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+}
@@ -0,0 +1,195 @@
+package gensenc
+
+import (
+	"hash/fnv"
+	"reflect"
+)
+
+// WithNamedFields writes struct fields along with their names, so that
+// values decode into structs with fields in another order, added or
+// removed, as long-lived stored documents need: fields unknown to the
+// destination are skipped and those missing from the input are zeroed. A
+// struct is written as its number of fields followed by, for each, its
+// name, the length of its encoding and the encoding. The name tag option
+// replaces the Go name of a field on the wire, as in `gensenc:"name=id"`.
+//
+// Sections and idx tag options have no effect in this mode, and strings
+// within structs are never interned, as skipping fields would lose the
+// strings later references point to.
+func WithNamedFields() Option {
+	return func(o *options) { o.named = true }
+}
+
+// WithHashedNames is like WithNamedFields but writes the 32-bit FNV-1a
+// hash of each name instead of the name, which saves space for long names
+// at the risk of collisions between them.
+func WithHashedNames() Option {
+	return func(o *options) {
+		o.named = true
+		o.hashNames = true
+	}
+}
+
+// wireName returns the name of the field f in WithNamedFields mode.
+func (f *fieldInfo) wireName() string {
+	if name := f.opts["name"]; name != "" {
+		return name
+	}
+	return f.name
+}
+
+func nameHash(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32()
+}
+
+// namedStruct reports whether structs described by info are written with
+// field names in WithNamedFields mode, as those without a codec replacing
+// their encoding are.
+func namedStruct(info *typeInfo) bool {
+	_, ok := info.codec.(*sectionCodec)
+	return ok || info.codec == nil
+}
+
+func (e *encodeState) encodeNamed(fields []fieldInfo, v reflect.Value) error {
+	e.writeUint64(uint64(len(fields)))
+	strings := e.strings
+	e.strings = nil
+	e.pinned++
+	defer func() {
+		e.pinned--
+		e.strings = strings
+	}()
+	for i := range fields {
+		name := fields[i].wireName()
+		if e.hashNames {
+			e.order().PutUint32(e.l[:4], nameHash(name))
+			e.buf.Write(e.l[:4])
+		} else {
+			e.writeString(name)
+		}
+		start := e.buf.Len()
+		e.writeUint64(0)
+		err := e.encodeFields(fields[i:i+1], v)
+		if err != nil {
+			return err
+		}
+		e.order().PutUint64(e.buf.Bytes()[start:], uint64(e.buf.Len()-start-8))
+	}
+	return nil
+}
+
+// readName reads the name of a field in WithNamedFields mode, and returns
+// the index of the field of fields it names, or -1 if none.
+func (d *decodeState) readName(fields []fieldInfo) (int, error) {
+	if d.hashNames {
+		b, err := d.take(4)
+		if err != nil {
+			return -1, err
+		}
+		h := d.order().Uint32(b)
+		for i := range fields {
+			if nameHash(fields[i].wireName()) == h {
+				return i, nil
+			}
+		}
+		return -1, nil
+	}
+	name, err := d.readString()
+	if err != nil {
+		return -1, err
+	}
+	for i := range fields {
+		if fields[i].wireName() == name {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// readNamed reads a struct written by encodeNamed, calling fn with each
+// field of fields on the wire to decode or skip it, and skipping the
+// fields not among them. It returns which fields were seen.
+func (d *decodeState) readNamed(fields []fieldInfo, fn func(f *fieldInfo) error) ([]bool, error) {
+	n, err := d.readUint64()
+	if err == nil {
+		err = d.checkLength(n, 12)
+	}
+	if err != nil {
+		return nil, err
+	}
+	intern := d.intern
+	d.intern = false
+	defer func() { d.intern = intern }()
+	seen := make([]bool, len(fields))
+	for range n {
+		err := d.tick()
+		if err != nil {
+			return nil, err
+		}
+		i, err := d.readName(fields)
+		if err != nil {
+			return nil, err
+		}
+		length, err := d.readUint64()
+		if err == nil {
+			err = d.checkLength(length, 1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := d.offset() + int64(length)
+		if i >= 0 {
+			err = fn(&fields[i])
+			if err != nil {
+				return nil, err
+			}
+			if d.offset() > end {
+				return nil, d.at(ErrMalformed, "."+fields[i].name)
+			}
+			seen[i] = true
+		}
+		err = d.discard(uint64(end - d.offset()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return seen, nil
+}
+
+func (d *decodeState) decodeNamed(fields []fieldInfo, v reflect.Value) error {
+	seen, err := d.readNamed(fields, func(f *fieldInfo) error {
+		return d.decodeStructField(f, v)
+	})
+	if err != nil {
+		return err
+	}
+	for i, f := range fields {
+		if seen[i] {
+			continue
+		}
+		if !v.CanSet() {
+			return ErrCantSet
+		}
+		v.Field(f.index).SetZero()
+	}
+	return nil
+}
+
+func (d *decodeState) skipNamed() error {
+	_, err := d.readNamed(nil, nil)
+	return err
+}
+
+// computeHasStructs reports whether t contains a struct. Like
+// computeHasArrays, it requires t to have a fixed wire size.
+func computeHasStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Array:
+		return computeHasStructs(t.Elem())
+	}
+	return false
+}
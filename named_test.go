@@ -0,0 +1,146 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type namedV1 struct {
+	ID     int
+	Name   string
+	Tags   []string
+	Pos    [2]point
+	Legacy string
+}
+
+// namedV2 is namedV1 with its fields reordered, Name renamed in Go, Legacy
+// removed and Extra added.
+type namedV2 struct {
+	Pos   [2]point
+	Extra float64
+	Label string `gensenc:"name=Name"`
+	ID    int
+	Tags  []string
+}
+
+var namedModes = []struct {
+	name string
+	opt  gensenc.Option
+}{
+	{"names", gensenc.WithNamedFields()},
+	{"hashed names", gensenc.WithHashedNames()},
+}
+
+func TestNamedFields(t *testing.T) {
+	in := namedV1{
+		ID:     7,
+		Name:   "seven",
+		Tags:   []string{"a", "a"},
+		Pos:    [2]point{{1, 2}, {3, 4}},
+		Legacy: "old",
+	}
+	for _, m := range namedModes {
+		t.Run(m.name, func(t *testing.T) {
+			c := gensenc.New(m.opt, gensenc.WithInterning())
+			if got := roundTrip(t, c, in); !reflect.DeepEqual(got, in) {
+				t.Errorf("got %+v, want %+v", got, in)
+			}
+			b, err := c.Encode(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Extra is missing from the input, so it's zeroed.
+			got := namedV2{Extra: 9}
+			err = c.Decode(b, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := namedV2{Pos: in.Pos, Label: in.Name, ID: in.ID, Tags: in.Tags}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded into namedV2 as %+v, want %+v", got, want)
+			}
+			// Whole structs are skipped where none is expected, as in slices
+			// of them decoded into another shape.
+			b, err = c.Encode([]namedV1{in, in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tags []struct{ Tags []string }
+			err = c.Decode(b, &tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tags) != 2 || !reflect.DeepEqual(tags[1].Tags, in.Tags) {
+				t.Errorf("decoded tags as %+v, want two of %v", tags, in.Tags)
+			}
+		})
+	}
+}
+
+// TestNamedFieldsLayout checks that fields are written with their names,
+// or the hashes of them, ahead of their lengths.
+func TestNamedFieldsLayout(t *testing.T) {
+	v := struct {
+		ID int64 `gensenc:"name=id"`
+	}{-1}
+	b, err := gensenc.New(gensenc.WithNamedFields()).Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := binary.LittleEndian.AppendUint64(nil, 1)
+	want = binary.LittleEndian.AppendUint64(want, 2)
+	want = append(want, "id"...)
+	want = binary.LittleEndian.AppendUint64(want, 8)
+	want = binary.LittleEndian.AppendUint64(want, 1<<64-1)
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	b, err = gensenc.New(gensenc.WithHashedNames()).Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0x37386ae0 is the FNV-1a hash of "id".
+	want = binary.LittleEndian.AppendUint64(nil, 1)
+	want = binary.LittleEndian.AppendUint32(want, 0x37386ae0)
+	want = binary.LittleEndian.AppendUint64(want, 8)
+	want = binary.LittleEndian.AppendUint64(want, 1<<64-1)
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded with hashed names as %x, want %x", b, want)
+	}
+}
+
+func TestNamedFieldsErrors(t *testing.T) {
+	type single struct{ ID int }
+	c := gensenc.New(gensenc.WithNamedFields())
+	b, err := c.Encode(single{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got single
+	// The length of ID is checked against the input left.
+	if err := c.Decode(b[:len(b)-1], &got); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding truncated input gave %v, want ErrInvalidLength", err)
+	}
+	// The struct claims more fields than the input could hold.
+	long := bytes.Clone(b)
+	binary.LittleEndian.PutUint64(long, 1<<40)
+	if err := c.Decode(long, &got); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding too many fields gave %v, want ErrInvalidLength", err)
+	}
+	// ID is written as shorter than its encoding.
+	short := bytes.Clone(b)
+	binary.LittleEndian.PutUint64(short[18:], 1)
+	err = c.Decode(short, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrMalformed) {
+		t.Fatalf("decoding a field past its length gave %v, want ErrMalformed", err)
+	}
+	if de.Path != "ID" {
+		t.Errorf("error at %q, want ID", de.Path)
+	}
+}
@@ -0,0 +1,115 @@
+package gensenc
+
+import (
+	"reflect"
+	"strings"
+)
+
+// An Event describes a value being encoded or decoded.
+type Event struct {
+	Decoding bool
+	Type     reflect.Type
+	// Path locates the value within the top-level value as in the keys of
+	// Encoder.Stats.
+	Path string
+	// Offset is the number of bytes of the top-level value before the
+	// value.
+	Offset int64
+	// Size is the number of bytes of the value, and Err the error encoding
+	// or decoding it failed with. Both are only set for End.
+	Size int64
+	Err  error
+}
+
+// An EventObserver is notified when the encoding or decoding of top-level
+// values and of struct fields at any depth starts and ends. Observers of a
+// Codec must be safe for concurrent use if the Codec is.
+type EventObserver interface {
+	Start(ev Event)
+	End(ev Event)
+}
+
+// WithObserver makes the Codec notify o of the values it encodes and
+// decodes. Values are encoded and decoded field by field then.
+func WithObserver(o EventObserver) Option {
+	return func(opts *options) { opts.observer = o }
+}
+
+// tracker follows the path of the value being encoded or decoded, for
+// statistics and observers.
+type tracker struct {
+	path string
+	// origin is the offset of the top-level value.
+	origin int64
+}
+
+// enter appends elem to the path of the value being processed and returns
+// a function restoring it.
+func (t *tracker) enter(elem string) func() {
+	path := t.path
+	t.path += elem
+	return func() { t.path = path }
+}
+
+func (t *tracker) event(decoding bool, typ reflect.Type, off int64) Event {
+	return Event{
+		Decoding: decoding,
+		Type:     typ,
+		Path:     strings.TrimPrefix(t.path, "."),
+		Offset:   off - t.origin,
+	}
+}
+
+func (e *encodeState) tracking() bool {
+	return e.stats != nil || e.observer != nil
+}
+
+// encodeTracked is encodeField, recording statistics and notifying the
+// observer.
+func (e *encodeState) encodeTracked(f *fieldInfo, v reflect.Value) error {
+	defer e.enter("." + f.name)()
+	ev := e.event(false, f.typ, int64(e.buf.Len()))
+	if e.observer != nil {
+		e.observer.Start(ev)
+	}
+	err := e.encodeField(f, v)
+	ev.Size = int64(e.buf.Len()) - e.origin - ev.Offset
+	if e.stats != nil {
+		e.stats[ev.Path] += ev.Size
+	}
+	if e.observer != nil {
+		ev.Err = err
+		e.observer.End(ev)
+	}
+	return err
+}
+
+func (e *encodeState) encodeObserved(v reflect.Value) error {
+	e.origin = int64(e.buf.Len())
+	ev := e.event(false, v.Type(), e.origin)
+	e.observer.Start(ev)
+	err := e.encode(v)
+	ev.Size, ev.Err = int64(e.buf.Len())-e.origin, err
+	e.observer.End(ev)
+	return err
+}
+
+func (d *decodeState) decodeTracked(f *fieldInfo, v reflect.Value) error {
+	defer d.enter("." + f.name)()
+	ev := d.event(true, f.typ, d.offset())
+	d.observer.Start(ev)
+	err := d.decodeField(f, v)
+	ev.Size, ev.Err = d.offset()-d.origin-ev.Offset, err
+	d.observer.End(ev)
+	return err
+}
+
+func (d *decodeState) decodeObserved(v reflect.Value) error {
+	d.origin = d.offset()
+	ev := d.event(true, v.Type(), d.origin)
+	d.observer.Start(ev)
+	err := d.decode(v)
+	ev.Size, ev.Err = d.offset()-d.origin, err
+	d.observer.End(ev)
+	return err
+}
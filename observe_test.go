@@ -0,0 +1,83 @@
+package gensenc_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// recorder notes the events it is notified of.
+type recorder struct {
+	events []string
+	errs   []error
+}
+
+func (r *recorder) Start(ev gensenc.Event) {
+	r.events = append(r.events, fmt.Sprintf("start %s %v @%d", ev.Path, ev.Type, ev.Offset))
+}
+
+func (r *recorder) End(ev gensenc.Event) {
+	r.events = append(r.events, fmt.Sprintf("end %s %d", ev.Path, ev.Size))
+	if ev.Err != nil {
+		r.errs = append(r.errs, ev.Err)
+	}
+}
+
+type leg struct {
+	From  string
+	Stops []point
+}
+
+func TestObserver(t *testing.T) {
+	var r recorder
+	c := gensenc.New(gensenc.WithObserver(&r))
+	v := leg{From: "a", Stops: []point{{1, 2}}}
+	b, err := c.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"start  gensenc_test.leg @0",
+		"start From string @0",
+		"end From 9",
+		"start Stops []gensenc_test.point @9",
+		"start Stops[].X int32 @17",
+		"end Stops[].X 8",
+		"start Stops[].Y int32 @25",
+		"end Stops[].Y 8",
+		"end Stops 24",
+		"end  33",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("encoding notified\n%q\nwant\n%q", r.events, want)
+	}
+
+	r.events = nil
+	var got leg
+	err = c.Decode(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("decoding notified\n%q\nwant\n%q", r.events, want)
+	}
+
+	// Failures are reported by the End events of the failing field and
+	// those enclosing it, here for a Y beyond int32.
+	b[len(b)-3] = 1
+	err = c.Decode(b, &got)
+	if !errors.Is(err, gensenc.ErrOverflow) || len(r.errs) != 3 {
+		t.Fatalf("got %v and the errors %v", err, r.errs)
+	}
+	for _, e := range r.errs {
+		if !errors.Is(e, gensenc.ErrOverflow) {
+			t.Errorf("an End event reported %v, want ErrOverflow", e)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gensenc
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// minParallelChunk is the smallest number of elements worth handing to a
+// separate goroutine.
+const minParallelChunk = 4096
+
+// EncodeParallel is like Encode but, when a is a large slice, encodes
+// contiguous chunks of it on up to workers goroutines and concatenates the
+// results in order. The output is identical to that of Encode. If workers
+// is not positive, GOMAXPROCS is used.
+func EncodeParallel(a any, workers int) ([]byte, error) {
+	v := addressable(reflect.ValueOf(a))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if v.Kind() != reflect.Slice {
+		return EncodeValue(v)
+	}
+	workers = min(workers, v.Len()/minParallelChunk)
+	if workers <= 1 {
+		return EncodeValue(v)
+	}
+	parts := make([]*encodeState, workers)
+	errs := make([]error, workers)
+	size := (v.Len() + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := range workers {
+		parts[w] = defaultCodec.newEncodeState()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w * size; i < min((w+1)*size, v.Len()); i++ {
+				err := parts[w].encode(v.Index(i))
+				if err != nil {
+					errs[w] = joinPath(parts[w].at(err, index(i)))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	e := defaultCodec.newEncodeState()
+	e.writeUint64(uint64(v.Len()))
+	for w := range workers {
+		if errs[w] != nil {
+			return nil, errs[w]
+		}
+		e.buf.Write(parts[w].buf.Bytes())
+	}
+	return e.buf.Bytes(), nil
+}
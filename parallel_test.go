@@ -0,0 +1,51 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestEncodeParallel(t *testing.T) {
+	v := make([]order, 20000)
+	for i := range v {
+		v[i] = order{ID: uint64(i), Items: []string{strconv.Itoa(i)}}
+	}
+	want, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 3, 100} {
+		got, err := gensenc.EncodeParallel(v, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d workers: encoding differs from Encode", workers)
+		}
+	}
+	// Values other than slices are encoded as by Encode.
+	got, err := gensenc.EncodeParallel(&v[1], 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := gensenc.Encode(v[1]); !bytes.Equal(got, want) {
+		t.Error("encoding of a struct differs from Encode")
+	}
+}
+
+func TestEncodeParallelError(t *testing.T) {
+	v := make([]any, 20000)
+	v[15000] = make(chan int)
+	_, err := gensenc.EncodeParallel(v, 4)
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnregisteredType) {
+		t.Fatalf("encoding a channel gave %v, want ErrUnregisteredType", err)
+	}
+	if ee.Path != "[15000]" {
+		t.Errorf("error at %q, want [15000]", ee.Path)
+	}
+}
@@ -0,0 +1,160 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+)
+
+var ErrTypeMismatch error = errors.New("values of different types")
+
+// patchable reports whether values of t are patched field by field, as
+// structs without a codec of their own are.
+func patchable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := infoOf(t).codec.(*sectionCodec)
+	return ok || infoOf(t).codec == nil
+}
+
+// patched reports whether the field f is patched rather than replaced
+// whole.
+func patched(f *fieldInfo) bool {
+	return f.codec == nil && patchable(f.typ)
+}
+
+// structOf returns the value v points to through any number of pointers,
+// taking nil pointers as pointing to the zero value.
+func structOf(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v = reflect.New(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// EncodePatch encodes the fields of the struct new that differ from those
+// of old, which must be of the same type or point to it, so that
+// ApplyPatch can turn a copy of old into new by decoding only what
+// changed. A patch starts with a bitmap of the struct's fields in wire
+// order, one bit per field, set for the fields that follow it. Struct
+// fields are themselves patched, all others are written whole as Encode
+// writes them when not equal according to reflect.DeepEqual. Patches only
+// apply to values of the same type.
+func EncodePatch(old, new any) ([]byte, error) {
+	a, b := structOf(reflect.ValueOf(old)), structOf(reflect.ValueOf(new))
+	if !a.IsValid() || !b.IsValid() {
+		return nil, ErrNilPointer
+	}
+	if a.Type() != b.Type() {
+		return nil, ErrTypeMismatch
+	}
+	if !patchable(a.Type()) {
+		return nil, ErrNotStruct
+	}
+	e := defaultCodec.newEncodeState()
+	fields := infoOf(a.Type()).fields
+	changed, err := e.encodePatch(fields, addressable(a), addressable(b))
+	if err != nil {
+		return nil, joinPath(err)
+	}
+	if !changed {
+		e.buf.Write(make([]byte, (len(fields)+7)/8))
+	}
+	return e.buf.Bytes(), nil
+}
+
+// encodePatch writes the patch from the struct old to new and reports
+// whether any field changed. Nothing is written if none did.
+func (e *encodeState) encodePatch(fields []fieldInfo, old, new reflect.Value) (bool, error) {
+	start := e.buf.Len()
+	bitmap := make([]byte, (len(fields)+7)/8)
+	e.buf.Write(bitmap)
+	changed := false
+	for i := range fields {
+		f := &fields[i]
+		a, b := old.Field(f.index), new.Field(f.index)
+		var ok bool
+		var err error
+		if patched(f) {
+			ok, err = e.encodePatch(infoOf(f.typ).fields, a, b)
+		} else if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			ok, err = true, e.encodeField(f, b)
+		}
+		if err != nil {
+			return false, e.at(err, "."+f.name)
+		}
+		if ok {
+			bitmap[i/8] |= 1 << (i % 8)
+			changed = true
+		}
+	}
+	if !changed {
+		e.buf.Truncate(start)
+		return false, nil
+	}
+	copy(e.buf.Bytes()[start:], bitmap)
+	return true, nil
+}
+
+// ApplyPatch decodes the patch b, written by EncodePatch, into the struct
+// a points to, setting the fields that changed and leaving the others as
+// they are.
+func ApplyPatch(b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrNilPointer
+	}
+	v = v.Elem()
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if !patchable(v.Type()) {
+		return ErrNotStruct
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	return d.guard(func() error {
+		err := d.applyPatch(infoOf(v.Type()).fields, v)
+		if err != nil {
+			return err
+		}
+		if d.off != len(d.b) {
+			return ErrTrailingBytes
+		}
+		return nil
+	})
+}
+
+func (d *decodeState) applyPatch(fields []fieldInfo, v reflect.Value) error {
+	bitmap, err := d.take(uint64(len(fields)+7) / 8)
+	if err != nil {
+		return err
+	}
+	// Bits past the last field must be clear.
+	if n := len(fields) % 8; n != 0 && bitmap[len(bitmap)-1]>>n != 0 {
+		return ErrMalformed
+	}
+	for i := range fields {
+		if bitmap[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+		f := &fields[i]
+		if !patched(f) {
+			err = d.decodeStructField(f, v)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = d.applyPatch(infoOf(f.typ).fields, v.Field(f.index))
+		if err != nil {
+			return d.at(err, "."+f.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package gensenc_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	patchInner struct {
+		A, B int
+		S    []string
+	}
+	patchState struct {
+		Name  string
+		Count int
+		In    patchInner
+		M     map[string]int
+		Skip  int `gensenc:"-"`
+	}
+)
+
+func TestPatch(t *testing.T) {
+	old := patchState{Name: "a", Count: 1, In: patchInner{1, 2, []string{"x"}}, M: map[string]int{"k": 1}}
+	next := old
+	next.In.B = 5
+	next.Count = 7
+	next.Skip = 3
+	b, err := gensenc.EncodePatch(old, &next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Count and In set in the bitmap, then B alone of In.
+	want := "\x06" + "\x07\x00\x00\x00\x00\x00\x00\x00" + "\x02" + "\x05\x00\x00\x00\x00\x00\x00\x00"
+	if string(b) != want {
+		t.Errorf("patch is %x, want %x", b, want)
+	}
+	got := old
+	if err := gensenc.ApplyPatch(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	next.Skip = 0
+	if !reflect.DeepEqual(got, next) {
+		t.Errorf("patched into %+v, want %+v", got, next)
+	}
+
+	// Patches without changes are an empty bitmap, and patch through
+	// pointers.
+	b, err = gensenc.EncodePatch(&old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\x00" {
+		t.Errorf("empty patch is %x, want 00", b)
+	}
+	var p *patchState
+	if err := gensenc.ApplyPatch(b, &p); err != nil || p == nil || !reflect.DeepEqual(*p, patchState{}) {
+		t.Errorf("patching a nil pointer gave %+v, %v", p, err)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	old := patchState{Name: "a"}
+	for _, c := range []struct {
+		old, new any
+		want     error
+	}{
+		{1, 2, gensenc.ErrNotStruct},
+		{old, patchInner{}, gensenc.ErrTypeMismatch},
+		{nil, old, gensenc.ErrNilPointer},
+		{old, patchState{M: map[string]int{"a": 1}, Skip: 1}, nil},
+	} {
+		if _, err := gensenc.EncodePatch(c.old, c.new); !errors.Is(err, c.want) {
+			t.Errorf("patching %T to %T gave %v, want %v", c.old, c.new, err, c.want)
+		}
+	}
+	_, err := gensenc.EncodePatch(struct{ C chan int }{}, struct{ C chan int }{make(chan int)})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnsupportedKind) || ee.Path != "C" {
+		t.Errorf("patching a channel gave %v, want ErrUnsupportedKind at C", err)
+	}
+
+	got := old
+	for _, c := range []struct {
+		patch string
+		want  error
+	}{
+		// A bit past the four fields.
+		{"\x10", gensenc.ErrMalformed},
+		{"\x02\x07\x00", gensenc.ErrTruncated},
+		{"\x00\x00", gensenc.ErrTrailingBytes},
+		{"", io.EOF},
+	} {
+		if err := gensenc.ApplyPatch([]byte(c.patch), &got); !errors.Is(err, c.want) {
+			t.Errorf("applying %x gave %v, want %v", c.patch, err, c.want)
+		}
+	}
+	if err := gensenc.ApplyPatch([]byte{0}, got); !errors.Is(err, gensenc.ErrNilPointer) {
+		t.Errorf("applying to a non-pointer gave %v, want ErrNilPointer", err)
+	}
+}
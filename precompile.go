@@ -0,0 +1,34 @@
+package gensenc
+
+import (
+	"reflect"
+)
+
+// Precompile analyzes the given types and the types they contain, which is
+// otherwise done the first time values of each are encoded or decoded, so
+// that latency-sensitive services can pay for it at startup instead.
+func (c *Codec) Precompile(types ...reflect.Type) {
+	seen := map[reflect.Type]bool{}
+	for _, t := range types {
+		precompile(t, seen)
+	}
+}
+
+func precompile(t reflect.Type, seen map[reflect.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	info := infoOf(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range info.fields {
+			precompile(f.typ, seen)
+		}
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		precompile(t.Elem(), seen)
+	case reflect.Map:
+		precompile(t.Key(), seen)
+		precompile(t.Elem(), seen)
+	}
+}
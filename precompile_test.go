@@ -0,0 +1,46 @@
+package gensenc_test
+
+import (
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	precompiledTree struct {
+		Children map[string][]*precompiledTree
+		Leaves   [2]precompiledLeaf
+	}
+	precompiledLeaf struct {
+		Key  precompiledKey
+		Name string
+	}
+	precompiledKey struct{ ID uint32 }
+	notPrecompiled struct{ ID uint32 }
+)
+
+// TestPrecompile checks that the types Precompile is given, and those they
+// contain however deep, need no analysis when first used.
+func TestPrecompile(t *testing.T) {
+	c := gensenc.New(gensenc.WithMetrics())
+	c.Precompile(reflect.TypeFor[precompiledTree](), reflect.TypeFor[int]())
+	for _, v := range []any{
+		precompiledTree{Children: map[string][]*precompiledTree{"x": {nil}}},
+		&precompiledLeaf{Name: "a"},
+		precompiledKey{1},
+		notPrecompiled{1},
+	} {
+		b, err := c.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Decode(b, reflect.New(reflect.TypeOf(v)).Interface()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// All but the first use of notPrecompiled.
+	if s := c.Stats(); s.PlanHits != 7 || s.PlanMisses != 1 {
+		t.Errorf("counted %d plan hits and %d misses, want 7 and 1", s.PlanHits, s.PlanMisses)
+	}
+}
@@ -0,0 +1,444 @@
+package gensenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrInvalidProto       error = errors.New("invalid protobuf data")
+	ErrInvalidFieldNumber error = errors.New("invalid protobuf field number")
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// A protoField is a struct field carrying a protobuf field number in its
+// tag, as in `gensenc:"proto=3"`. The zigzag option selects the sint32 and
+// sint64 encoding for signed integers.
+type protoField struct {
+	fieldInfo
+	num    uint64
+	zigzag bool
+}
+
+var protoFieldCache sync.Map
+
+func protoFieldsOf(t reflect.Type) ([]protoField, error) {
+	cached, ok := protoFieldCache.Load(t)
+	if ok {
+		return cached.([]protoField), nil
+	}
+	var fields []protoField
+	seen := map[uint64]bool{}
+	for _, f := range infoOf(t).fields {
+		s, ok := f.opts["proto"]
+		if !ok {
+			continue
+		}
+		num, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || num < 1 || num > 1<<29-1 || seen[num] {
+			return nil, ErrInvalidFieldNumber
+		}
+		seen[num] = true
+		fields = append(fields, protoField{fieldInfo: f, num: num, zigzag: f.opts.has("zigzag")})
+	}
+	protoFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+func appendProtoTag(b []byte, num uint64, wt int) []byte {
+	return binary.AppendUvarint(b, num<<3|uint64(wt))
+}
+
+func appendProtoBytes(b []byte, num uint64, data []byte) []byte {
+	b = appendProtoTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func isProtoScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func protoWireType(k reflect.Kind) int {
+	switch k {
+	case reflect.Float32:
+		return wireFixed32
+	case reflect.Float64:
+		return wireFixed64
+	}
+	return wireVarint
+}
+
+// appendProtoScalar appends the untagged encoding of a numeric or bool
+// value.
+func appendProtoScalar(b []byte, v reflect.Value, zigzag bool) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := v.Int()
+		if zigzag {
+			return binary.AppendUvarint(b, uint64(x<<1^x>>63))
+		}
+		return binary.AppendUvarint(b, uint64(x))
+	case reflect.Float32:
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float()))
+	}
+	return binary.AppendUvarint(b, v.Uint())
+}
+
+func (e *encodeState) appendProtoMessage(b []byte, v reflect.Value) ([]byte, error) {
+	fields, err := protoFieldsOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		b, err = e.appendProtoField(b, f.num, f.zigzag, v.Field(f.index))
+		if err != nil {
+			return nil, e.at(err, "."+f.name)
+		}
+	}
+	return b, nil
+}
+
+func (e *encodeState) appendProtoField(b []byte, num uint64, zigzag bool, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return b, nil
+		}
+		return e.appendProtoValue(b, num, zigzag, v.Elem(), true)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return b, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendProtoBytes(b, num, v.Bytes()), nil
+		}
+		if isProtoScalar(v.Type().Elem().Kind()) {
+			var packed []byte
+			for i := range v.Len() {
+				packed = appendProtoScalar(packed, v.Index(i), zigzag)
+			}
+			return appendProtoBytes(b, num, packed), nil
+		}
+		var err error
+		for i := range v.Len() {
+			b, err = e.appendProtoValue(b, num, zigzag, v.Index(i), true)
+			if err != nil {
+				return nil, e.at(err, index(i))
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			entry, err := e.appendProtoValue(nil, 1, zigzag, key, true)
+			if err == nil {
+				entry, err = e.appendProtoValue(entry, 2, zigzag, v.MapIndex(key), true)
+			}
+			if err != nil {
+				return nil, e.at(err, "["+fmt.Sprint(key)+"]")
+			}
+			b = appendProtoBytes(b, num, entry)
+		}
+		return b, nil
+	}
+	return e.appendProtoValue(b, num, zigzag, v, false)
+}
+
+// appendProtoValue appends a single tagged value. Zero values are omitted
+// unless force is set, matching proto3 implicit presence. Messages nest
+// through it, so it counts against the depth limit like encode.
+func (e *encodeState) appendProtoValue(b []byte, num uint64, zigzag bool, v reflect.Value, force bool) ([]byte, error) {
+	if e.depth >= e.depthLimit() {
+		return nil, ErrMaxDepth
+	}
+	e.depth++
+	b, err := e.appendProtoTagged(b, num, zigzag, v, force)
+	e.depth--
+	return b, err
+}
+
+func (e *encodeState) appendProtoTagged(b []byte, num uint64, zigzag bool, v reflect.Value, force bool) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 && !force {
+			return b, nil
+		}
+		return appendProtoBytes(b, num, []byte(v.String())), nil
+	case reflect.Struct:
+		m, err := e.appendProtoMessage(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == 0 && !force {
+			return b, nil
+		}
+		return appendProtoBytes(b, num, m), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, ErrUnsupportedKind
+		}
+		if v.Len() == 0 && !force {
+			return b, nil
+		}
+		return appendProtoBytes(b, num, v.Bytes()), nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return e.appendProtoValue(b, num, zigzag, reflect.New(v.Type().Elem()).Elem(), force)
+		}
+		return e.appendProtoValue(b, num, zigzag, v.Elem(), force)
+	}
+	if !isProtoScalar(v.Kind()) {
+		return nil, ErrUnsupportedKind
+	}
+	if v.IsZero() && !force {
+		return b, nil
+	}
+	b = appendProtoTag(b, num, protoWireType(v.Kind()))
+	return appendProtoScalar(b, v, zigzag), nil
+}
+
+// protoNext reads one value of wire type wt from b, returning its integer
+// value or, for length-delimited values, its contents, and the rest of b.
+func protoNext(b []byte, wt int) (uint64, []byte, []byte, error) {
+	switch wt {
+	case wireVarint:
+		x, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, nil, nil, ErrInvalidProto
+		}
+		return x, nil, b[n:], nil
+	case wireFixed64:
+		if len(b) < 8 {
+			return 0, nil, nil, ErrInvalidProto
+		}
+		return binary.LittleEndian.Uint64(b), nil, b[8:], nil
+	case wireFixed32:
+		if len(b) < 4 {
+			return 0, nil, nil, ErrInvalidProto
+		}
+		return uint64(binary.LittleEndian.Uint32(b)), nil, b[4:], nil
+	case wireBytes:
+		l, n := binary.Uvarint(b)
+		if n <= 0 || l > uint64(len(b)-n) {
+			return 0, nil, nil, ErrInvalidProto
+		}
+		return 0, b[n : n+int(l)], b[n+int(l):], nil
+	}
+	return 0, nil, nil, ErrInvalidProto
+}
+
+// decodeProtoMessage decodes the message b, which is d.b or part of it,
+// into the struct v.
+func (d *decodeState) decodeProtoMessage(b []byte, v reflect.Value) error {
+	fields, err := protoFieldsOf(v.Type())
+	if err != nil {
+		return err
+	}
+	for len(b) > 0 {
+		// b ends where d.b does or shares its array, so the capacity left
+		// tells how far into d.b it starts.
+		d.off = cap(d.b) - cap(b)
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrInvalidProto
+		}
+		num, wt := key>>3, int(key&7)
+		x, data, rest, err := protoNext(b[n:], wt)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if f.num == num {
+				err = d.decodeProtoField(v.Field(f.index), wt, x, data, f.zigzag)
+				if err != nil {
+					return d.at(err, "."+f.name)
+				}
+				break
+			}
+		}
+		b = rest
+	}
+	return nil
+}
+
+// decodeProtoField decodes a value of the field v. Messages nest through
+// it, so it counts against the depth limit like decode.
+func (d *decodeState) decodeProtoField(v reflect.Value, wt int, x uint64, data []byte, zigzag bool) error {
+	if d.depth >= d.depthLimit() {
+		return ErrMaxDepth
+	}
+	d.depth++
+	err := d.decodeProtoValue(v, wt, x, data, zigzag)
+	d.depth--
+	return err
+}
+
+func (d *decodeState) decodeProtoValue(v reflect.Value, wt int, x uint64, data []byte, zigzag bool) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeProtoField(v.Elem(), wt, x, data, zigzag)
+	case reflect.Slice:
+		et := v.Type().Elem()
+		if et.Kind() == reflect.Uint8 {
+			if wt != wireBytes {
+				return ErrInvalidProto
+			}
+			v.SetBytes(bytes.Clone(data))
+			return nil
+		}
+		if isProtoScalar(et.Kind()) && wt == wireBytes {
+			for len(data) > 0 {
+				var err error
+				x, _, data, err = protoNext(data, protoWireType(et.Kind()))
+				if err != nil {
+					return err
+				}
+				elem := reflect.New(et).Elem()
+				err = d.decodeProtoField(elem, protoWireType(et.Kind()), x, nil, zigzag)
+				if err != nil {
+					return d.at(err, index(v.Len()))
+				}
+				v.Set(reflect.Append(v, elem))
+			}
+			return nil
+		}
+		elem := reflect.New(et).Elem()
+		err := d.decodeProtoField(elem, wt, x, data, zigzag)
+		if err != nil {
+			return d.at(err, index(v.Len()))
+		}
+		v.Set(reflect.Append(v, elem))
+		return nil
+	case reflect.Map:
+		if wt != wireBytes {
+			return ErrInvalidProto
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		value := reflect.New(v.Type().Elem()).Elem()
+		for len(data) > 0 {
+			k, n := binary.Uvarint(data)
+			if n <= 0 {
+				return ErrInvalidProto
+			}
+			ex, edata, rest, err := protoNext(data[n:], int(k&7))
+			if err != nil {
+				return err
+			}
+			data = rest
+			switch k >> 3 {
+			case 1:
+				err = d.decodeProtoField(key, int(k&7), ex, edata, zigzag)
+			case 2:
+				err = d.decodeProtoField(value, int(k&7), ex, edata, zigzag)
+			}
+			if err != nil {
+				return d.at(err, index(v.Len()))
+			}
+		}
+		v.SetMapIndex(key, value)
+		return nil
+	case reflect.Struct:
+		if wt != wireBytes {
+			return ErrInvalidProto
+		}
+		return d.decodeProtoMessage(data, v)
+	case reflect.String:
+		if wt != wireBytes {
+			return ErrInvalidProto
+		}
+		v.SetString(string(data))
+		return nil
+	}
+	if !isProtoScalar(v.Kind()) {
+		return ErrUnsupportedKind
+	}
+	if wt != protoWireType(v.Kind()) {
+		return ErrInvalidProto
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(x != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if zigzag {
+			x = x>>1 ^ -(x & 1)
+		}
+		return d.setInteger(v, x)
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(x))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(x))
+	default:
+		return d.setInteger(v, x)
+	}
+	return nil
+}
+
+// EncodeProto encodes the struct a as a protobuf message. Only fields
+// tagged with a field number, as in `gensenc:"proto=1"`, are written.
+// Integers use the int32/int64 and uint32/uint64 varint encodings, or
+// sint32/sint64 with the zigzag option, floats use fixed32 and fixed64,
+// repeated scalars are packed and maps are written as repeated entry
+// messages. Values nested deeper than the depth limit, such as cyclic ones,
+// fail with ErrMaxDepth.
+func EncodeProto(a any) ([]byte, error) {
+	v := addressable(reflect.ValueOf(a))
+	if v.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+	e := defaultCodec.newEncodeState()
+	b, err := e.appendProtoMessage(nil, v)
+	if err != nil {
+		return nil, joinPath(err)
+	}
+	return b, nil
+}
+
+// DecodeProto merges the protobuf message b into the struct a points to.
+// Fields with unknown numbers are skipped. Integers too large for the
+// field they are decoded into fail with ErrOverflow and, as with Decode,
+// errors are *DecodeErrors.
+func DecodeProto(b []byte, a any) error {
+	v := reflect.ValueOf(a)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !v.CanSet() {
+		return ErrNotStruct
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	return d.guard(func() error {
+		return d.decodeProtoMessage(b, v)
+	})
+}
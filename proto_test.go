@@ -0,0 +1,170 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type protoInner struct {
+	A int32 `gensenc:"proto=1"`
+}
+
+type protoMessage struct {
+	A      int32            `gensenc:"proto=1"`
+	B      string           `gensenc:"proto=2"`
+	C      *protoInner      `gensenc:"proto=3"`
+	D      []int32          `gensenc:"proto=4"`
+	M      map[string]int32 `gensenc:"proto=5"`
+	F64    float64          `gensenc:"proto=6"`
+	F32    float32          `gensenc:"proto=7"`
+	Ok     bool             `gensenc:"proto=8"`
+	S      int32            `gensenc:"proto=9,zigzag"`
+	Neg    int64            `gensenc:"proto=10"`
+	Blob   []byte           `gensenc:"proto=11"`
+	Ignore string
+}
+
+// protoBytes holds protobuf encodings of single fields of protoMessage, as
+// produced by protoc-generated code; the first four are the examples of
+// the protobuf encoding guide.
+var protoBytes = []struct {
+	name string
+	b    []byte
+	want protoMessage
+}{
+	{"int32 150", []byte{0x08, 0x96, 0x01}, protoMessage{A: 150}},
+	{"string", []byte("\x12\x07testing"), protoMessage{B: "testing"}},
+	{"embedded message", []byte{0x1a, 0x03, 0x08, 0x96, 0x01}, protoMessage{C: &protoInner{A: 150}}},
+	{"packed int32", []byte{0x22, 0x06, 0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05}, protoMessage{D: []int32{3, 270, 86942}}},
+	{"map entry", []byte{0x2a, 0x05, 0x0a, 0x01, 'a', 0x10, 0x01}, protoMessage{M: map[string]int32{"a": 1}}},
+	{"double", []byte{0x31, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f}, protoMessage{F64: 1}},
+	{"float", []byte{0x3d, 0, 0, 0x80, 0x3f}, protoMessage{F32: 1}},
+	{"bool", []byte{0x40, 0x01}, protoMessage{Ok: true}},
+	{"sint32 -2", []byte{0x48, 0x03}, protoMessage{S: -2}},
+	{"int64 -1", []byte{0x50, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, protoMessage{Neg: -1}},
+	{"bytes", []byte{0x5a, 0x02, 0x01, 0x02}, protoMessage{Blob: []byte{1, 2}}},
+}
+
+func TestProtoBytes(t *testing.T) {
+	for _, tt := range protoBytes {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := gensenc.EncodeProto(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, tt.b) {
+				t.Errorf("encoded as %x, want %x", b, tt.b)
+			}
+			var got protoMessage
+			err = gensenc.DecodeProto(tt.b, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtoUnknownFields(t *testing.T) {
+	// Field 15 of each wire type precedes field 1.
+	b := []byte{
+		0x78, 0x01,
+		0x79, 1, 2, 3, 4, 5, 6, 7, 8,
+		0x7a, 0x01, 0xff,
+		0x7d, 1, 2, 3, 4,
+		0x08, 0x96, 0x01,
+	}
+	var got protoMessage
+	err := gensenc.DecodeProto(b, &got)
+	if err != nil || got.A != 150 {
+		t.Errorf("got %+v, %v; want A 150", got, err)
+	}
+}
+
+type protoNarrow struct {
+	I8  int8   `gensenc:"proto=1"`
+	U16 uint16 `gensenc:"proto=2"`
+}
+
+func TestProtoOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		name, path string
+		b          []byte
+	}{
+		{"int8 150", "I8", []byte{0x08, 0x96, 0x01}},
+		{"uint16 86942", "U16", []byte{0x10, 0x9e, 0xa7, 0x05}},
+	} {
+		var got protoNarrow
+		err := gensenc.DecodeProto(tt.b, &got)
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) || de.Path != tt.path {
+			t.Errorf("%s: got %v, want ErrOverflow at %s", tt.name, err, tt.path)
+		}
+	}
+}
+
+func TestProtoMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"truncated varint", []byte{0x08, 0x96}, gensenc.ErrInvalidProto},
+		{"length past the end", []byte{0x12, 0x07, 't'}, gensenc.ErrInvalidProto},
+		{"wrong wire type", []byte{0x0d, 1, 2, 3, 4}, gensenc.ErrInvalidProto},
+		{"bad wire type", []byte{0x0b}, gensenc.ErrInvalidProto},
+		{"truncated embedded message", []byte{0x1a, 0x02, 0x08, 0x96}, gensenc.ErrInvalidProto},
+	} {
+		var got protoMessage
+		err := gensenc.DecodeProto(tt.b, &got)
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want a *DecodeError wrapping %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+type protoList struct {
+	Value int32      `gensenc:"proto=1"`
+	Next  *protoList `gensenc:"proto=2"`
+}
+
+func TestProtoDepth(t *testing.T) {
+	l := &protoList{Value: 1}
+	l.Next = l
+	_, err := gensenc.EncodeProto(l)
+	if !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("encoding a cycle gave %v, want ErrMaxDepth", err)
+	}
+	var ee *gensenc.EncodeError
+	if errors.As(err, &ee) && !strings.HasPrefix(ee.Path, "Next.Next") {
+		t.Errorf("error at %q, want a path along Next", ee.Path)
+	}
+
+	// Messages nested 10001 deep, each holding the next as field 2.
+	var b []byte
+	for range 10001 {
+		b = append(append([]byte{0x12}, protoLen(len(b))...), b...)
+	}
+	var got protoList
+	err = gensenc.DecodeProto(b, &got)
+	if !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("decoding deep messages gave %v, want ErrMaxDepth", err)
+	}
+}
+
+func protoLen(n int) []byte {
+	var b []byte
+	for n >= 0x80 {
+		b = append(b, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(b, byte(n))
+}
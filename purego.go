@@ -0,0 +1,34 @@
+//go:build tinygo || purego
+
+package gensenc
+
+import "reflect"
+
+// In builds for TinyGo, and others with the purego tag such as for
+// WebAssembly runtimes with a restricted reflect package, values are always
+// encoded field by field and element by element: computeMemLayout reports
+// no type as having the wire layout in memory, so the raw memory functions
+// below are never called. Strings decoded by DecodeAlias or from an Arena
+// are copies, and Arenas only allocate strings. The wire package encodes
+// without reflection at all, for code written against the wire format.
+const rawMemory = false
+
+func rawBytes(reflect.Value) []byte {
+	panic("gensenc: raw memory access in a purego build")
+}
+
+func rawSliceBytes(reflect.Value) []byte {
+	panic("gensenc: raw memory access in a purego build")
+}
+
+func unsafeString(b []byte) string {
+	return string(b)
+}
+
+func alignPad([]byte, int) int {
+	return 0
+}
+
+func sliceAt(t reflect.Type, _ []byte, n int) reflect.Value {
+	return reflect.MakeSlice(t, n, n)
+}
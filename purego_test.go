@@ -0,0 +1,45 @@
+//go:build tinygo || purego
+
+package gensenc_test
+
+import (
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// TestDecodeAliasCopies checks that strings decoded by DecodeAlias are
+// copies in builds without raw memory access.
+func TestDecodeAliasCopies(t *testing.T) {
+	b, err := gensenc.Encode([]string{"abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	err = gensenc.DecodeAlias(b, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1] = 'x'
+	if s[0] != "abc" {
+		t.Errorf("got %q after changing the input, want a copy", s[0])
+	}
+}
+
+// TestArenaSlices checks that slices decoded with an Arena are allocated
+// as usual and still decode right.
+func TestArenaSlices(t *testing.T) {
+	v := []point{{1, 2}, {3, 4}}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []point
+	if err := gensenc.DecodeArena(b, &got, gensenc.NewArena(0)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %v, want %v", got, v)
+	}
+}
@@ -0,0 +1,39 @@
+package gensenc
+
+import (
+	"reflect"
+)
+
+// Raw holds an already encoded value. It is written as a byte string of its
+// contents, copied verbatim, and decoding captures those contents without
+// looking into them, so that values can be forwarded, re-encoded next to
+// changed fields or decoded later.
+type Raw []byte
+
+// EncodeRaw encodes a for deferred decoding with Raw.Decode.
+func EncodeRaw(a any) (Raw, error) {
+	return defaultCodec.EncodeRaw(a)
+}
+
+// Decode decodes the value r holds into the value a points to, failing with
+// ErrTrailingBytes if r holds more than one value.
+func (r Raw) Decode(a any) error {
+	return defaultCodec.DecodeRaw(r, a)
+}
+
+// EncodeRaw is like the package-level EncodeRaw, with the configuration of
+// c.
+func (c *Codec) EncodeRaw(a any) (Raw, error) {
+	return c.Encode(a)
+}
+
+// DecodeRaw is like Raw.Decode, with the configuration of c, which should
+// be the one the value was encoded with.
+func (c *Codec) DecodeRaw(r Raw, a any) error {
+	d := c.newDecodeState(r, nil)
+	err := c.decode(d, reflect.ValueOf(a))
+	if err == nil && d.off != len(r) {
+		return &DecodeError{Err: ErrTrailingBytes, Offset: int64(d.off)}
+	}
+	return err
+}
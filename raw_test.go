@@ -0,0 +1,110 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type rawMessage struct {
+	Kind string
+	Body gensenc.Raw
+}
+
+func TestRaw(t *testing.T) {
+	inner, err := gensenc.Encode([]int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := gensenc.Encode(rawMessage{"x", inner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte("\x01\x00\x00\x00\x00\x00\x00\x00x\x18\x00\x00\x00\x00\x00\x00\x00"), inner...)
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	var m rawMessage
+	if err := gensenc.Decode(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	// The contents are copied from the input.
+	clear(b)
+	if !bytes.Equal(m.Body, inner) {
+		t.Errorf("captured %x, want %x", m.Body, inner)
+	}
+
+	// Forwarding with another kind keeps the body as it was.
+	m.Kind = "y"
+	b, err = gensenc.Encode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []int
+	if err := gensenc.Decode(b[9+8:], &s); err != nil || !reflect.DeepEqual(s, []int{1, 2}) {
+		t.Errorf("forwarded body decodes as %v, %v", s, err)
+	}
+	if err := gensenc.Validate(b, reflect.TypeFor[rawMessage]()); err != nil {
+		t.Error(err)
+	}
+	if got := roundTrip(t, gensenc.New(), rawMessage{Kind: "empty"}); got.Kind != "empty" || len(got.Body) != 0 {
+		t.Errorf("got %+v, want an empty body", got)
+	}
+}
+
+func TestRawTruncated(t *testing.T) {
+	b, err := gensenc.Encode(rawMessage{"x", gensenc.Raw("abc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m rawMessage
+	err = gensenc.Decode(b[:len(b)-1], &m)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrInvalidLength) || de.Path != "Body" {
+		t.Errorf("got %v, want ErrInvalidLength at Body", err)
+	}
+}
+
+func TestRawDecode(t *testing.T) {
+	c := gensenc.New(gensenc.WithVarints())
+	r, err := c.EncodeRaw(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Encode(rawMessage{"m", r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m rawMessage
+	if err := c.Decode(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := c.DecodeRaw(m.Body, &v); err != nil || v["a"] != 1 {
+		t.Errorf("got %v, %v", v, err)
+	}
+	// The default configuration reads the varints as another value.
+	if err := m.Body.Decode(&v); err == nil {
+		t.Error("decoding varints without them succeeded")
+	}
+
+	r, err = gensenc.EncodeRaw(int64(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var x int64
+	if err := r.Decode(&x); err != nil || x != 5 {
+		t.Errorf("got %d, %v, want 5", x, err)
+	}
+	err = append(r, 0).Decode(&x)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTrailingBytes) || de.Offset != 8 {
+		t.Errorf("decoding a byte too many gave %v, want ErrTrailingBytes at 8", err)
+	}
+	if err := r[:4].Decode(&x); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a truncated value gave %v, want ErrTruncated", err)
+	}
+}
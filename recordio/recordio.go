@@ -0,0 +1,175 @@
+// Package recordio stores gensenc values as an append-only sequence of
+// framed records, for simple durable queues and write-ahead logs.
+//
+// Every record is a 4 byte little-endian payload length, a 4 byte CRC-32C
+// of the payload and the gensenc encoded payload. A crash while appending
+// leaves at most one incomplete or corrupt record at the end of the log,
+// which Reader reports as ErrTruncated or ErrCorrupt and Recover removes.
+package recordio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+var (
+	ErrTruncated error = errors.New("recordio: truncated record")
+	ErrCorrupt   error = errors.New("recordio: corrupt record")
+	ErrTooLarge  error = errors.New("recordio: record too large")
+)
+
+// MaxRecordSize is the largest payload a record may have.
+const MaxRecordSize = 1 << 30
+
+const headerSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Append encodes v and writes it as one record with a single Write call.
+func (w *Writer) Append(v any) error {
+	payload, err := gensenc.Encode(v)
+	if err != nil {
+		return err
+	}
+	if len(payload) > MaxRecordSize {
+		return ErrTooLarge
+	}
+	b := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(b, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[4:], crc32.Checksum(payload, crcTable))
+	_, err = w.w.Write(append(b, payload...))
+	return err
+}
+
+// Sync commits the written records to stable storage if the underlying
+// writer supports it, as *os.File does.
+func (w *Writer) Sync() error {
+	s, ok := w.w.(interface{ Sync() error })
+	if !ok {
+		return nil
+	}
+	return s.Sync()
+}
+
+type Reader struct {
+	r   *bufio.Reader
+	off int64
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Offset returns the position just after the last record read
+// successfully.
+func (r *Reader) Offset() int64 {
+	return r.off
+}
+
+func (r *Reader) next() ([]byte, error) {
+	var h [headerSize]byte
+	n, err := io.ReadFull(r.r, h[:])
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		if n > 0 && err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(h[:])
+	if length > MaxRecordSize {
+		return nil, ErrCorrupt
+	}
+	payload, err := r.payload(length)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(h[4:]) {
+		return nil, ErrCorrupt
+	}
+	r.off += int64(headerSize + len(payload))
+	return payload, nil
+}
+
+// payload reads the n bytes of a payload. The length of a corrupt header
+// isn't trusted for the allocation: larger payloads grow as they are read.
+func (r *Reader) payload(n uint32) ([]byte, error) {
+	if n <= 64<<10 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r.r, b)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrTruncated
+		}
+		return b, err
+	}
+	var buf bytes.Buffer
+	m, err := io.CopyN(&buf, r.r, int64(n))
+	if m < int64(n) {
+		if err == io.EOF {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Next decodes the next record into v. It returns io.EOF at the end of a
+// cleanly written log.
+func (r *Reader) Next(v any) error {
+	payload, err := r.next()
+	if err != nil {
+		return err
+	}
+	return gensenc.Decode(payload, v)
+}
+
+// Skip advances past the next record without decoding it.
+func (r *Reader) Skip() error {
+	_, err := r.next()
+	return err
+}
+
+// Recover truncates the log file at path after its last intact record and
+// returns the new size.
+func Recover(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := NewReader(f)
+	for {
+		err = r.Skip()
+		if err == io.EOF {
+			return r.Offset(), nil
+		}
+		if err == ErrTruncated || err == ErrCorrupt {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = f.Truncate(r.Offset())
+	if err != nil {
+		return 0, err
+	}
+	return r.Offset(), f.Sync()
+}
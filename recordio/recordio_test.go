@@ -0,0 +1,231 @@
+package recordio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/recordio"
+)
+
+type entry struct {
+	Seq  uint64
+	Op   string
+	Args map[string]string
+}
+
+func entries() []entry {
+	return []entry{
+		{Seq: 1, Op: "put", Args: map[string]string{"k": "v"}},
+		{Seq: 2, Op: "del", Args: map[string]string{}},
+		{Seq: 3, Op: "put", Args: map[string]string{"k": "w"}},
+	}
+}
+
+// writeLog returns a log of entries and the offsets at which each record
+// ends.
+func writeLog(t *testing.T) ([]byte, []int64) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := recordio.NewWriter(&buf)
+	var ends []int64
+	for _, e := range entries() {
+		if err := w.Append(e); err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, int64(buf.Len()))
+	}
+	return buf.Bytes(), ends
+}
+
+func TestRoundTrip(t *testing.T) {
+	log, ends := writeLog(t)
+	r := recordio.NewReader(bytes.NewReader(log))
+	for i, want := range entries() {
+		var got entry
+		if err := r.Next(&got); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d: got %+v, want %+v", i, got, want)
+		}
+		if r.Offset() != ends[i] {
+			t.Errorf("record %d: offset %d, want %d", i, r.Offset(), ends[i])
+		}
+	}
+	if err := r.Next(new(entry)); err != io.EOF {
+		t.Errorf("reading past the last record gave %v, want io.EOF", err)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	var buf bytes.Buffer
+	if err := recordio.NewWriter(&buf).Append("ab"); err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := gensenc.Encode("ab")
+	want := binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))
+	want = binary.LittleEndian.AppendUint32(want, crc32.Checksum(payload, crc32.MakeTable(crc32.Castagnoli)))
+	want = append(want, payload...)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote %x, want %x", buf.Bytes(), want)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	log, ends := writeLog(t)
+	r := recordio.NewReader(bytes.NewReader(log))
+	if err := r.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Offset() != ends[0] {
+		t.Errorf("offset %d after skipping, want %d", r.Offset(), ends[0])
+	}
+	var got entry
+	if err := r.Next(&got); err != nil || got.Seq != 2 {
+		t.Errorf("read %+v, %v after skipping, want record 2", got, err)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	log, ends := writeLog(t)
+	flipped := bytes.Clone(log)
+	flipped[ends[1]-1] ^= 1
+	badCRC := bytes.Clone(log)
+	badCRC[ends[1]+4] ^= 1
+	huge := binary.LittleEndian.AppendUint32(bytes.Clone(log[:ends[2]]), recordio.MaxRecordSize+1)
+	huge = append(huge, 0, 0, 0, 0)
+	// A length right at the limit, with no payload after it, must not
+	// allocate the whole of it up front.
+	unwritten := binary.LittleEndian.AppendUint32(bytes.Clone(log[:ends[2]]), recordio.MaxRecordSize)
+	unwritten = append(unwritten, 0, 0, 0, 0, 1, 2, 3)
+	for _, tt := range []struct {
+		name string
+		log  []byte
+		want error
+		// good is the number of intact records before the damage.
+		good int
+	}{
+		{"half a header", log[:ends[0]+3], recordio.ErrTruncated, 1},
+		{"half a payload", log[:ends[2]-1], recordio.ErrTruncated, 2},
+		{"flipped payload bit", flipped, recordio.ErrCorrupt, 1},
+		{"flipped checksum bit", badCRC, recordio.ErrCorrupt, 2},
+		{"length past the limit", huge, recordio.ErrCorrupt, 3},
+		{"length past the input", unwritten, recordio.ErrTruncated, 3},
+	} {
+		r := recordio.NewReader(bytes.NewReader(tt.log))
+		var err error
+		var read int
+		for ; ; read++ {
+			if err = r.Next(new(entry)); err != nil {
+				break
+			}
+		}
+		if err != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		if read != tt.good || r.Offset() != ends[tt.good-1] {
+			t.Errorf("%s: read %d records up to %d, want %d up to %d", tt.name, read, r.Offset(), tt.good, ends[tt.good-1])
+		}
+	}
+}
+
+// TestNextDecodeError checks that an intact record that doesn't decode as
+// the value given reports the decoding error.
+func TestNextDecodeError(t *testing.T) {
+	var buf bytes.Buffer
+	if err := recordio.NewWriter(&buf).Append(uint8(1)); err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	err := recordio.NewReader(&buf).Next(&s)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("got %v, want a *gensenc.DecodeError", err)
+	}
+}
+
+func TestAppendErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := recordio.NewWriter(&buf).Append(make(chan int)); err == nil {
+		t.Error("appending a channel succeeded")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("a failed append wrote %x", buf.Bytes())
+	}
+	werr := errors.New("disk full")
+	if err := recordio.NewWriter(failingWriter{werr}).Append(1); err != werr {
+		t.Errorf("got %v, want the write error", err)
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestRecover(t *testing.T) {
+	log, ends := writeLog(t)
+	for _, tt := range []struct {
+		name string
+		log  []byte
+		size int64
+	}{
+		{"clean", log, ends[2]},
+		{"torn append", log[:ends[2]-2], ends[1]},
+		{"torn header", append(bytes.Clone(log), 1, 2), ends[2]},
+		{"empty", nil, 0},
+	} {
+		path := filepath.Join(t.TempDir(), "log")
+		if err := os.WriteFile(path, tt.log, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		size, err := recordio.Recover(path)
+		if err != nil || size != tt.size {
+			t.Errorf("%s: recovered %d, %v, want %d", tt.name, size, err, tt.size)
+			continue
+		}
+		b, _ := os.ReadFile(path)
+		if !bytes.Equal(b, log[:size]) {
+			t.Errorf("%s: left %x, want %x", tt.name, b, log[:size])
+		}
+		// Appending after recovery gives a readable log again.
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := recordio.NewWriter(f)
+		err = w.Append(entry{Seq: 9, Args: map[string]string{}})
+		if err == nil {
+			err = w.Sync()
+		}
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, _ = os.Open(path)
+		r := recordio.NewReader(f)
+		var e entry
+		for err = r.Next(&e); err == nil; err = r.Next(&e) {
+		}
+		f.Close()
+		if err != io.EOF || e.Seq != 9 {
+			t.Errorf("%s: reading after recovery ended with %v at %+v", tt.name, err, e)
+		}
+	}
+	if _, err := recordio.Recover(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("recovering a missing file gave %v", err)
+	}
+}
+
+func TestSyncUnsupported(t *testing.T) {
+	if err := recordio.NewWriter(io.Discard).Sync(); err != nil {
+		t.Errorf("syncing a writer without Sync gave %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package gensenc
+
+import (
+	"maps"
+	"reflect"
+)
+
+// redactCodec writes the zero value of fields tagged with redact in place
+// of their content, so secrets never leave the process while the wire
+// layout stays that of the field. String fields tagged with redact=text
+// are written as text instead. The remaining tag options of the field
+// apply to the value written.
+type redactCodec struct {
+	t reflect.Type
+	// inner, if not nil, is the codec of the remaining tag options.
+	inner       wireCodec
+	placeholder string
+}
+
+func newRedactCodec(t reflect.Type, opts tagOptions) wireCodec {
+	placeholder := opts["redact"]
+	if placeholder != "" && t.Kind() != reflect.String {
+		return invalidTag{}
+	}
+	rest := maps.Clone(opts)
+	delete(rest, "redact")
+	return redactCodec{t, newFieldCodec(t, rest), placeholder}
+}
+
+// redacted returns the value written in place of that of the field.
+func (c redactCodec) redacted() reflect.Value {
+	v := reflect.New(c.t).Elem()
+	if c.placeholder != "" {
+		v.SetString(c.placeholder)
+	}
+	return v
+}
+
+func (c redactCodec) encode(e *encodeState, _ reflect.Value) error {
+	v := c.redacted()
+	if c.inner != nil {
+		return c.inner.encode(e, v)
+	}
+	return e.encode(v)
+}
+
+func (c redactCodec) decode(d *decodeState, v reflect.Value) error {
+	if c.inner != nil {
+		return c.inner.decode(d, v)
+	}
+	return d.decode(v)
+}
+
+func (c redactCodec) skip(d *decodeState) error {
+	if c.inner != nil {
+		return c.inner.skip(d)
+	}
+	return d.skip(c.t)
+}
+
+func (c redactCodec) wireSize(visiting map[reflect.Type]bool) int {
+	if c.inner != nil {
+		return c.inner.wireSize(visiting)
+	}
+	// Sizes of fields with a codec are taken to hold with any options, so
+	// only claim one that WithArrayLengths and WithVarints don't change.
+	s := computeWireSize(c.t, visiting)
+	if s < 0 || computeHasArrays(c.t) || computeHasIntegers(c.t) {
+		return -1
+	}
+	return s
+}
+
+func (c redactCodec) writeFormat(e *encodeState, w FormatWriter, _ reflect.Value) error {
+	v := c.redacted()
+	if inner, ok := c.inner.(formatCodec); ok {
+		return inner.writeFormat(e, w, v)
+	}
+	return e.encodeFormat(w, v)
+}
+
+func (c redactCodec) readFormat(d *decodeState, r FormatReader, v reflect.Value) error {
+	if inner, ok := c.inner.(formatCodec); ok {
+		return inner.readFormat(d, r, v)
+	}
+	return d.decodeFormat(r, v)
+}
@@ -0,0 +1,76 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/msgpack"
+)
+
+type login struct {
+	User  string
+	Pass  string   `gensenc:"redact=***"`
+	Pin   int      `gensenc:"redact"`
+	Hist  []int64  `gensenc:"redact,delta"`
+	Key   *[2]byte `gensenc:"redact"`
+	After int
+}
+
+// loginDump is login as it is written.
+type loginDump struct {
+	User  string
+	Pass  string
+	Pin   int
+	Hist  []int64 `gensenc:"delta"`
+	Key   *[2]byte
+	After int
+}
+
+func TestRedact(t *testing.T) {
+	v := login{User: "u", Pass: "hunter2", Pin: 1234, Hist: []int64{1, 2}, Key: &[2]byte{0xab, 0xcd}, After: 7}
+	for _, c := range []*gensenc.Codec{gensenc.New(), gensenc.New(gensenc.WithVarints())} {
+		b, err := c.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := c.Encode(loginDump{User: "u", Pass: "***", After: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, want) {
+			t.Errorf("encoded as %x, want %x", b, want)
+		}
+		var got login
+		if err := c.Decode(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if w := (login{User: "u", Pass: "***", After: 7}); !reflect.DeepEqual(got, w) {
+			t.Errorf("got %+v, want %+v", got, w)
+		}
+	}
+	b, err := gensenc.EncodeFormat(msgpack.Format, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("hunter2")) || bytes.Contains(b, []byte{0xab, 0xcd}) {
+		t.Errorf("MessagePack output %x holds a secret", b)
+	}
+}
+
+func TestRedactInvalid(t *testing.T) {
+	_, err := gensenc.Encode(struct {
+		N int `gensenc:"redact=x"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("a placeholder for an int gave %v, want ErrInvalidTag", err)
+	}
+	_, err = gensenc.Encode(struct {
+		S string `gensenc:"redact,delta"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("redacting with an invalid option gave %v, want ErrInvalidTag", err)
+	}
+}
@@ -0,0 +1,145 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var (
+	ErrUnregisteredType error = errors.New("type not registered for interface value")
+	ErrUnknownType      error = errors.New("unknown type name")
+	ErrNotAssignable    error = errors.New("type not assignable to destination")
+)
+
+// registry maps the types registered for interface values to their names and
+// back.
+var registry = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{byName: map[string]reflect.Type{}, byType: map[reflect.Type]string{}}
+
+func init() {
+	for _, value := range []any{
+		false, "", []byte(nil),
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), complex64(0), complex128(0),
+		[]any(nil), map[string]any(nil),
+	} {
+		Register(value)
+	}
+}
+
+// RegisterName records the concrete type of value under name, so that values
+// of that type can be encoded in interface values, such as those of a
+// map[string]any. Interface values are written as the name of their type,
+// empty for nil, followed by the value. Booleans, strings, byte slices,
+// numbers, []any and map[string]any are registered by default.
+//
+// Like gob.RegisterName, it panics if the type or the name is already
+// registered differently.
+func RegisterName(name string, value any) {
+	if name == "" {
+		panic("gensenc: empty type name")
+	}
+	t := reflect.TypeOf(value)
+	registry.Lock()
+	defer registry.Unlock()
+	if other, ok := registry.byName[name]; ok && other != t {
+		panic("gensenc: registering duplicate types for " + name)
+	}
+	if other, ok := registry.byType[t]; ok && other != name {
+		panic("gensenc: registering duplicate names for " + t.String())
+	}
+	registry.byName[name] = t
+	registry.byType[t] = name
+}
+
+// Register is RegisterName with the name of value's type, qualified by its
+// package path if it is a named type.
+func Register(value any) {
+	t := reflect.TypeOf(value)
+	name := t.String()
+	if t.Name() != "" && t.PkgPath() != "" {
+		name = t.PkgPath() + "." + t.Name()
+	}
+	RegisterName(name, value)
+}
+
+func registeredName(t reflect.Type) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.byType[t]
+	return name, ok
+}
+
+func registeredType(name string) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.byName[name]
+	return t, ok
+}
+
+func (e *encodeState) encodeInterface(v reflect.Value) error {
+	if v.IsNil() {
+		e.writeString("")
+		return nil
+	}
+	elem := v.Elem()
+	name, ok := registeredName(elem.Type())
+	if !ok {
+		return ErrUnregisteredType
+	}
+	e.writeString(name)
+	c := reflect.New(elem.Type()).Elem()
+	c.Set(elem)
+	return e.encode(c)
+}
+
+// readType reads the name preceding an interface value and returns its
+// registered type, or nil for a nil value.
+func (d *decodeState) readType() (reflect.Type, error) {
+	name, err := d.readString()
+	if err != nil || name == "" {
+		return nil, err
+	}
+	t, ok := registeredType(name)
+	if !ok {
+		return nil, ErrUnknownType
+	}
+	return t, nil
+}
+
+func (d *decodeState) decodeInterface(v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	t, err := d.readType()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		v.SetZero()
+		return nil
+	}
+	if !t.AssignableTo(v.Type()) {
+		return ErrNotAssignable
+	}
+	elem := reflect.New(t).Elem()
+	err = d.decode(elem)
+	if err != nil {
+		return err
+	}
+	v.Set(elem)
+	return nil
+}
+
+func (d *decodeState) skipInterface() error {
+	t, err := d.readType()
+	if err != nil || t == nil {
+		return err
+	}
+	return d.skip(t)
+}
@@ -0,0 +1,99 @@
+package gensenc_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type vertex struct{ X, Y int }
+
+func (v vertex) String() string { return fmt.Sprint(v.X, v.Y) }
+
+func init() {
+	gensenc.Register(vertex{})
+	gensenc.RegisterName("vertexptr", &vertex{})
+}
+
+type config struct {
+	Name   string
+	Any    any
+	Values map[string]any
+	Shown  fmt.Stringer
+}
+
+func TestInterfaceValues(t *testing.T) {
+	m := map[string]any{
+		"int":    1,
+		"string": "x",
+		"list":   []any{true, 2.5, nil, []byte{1}},
+		"nested": map[string]any{"v": vertex{1, 2}},
+		"ptr":    &vertex{3, 4},
+		"nil":    nil,
+	}
+	for _, c := range []*gensenc.Codec{gensenc.New(), gensenc.New(gensenc.WithInterning()), gensenc.New(gensenc.WithVarints())} {
+		if got := roundTrip(t, c, m); !reflect.DeepEqual(got, m) {
+			t.Errorf("got %#v, want %#v", got, m)
+		}
+		v := config{Name: "n", Any: vertex{5, 6}, Values: m, Shown: &vertex{7, 8}}
+		if got := roundTrip(t, c, v); !reflect.DeepEqual(got, v) {
+			t.Errorf("got %#v, want %#v", got, v)
+		}
+	}
+	// Values are preceded by the name of their type.
+	b, err := gensenc.Encode(struct{ V any }{vertex{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "github.com/CodeSpoof/gogenericencoder_test.vertex"
+	want := string([]byte{byte(len(name)), 0, 0, 0, 0, 0, 0, 0}) + name + "\x01\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00"
+	if string(b) != want {
+		t.Errorf("encoded as %q, want %q", b, want)
+	}
+	if err := gensenc.Validate(b, reflect.TypeFor[struct{ V any }]()); err != nil {
+		t.Errorf("validating: %v", err)
+	}
+}
+
+func TestInterfaceValuesErrors(t *testing.T) {
+	type unregistered struct{}
+	_, err := gensenc.Encode(map[string]any{"u": unregistered{}})
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrUnregisteredType) || ee.Path != "[u]" {
+		t.Errorf("encoding an unregistered type gave %v, want ErrUnregisteredType at [u]", err)
+	}
+	b, err := gensenc.Encode(struct{ N string }{"nope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct{ V any }
+	if err := gensenc.Decode(b, &v); !errors.Is(err, gensenc.ErrUnknownType) {
+		t.Errorf("decoding an unknown type name gave %v, want ErrUnknownType", err)
+	}
+	// An int isn't a fmt.Stringer.
+	b, err = gensenc.Encode(struct{ V any }{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct{ V fmt.Stringer }
+	if err := gensenc.Decode(b, &s); !errors.Is(err, gensenc.ErrNotAssignable) {
+		t.Errorf("decoding an int into a fmt.Stringer gave %v, want ErrNotAssignable", err)
+	}
+	for _, register := range []func(){
+		func() { gensenc.RegisterName("vertexptr", vertex{}) },
+		func() { gensenc.RegisterName("other", vertex{}) },
+		func() { gensenc.RegisterName("", point{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("a conflicting registration didn't panic")
+				}
+			}()
+			register()
+		}()
+	}
+}
@@ -0,0 +1,172 @@
+package gensenc
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// rleCodec encodes slice fields tagged with rle as runs of equal elements.
+// The slice length is followed by the runs, each written as the number of
+// elements in it and then the element once. Elements are equal when they
+// encode to the same bytes. Decoding expands the runs, so the decoded
+// slice may be far larger than the input, up to maxExpanded bytes unless
+// WithMaxDecodedBytes sets another limit.
+type rleCodec struct {
+	t reflect.Type
+}
+
+// maxExpanded bounds the memory taken by the elements of slices decoded
+// from runs when no budget is set with WithMaxDecodedBytes, since a few
+// bytes of runs suffice to announce any number of elements.
+const maxExpanded = 1 << 30
+
+// chargeRuns accounts the n elements of size bytes a slice tagged rle
+// expands to, like charge, or against maxExpanded if there is no budget.
+func (d *decodeState) chargeRuns(n uint64, size uintptr) error {
+	if d.maxDecoded > 0 {
+		return d.charge(n, size)
+	}
+	if n > maxExpanded/uint64(max(size, 1)) {
+		return ErrLimitExceeded
+	}
+	return nil
+}
+
+func newRLECodec(t reflect.Type) wireCodec {
+	if t.Kind() != reflect.Slice {
+		return invalidTag{}
+	}
+	return rleCodec{t}
+}
+
+func (c rleCodec) encode(e *encodeState, v reflect.Value) error {
+	e.writeUint64(uint64(v.Len()))
+	// key encodes elements for comparison only, without string references
+	// that would make equal elements differ.
+	key := &encodeState{buf: bytes.NewBuffer(nil), options: e.options}
+	var prev []byte
+	start := 0
+	for i := 0; i <= v.Len(); i++ {
+		var cur []byte
+		if i < v.Len() {
+			key.buf.Reset()
+			err := key.encode(v.Index(i))
+			if err != nil {
+				return err
+			}
+			cur = key.buf.Bytes()
+			if i > start && bytes.Equal(cur, prev) {
+				continue
+			}
+		}
+		if i > start {
+			e.writeUint64(uint64(i - start))
+			err := e.encode(v.Index(start))
+			if err != nil {
+				return err
+			}
+			start = i
+		}
+		prev = append(prev[:0], cur...)
+	}
+	return nil
+}
+
+func (c rleCodec) decode(d *decodeState, v reflect.Value) error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	err = d.checkLength(length, 0)
+	if err == nil {
+		err = d.chargeRuns(length, c.t.Elem().Size())
+	}
+	if err != nil {
+		return err
+	}
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	v.Clear()
+	v.SetLen(0)
+	elem := c.t.Elem()
+	// The slice grows in bounded steps as runs are expanded, not by the
+	// length up front, which a few bytes suffice to make huge.
+	step := max(1, growStep/max(1, int(elem.Size())))
+	plain := d.options
+	plain.intern = false
+	for n := 0; n < int(length); {
+		run, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if run == 0 || run > length-uint64(n) {
+			return ErrMalformed
+		}
+		v.Grow(1)
+		v.SetLen(n + 1)
+		first := v.Index(n)
+		err = d.decode(first)
+		if err != nil {
+			return d.at(err, index(n))
+		}
+		// Elements with pointers are copied by decoding the encoding of the
+		// first again, so that they don't share memory.
+		var enc []byte
+		if run > 1 && hasPointers(elem) {
+			e := &encodeState{buf: bytes.NewBuffer(nil), options: plain}
+			err = e.encode(first)
+			if err != nil {
+				return err
+			}
+			enc = e.buf.Bytes()
+		}
+		for i := n + 1; i < n+int(run); i++ {
+			err = d.tick()
+			if err != nil {
+				return err
+			}
+			if i == v.Cap() {
+				v.Grow(min(step, n+int(run)-i))
+			}
+			v.SetLen(i + 1)
+			if enc == nil {
+				v.Index(i).Set(first)
+				continue
+			}
+			dc := &decodeState{b: enc, options: plain}
+			err = dc.decode(v.Index(i))
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+		n += int(run)
+	}
+	return nil
+}
+
+func (c rleCodec) skip(d *decodeState) error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	for length > 0 {
+		run, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if run == 0 || run > length {
+			return ErrMalformed
+		}
+		length -= run
+		err = d.skip(c.t.Elem())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rleCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
@@ -0,0 +1,96 @@
+package gensenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type runs struct {
+	Ints  []int      `gensenc:"rle"`
+	Names []string   `gensenc:"rle"`
+	Ptrs  []*float64 `gensenc:"rle"`
+}
+
+func TestRLERoundTrip(t *testing.T) {
+	x := 1.5
+	in := runs{
+		Ints:  []int{0, 0, 0, 7, 7, -1},
+		Names: []string{"a", "a", "b", "a"},
+		Ptrs:  []*float64{&x, &x, nil},
+	}
+	for _, c := range []*gensenc.Codec{gensenc.New(), gensenc.New(gensenc.WithInterning())} {
+		var got runs
+		b, err := c.Encode(in)
+		if err == nil {
+			err = c.Decode(b, &got)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, in) {
+			t.Errorf("got %+v, want %+v", got, in)
+		}
+		if got.Ptrs[0] == got.Ptrs[1] {
+			t.Error("elements of a run share memory")
+		}
+	}
+}
+
+// bomb returns the encoding of a runs value holding n zero integers in a
+// single run.
+func bomb(n uint64) []byte {
+	b := binary.LittleEndian.AppendUint64(nil, n)
+	b = binary.LittleEndian.AppendUint64(b, n)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	return binary.LittleEndian.AppendUint64(b, 0)
+}
+
+func TestRLEBomb(t *testing.T) {
+	var v runs
+	err := gensenc.Decode(bomb(1<<40), &v)
+	if !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decoding 2^40 elements gave %v, want ErrLimitExceeded", err)
+	}
+	err = gensenc.New(gensenc.WithMaxDecodedBytes(1<<10)).Decode(bomb(1<<10), &v)
+	if !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decoding past the budget gave %v, want ErrLimitExceeded", err)
+	}
+	err = gensenc.Decode(bomb(1<<10), &v)
+	if err != nil || len(v.Ints) != 1<<10 {
+		t.Errorf("decoding 1024 elements gave %d, %v", len(v.Ints), err)
+	}
+
+	_, err = gensenc.DecodeDynamic(bomb(1<<40), gensenc.DescribeType(reflect.TypeFor[runs]()))
+	if !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decoding 2^40 elements dynamically gave %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestRLEMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		b    []byte
+	}{
+		{"empty run", func() []byte {
+			b := bomb(2)
+			binary.LittleEndian.PutUint64(b[8:], 0)
+			return b
+		}()},
+		{"run too long", func() []byte {
+			b := bomb(2)
+			binary.LittleEndian.PutUint64(b[8:], 3)
+			return b
+		}()},
+	} {
+		var v runs
+		err := gensenc.Decode(tt.b, &v)
+		if !errors.Is(err, gensenc.ErrMalformed) {
+			t.Errorf("%s: got %v, want ErrMalformed", tt.name, err)
+		}
+	}
+}
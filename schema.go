@@ -0,0 +1,200 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+)
+
+var ErrInvalidSchema error = errors.New("invalid schema")
+
+// A Schema describes the wire layout of a Go type.
+type Schema struct {
+	Kind reflect.Kind
+	// Name is the package qualified name of named types.
+	Name string
+	// Fields lists the encoded fields of a struct in wire order.
+	Fields []SchemaField
+	// Elem describes the element type of slices and arrays, the value type
+	// of maps and the referenced type of pointers.
+	Elem *Schema
+	// Key describes the key type of maps.
+	Key *Schema
+	// Len is the length of arrays.
+	Len int
+	// Size is the wire size of every value of the type, or -1 if it
+	// depends on the value.
+	Size int
+	// Ref is set when the schema refers back to the enclosing schema of
+	// the same Name, as happens for recursive types. All other fields but
+	// Kind are left empty then.
+	Ref bool
+}
+
+type SchemaField struct {
+	Name string
+	// Tag is the field's gensenc struct tag.
+	Tag    string
+	Schema Schema
+}
+
+func typeName(t reflect.Type) string {
+	if t.Name() == "" {
+		return ""
+	}
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// DescribeType returns the schema of values of type t.
+func DescribeType(t reflect.Type) Schema {
+	return describe(t, map[reflect.Type]bool{})
+}
+
+func describe(t reflect.Type, visiting map[reflect.Type]bool) Schema {
+	s := Schema{Kind: t.Kind(), Name: typeName(t)}
+	if visiting[t] {
+		s.Ref = true
+		return s
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	s.Size = wireSize(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range infoOf(t).fields {
+			s.Fields = append(s.Fields, SchemaField{
+				Name:   f.name,
+				Tag:    t.Field(f.index).Tag.Get("gensenc"),
+				Schema: describe(f.typ, visiting),
+			})
+		}
+	case reflect.Array:
+		s.Len = t.Len()
+		fallthrough
+	case reflect.Slice, reflect.Pointer:
+		elem := describe(t.Elem(), visiting)
+		s.Elem = &elem
+	case reflect.Map:
+		key := describe(t.Key(), visiting)
+		elem := describe(t.Elem(), visiting)
+		s.Key, s.Elem = &key, &elem
+	}
+	return s
+}
+
+// schemaNode is the serialized form of one Schema in a preorder list of
+// nodes. Elem, Key and the field schemas refer to later nodes by their
+// index plus one, zero meaning none.
+type schemaNode struct {
+	Kind   uint64
+	Name   string
+	Fields []schemaFieldNode
+	Elem   uint64
+	Key    uint64
+	Len    int64
+	Size   int64
+	Ref    bool
+}
+
+type schemaFieldNode struct {
+	Name string
+	Tag  string
+	Node uint64
+}
+
+func (s *Schema) flatten(nodes []schemaNode) []schemaNode {
+	i := len(nodes)
+	nodes = append(nodes, schemaNode{
+		Kind: uint64(s.Kind),
+		Name: s.Name,
+		Len:  int64(s.Len),
+		Size: int64(s.Size),
+		Ref:  s.Ref,
+	})
+	for _, f := range s.Fields {
+		n := uint64(len(nodes) + 1)
+		nodes = f.Schema.flatten(nodes)
+		nodes[i].Fields = append(nodes[i].Fields, schemaFieldNode{Name: f.Name, Tag: f.Tag, Node: n})
+	}
+	if s.Elem != nil {
+		nodes[i].Elem = uint64(len(nodes) + 1)
+		nodes = s.Elem.flatten(nodes)
+	}
+	if s.Key != nil {
+		nodes[i].Key = uint64(len(nodes) + 1)
+		nodes = s.Key.flatten(nodes)
+	}
+	return nodes
+}
+
+// unflatten rebuilds the schema of node i. used marks the nodes referred
+// to so far: flatten refers to each node once, and rejecting repeated
+// references keeps crafted input from expanding into more schemas than it
+// has nodes. depth counts the enclosing schemas.
+func unflatten(nodes []schemaNode, i uint64, used []bool, depth int) (Schema, error) {
+	if depth >= defaultMaxDepth {
+		return Schema{}, ErrMaxDepth
+	}
+	n := nodes[i]
+	s := Schema{
+		Kind: reflect.Kind(n.Kind),
+		Name: n.Name,
+		Len:  int(n.Len),
+		Size: int(n.Size),
+		Ref:  n.Ref,
+	}
+	// Children always follow their parent, which rules out cycles.
+	child := func(ref uint64) (*Schema, error) {
+		if ref == 0 {
+			return nil, nil
+		}
+		if ref <= i+1 || ref > uint64(len(nodes)) || used[ref-1] {
+			return nil, ErrInvalidSchema
+		}
+		used[ref-1] = true
+		c, err := unflatten(nodes, ref-1, used, depth+1)
+		return &c, err
+	}
+	for _, f := range n.Fields {
+		c, err := child(f.Node)
+		if err != nil {
+			return Schema{}, err
+		}
+		if c == nil {
+			return Schema{}, ErrInvalidSchema
+		}
+		s.Fields = append(s.Fields, SchemaField{Name: f.Name, Tag: f.Tag, Schema: *c})
+	}
+	var err error
+	s.Elem, err = child(n.Elem)
+	if err != nil {
+		return Schema{}, err
+	}
+	s.Key, err = child(n.Key)
+	if err != nil {
+		return Schema{}, err
+	}
+	return s, nil
+}
+
+// MarshalBinary encodes the schema itself with gensenc.
+func (s Schema) MarshalBinary() ([]byte, error) {
+	return Encode(s.flatten(nil))
+}
+
+func (s *Schema) UnmarshalBinary(b []byte) error {
+	var nodes []schemaNode
+	err := Decode(b, &nodes)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return ErrInvalidSchema
+	}
+	used := make([]bool, len(nodes))
+	used[0] = true
+	*s, err = unflatten(nodes, 0, used, 0)
+	return err
+}
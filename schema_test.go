@@ -0,0 +1,125 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type tree struct {
+	Name     string `gensenc:"intern"`
+	Skipped  int    `gensenc:"-"`
+	Weights  [2]float32
+	Children []*tree
+	Attrs    map[string][]byte
+}
+
+func TestDescribeType(t *testing.T) {
+	s := gensenc.DescribeType(reflect.TypeFor[tree]())
+	name := "github.com/CodeSpoof/gogenericencoder_test.tree"
+	if s.Kind != reflect.Struct || s.Name != name || s.Size != -1 {
+		t.Fatalf("got %+v", s)
+	}
+	var fields []string
+	for _, f := range s.Fields {
+		fields = append(fields, f.Name)
+	}
+	if want := []string{"Name", "Weights", "Children", "Attrs"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("fields %v, want %v", fields, want)
+	}
+	if f := s.Fields[0]; f.Tag != "intern" || f.Schema.Kind != reflect.String {
+		t.Errorf("Name: %+v", f)
+	}
+	if w := s.Fields[1].Schema; w.Kind != reflect.Array || w.Len != 2 || w.Size != 8 || w.Elem.Kind != reflect.Float32 {
+		t.Errorf("Weights: %+v", w)
+	}
+	// The children refer back to tree rather than repeating it.
+	want := gensenc.Schema{Kind: reflect.Struct, Name: name, Ref: true}
+	if c := s.Fields[2].Schema; c.Kind != reflect.Slice || c.Elem.Kind != reflect.Pointer || !reflect.DeepEqual(*c.Elem.Elem, want) {
+		t.Errorf("Children: %+v", c)
+	}
+	if a := s.Fields[3].Schema; a.Kind != reflect.Map || a.Key.Kind != reflect.String || a.Elem.Kind != reflect.Slice || a.Elem.Elem.Kind != reflect.Uint8 {
+		t.Errorf("Attrs: %+v", a)
+	}
+}
+
+func TestSchemaBinary(t *testing.T) {
+	s := gensenc.DescribeType(reflect.TypeFor[tree]())
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got gensenc.Schema
+	err = got.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("got %+v, want %+v", got, s)
+	}
+	err = got.UnmarshalBinary(b[:len(b)-1])
+	if !errors.Is(err, gensenc.ErrTruncated) && !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a truncated schema gave %v", err)
+	}
+}
+
+// schemaNode mirrors the serialized form of a schema, to craft invalid ones.
+type schemaNode struct {
+	Kind   uint64
+	Name   string
+	Fields []schemaFieldNode
+	Elem   uint64
+	Key    uint64
+	Len    int64
+	Size   int64
+	Ref    bool
+}
+
+type schemaFieldNode struct {
+	Name string
+	Tag  string
+	Node uint64
+}
+
+func TestSchemaBinaryInvalid(t *testing.T) {
+	slice, i64 := uint64(reflect.Slice), uint64(reflect.Int64)
+	for _, tt := range []struct {
+		name  string
+		nodes []schemaNode
+	}{
+		{"no nodes", nil},
+		{"reference to itself", []schemaNode{{Kind: slice, Elem: 1}}},
+		{"reference past the end", []schemaNode{{Kind: slice, Elem: 3}, {Kind: i64}}},
+		{"repeated reference", []schemaNode{{Kind: uint64(reflect.Map), Elem: 2, Key: 2}, {Kind: i64}}},
+		{"field without a schema", []schemaNode{{Kind: uint64(reflect.Struct), Fields: []schemaFieldNode{{Name: "A"}}}}},
+	} {
+		b, err := gensenc.Encode(tt.nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s gensenc.Schema
+		err = s.UnmarshalBinary(b)
+		if !errors.Is(err, gensenc.ErrInvalidSchema) {
+			t.Errorf("%s: got %v, want ErrInvalidSchema", tt.name, err)
+		}
+	}
+}
+
+func TestSchemaBinaryDepth(t *testing.T) {
+	nodes := make([]schemaNode, 20000)
+	for i := range nodes {
+		nodes[i] = schemaNode{Kind: uint64(reflect.Pointer), Elem: uint64(i + 2)}
+	}
+	nodes[len(nodes)-1] = schemaNode{Kind: uint64(reflect.Int64)}
+	b, err := gensenc.Encode(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s gensenc.Schema
+	err = s.UnmarshalBinary(b)
+	if !errors.Is(err, gensenc.ErrMaxDepth) {
+		t.Errorf("got %v, want ErrMaxDepth", err)
+	}
+}
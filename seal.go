@@ -0,0 +1,53 @@
+package gensenc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+var ErrOpenFailed error = errors.New("cannot open sealed payload")
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncodeSealed encodes v and encrypts the result with AES-GCM under key,
+// which must be 16, 24 or 32 bytes long. A random nonce is generated for
+// every call and prepended to the ciphertext.
+func EncodeSealed(key []byte, v any) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(b)+aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, b, nil), nil
+}
+
+func DecodeSealed(key, b []byte, v any) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if len(b) < aead.NonceSize() {
+		return ErrOpenFailed
+	}
+	payload, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return ErrOpenFailed
+	}
+	return Decode(payload, v)
+}
@@ -0,0 +1,68 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestSealed(t *testing.T) {
+	v := order{ID: 9, Items: []string{"secret"}, Tags: map[string]int{"k": 1}}
+	for _, size := range []int{16, 24, 32} {
+		key := bytes.Repeat([]byte{byte(size)}, size)
+		b, err := gensenc.EncodeSealed(key, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(b, []byte("secret")) {
+			t.Errorf("%d byte key: sealed payload holds the plaintext", size)
+		}
+		again, err := gensenc.EncodeSealed(key, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(b, again) {
+			t.Errorf("%d byte key: sealing twice gave the same bytes, so the nonce is not random", size)
+		}
+		var got order
+		err = gensenc.DecodeSealed(key, b, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("%d byte key: got %+v, want %+v", size, got, v)
+		}
+	}
+}
+
+func TestSealedErrors(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	b, err := gensenc.EncodeSealed(key, order{ID: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got order
+	wrong := bytes.Repeat([]byte{2}, 32)
+	if err := gensenc.DecodeSealed(wrong, b, &got); !errors.Is(err, gensenc.ErrOpenFailed) {
+		t.Errorf("opening with the wrong key gave %v, want ErrOpenFailed", err)
+	}
+	for i := range b {
+		tampered := append([]byte(nil), b...)
+		tampered[i] ^= 1
+		if err := gensenc.DecodeSealed(key, tampered, &got); !errors.Is(err, gensenc.ErrOpenFailed) {
+			t.Fatalf("changing byte %d gave %v, want ErrOpenFailed", i, err)
+		}
+	}
+	if err := gensenc.DecodeSealed(key, b[:5], &got); !errors.Is(err, gensenc.ErrOpenFailed) {
+		t.Errorf("opening a short payload gave %v, want ErrOpenFailed", err)
+	}
+	if _, err := gensenc.EncodeSealed(key[:10], order{}); err == nil {
+		t.Error("sealing with a 10 byte key succeeded")
+	}
+	if err := gensenc.DecodeSealed(key[:10], b, &got); err == nil {
+		t.Error("opening with a 10 byte key succeeded")
+	}
+}
@@ -0,0 +1,44 @@
+package gensenc
+
+// WithSecrets adapts encoding and decoding to values holding keys, tokens
+// and other credentials.
+//
+// Buffers a Codec, Encoder or Decoder allocates or reuses while encoding or
+// decoding a value are cleared once it is done, so secrets don't linger in
+// pooled memory. Memory a buffer left behind while growing and the buffers
+// of readers and writers, including the bufio.Reader a Decoder may wrap its
+// reader in, are out of its reach; Encoder and Decoder reuse theirs.
+//
+// Decoding doesn't branch on the bytes of strings, and on integers, floats
+// and bools only to reject values out of range for their destination.
+// Lengths, presence bytes, varints, string references and the checks of
+// WithStrict branch on the input as usual, so secrets are best kept in
+// fixed-size fields or in strings of a length that is not itself secret.
+func WithSecrets() Option {
+	return func(o *options) { o.secrets = true }
+}
+
+// keep records b for scrub if secrets are enabled.
+func (d *decodeState) keep(b []byte) {
+	if d.secrets {
+		d.scratch = append(d.scratch, b)
+	}
+}
+
+// scrub clears the buffers used while decoding a value.
+func (d *decodeState) scrub() {
+	clear(d.l[:])
+	clear(d.region)
+	for _, b := range d.scratch {
+		clear(b)
+	}
+	clear(d.scratch)
+	d.scratch = d.scratch[:0]
+}
+
+// scrub clears the buffers used while encoding a value.
+func (e *encodeState) scrub() {
+	b := e.buf.Bytes()
+	clear(b[:cap(b)])
+	clear(e.l[:])
+}
@@ -0,0 +1,101 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type credential struct {
+	User  string
+	Token [4]uint64
+	Key   string
+}
+
+// keeper is a writer and byte reader that, against the io contracts,
+// keeps the buffers it is handed, to see what is left in them afterwards.
+type keeper struct {
+	r    *bytes.Reader
+	kept [][]byte
+}
+
+func (k *keeper) Write(p []byte) (int, error) {
+	k.kept = append(k.kept, p)
+	return len(p), nil
+}
+
+func (k *keeper) Read(p []byte) (int, error) {
+	k.kept = append(k.kept, p)
+	return k.r.Read(p)
+}
+
+func (k *keeper) ReadByte() (byte, error) { return k.r.ReadByte() }
+
+func (k *keeper) UnreadByte() error { return k.r.UnreadByte() }
+
+func (k *keeper) cleared() bool {
+	for _, b := range k.kept {
+		if !bytes.Equal(b, make([]byte, len(b))) {
+			return false
+		}
+	}
+	return len(k.kept) > 0
+}
+
+func TestSecrets(t *testing.T) {
+	c := gensenc.New(gensenc.WithSecrets())
+	v := credential{User: "u", Token: [4]uint64{1, 2, 3, 4}, Key: "supersecretkey"}
+	b, err := c.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, plain) {
+		t.Errorf("encoded as %x, want the usual %x", b, plain)
+	}
+	// The pooled buffer the encoding was copied from is cleared, not the
+	// encoding returned.
+	again, err := c.Encode(v)
+	if err != nil || !bytes.Equal(again, b) {
+		t.Errorf("encoding again gave %x, %v", again, err)
+	}
+	var got credential
+	if err := c.Decode(b, &got); err != nil || got != v {
+		t.Errorf("got %+v, %v, want %+v", got, err, v)
+	}
+
+	// Encoders clear the buffer they wrote from after each value.
+	w := &keeper{}
+	enc := c.NewEncoder(w)
+	for range 2 {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !w.cleared() {
+		t.Error("an Encoder left the encoding in its buffer")
+	}
+	// Decoders clear the buffers they read lengths and strings into.
+	// Fixed-size values like Token are read into their memory directly.
+	type secret struct{ User, Key string }
+	sb, err := gensenc.Encode(secret{v.User, v.Key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &keeper{r: bytes.NewReader(sb)}
+	var s secret
+	if err := c.NewDecoder(r).Decode(&s); err != nil || s != (secret{v.User, v.Key}) {
+		t.Fatalf("got %+v, %v", s, err)
+	}
+	if !r.cleared() {
+		t.Error("a Decoder left the input in its buffer")
+	}
+	if err := c.NewDecoder(r).Decode(&s); err != io.EOF {
+		t.Errorf("decoding past the end gave %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,260 @@
+package gensenc
+
+import (
+	"reflect"
+)
+
+// section returns the name of the section of a field with options o: the
+// value of the section option, or "body" for fields tagged body.
+func (o tagOptions) section() string {
+	if o.has("body") {
+		return "body"
+	}
+	return o["section"]
+}
+
+// A fieldRun is a run of consecutive struct fields in the same section, or
+// a single field outside of any.
+type fieldRun struct {
+	section string
+	fields  []fieldInfo
+}
+
+// sectionCodec encodes structs with fields tagged section=name. Each run of
+// consecutive fields with the same section name is written behind a uint64
+// length prefix, so that decoders can skip the section whole. Decoders zero
+// the fields missing at the end of a shorter section and skip the unknown
+// ones at the end of a longer one, which lets sections grow at the end.
+// Strings in sections are never interned, as skipping them would lose the
+// strings later references point to. The body tag is short for
+// section=body, the section DecodeHeader skips.
+type sectionCodec struct {
+	runs []fieldRun
+}
+
+func fieldRuns(fields []fieldInfo) []fieldRun {
+	var runs []fieldRun
+	for _, f := range fields {
+		s := f.opts.section()
+		if n := len(runs); n > 0 && s != "" && runs[n-1].section == s {
+			runs[n-1].fields = append(runs[n-1].fields, f)
+		} else {
+			runs = append(runs, fieldRun{s, []fieldInfo{f}})
+		}
+	}
+	return runs
+}
+
+// sectionCount returns the number of sections of the struct type t.
+func sectionCount(t reflect.Type) int {
+	n, last := 0, ""
+	fields, _ := wireFields(t)
+	for _, f := range fields {
+		s := parseTag(f.Tag.Get("gensenc")).section()
+		if s != "" && s != last {
+			n++
+		}
+		last = s
+	}
+	return n
+}
+
+// newSectionCodec returns the codec of a struct with the given fields, or
+// nil if none of them is in a section.
+func newSectionCodec(fields []fieldInfo) wireCodec {
+	runs := fieldRuns(fields)
+	for _, r := range runs {
+		if r.section != "" {
+			return &sectionCodec{runs}
+		}
+	}
+	return nil
+}
+
+func (c *sectionCodec) encode(e *encodeState, v reflect.Value) error {
+	for _, r := range c.runs {
+		if r.section == "" {
+			err := e.encodeFields(r.fields, v)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		start := e.buf.Len()
+		e.writeUint64(0)
+		strings := e.strings
+		e.strings = nil
+		e.pinned++
+		err := e.encodeFields(r.fields, v)
+		e.pinned--
+		e.strings = strings
+		if err != nil {
+			return err
+		}
+		e.order().PutUint64(e.buf.Bytes()[start:], uint64(e.buf.Len()-start-8))
+	}
+	return nil
+}
+
+func (c *sectionCodec) decode(d *decodeState, v reflect.Value) error {
+	for _, r := range c.runs {
+		var err error
+		if r.section == "" {
+			err = d.decodeFields(r.fields, v)
+		} else {
+			err = d.decodeSection(r.fields, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSection reads the section of the given fields, calling fn with each
+// field on the wire to read or skip it until fn returns true, and returns
+// the fields missing from the end of the section. The rest of the section is
+// skipped unless fn stopped early.
+func (d *decodeState) readSection(fields []fieldInfo, fn func(f *fieldInfo) (bool, error)) ([]fieldInfo, error) {
+	length, err := d.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	err = d.checkLength(length, 1)
+	if err != nil {
+		return nil, err
+	}
+	end := d.offset() + int64(length)
+	intern := d.intern
+	d.intern = false
+	defer func() { d.intern = intern }()
+	for i := range fields {
+		if d.offset() >= end {
+			return fields[i:], nil
+		}
+		stop, err := fn(&fields[i])
+		if err != nil || stop {
+			return nil, err
+		}
+		if d.offset() > end {
+			return nil, d.at(ErrMalformed, "."+fields[i].name)
+		}
+	}
+	return nil, d.discard(uint64(end - d.offset()))
+}
+
+func (d *decodeState) decodeSection(fields []fieldInfo, v reflect.Value) error {
+	missing, err := d.readSection(fields, func(f *fieldInfo) (bool, error) {
+		return false, d.decodeStructField(f, v)
+	})
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	for _, f := range missing {
+		v.Field(f.index).SetZero()
+	}
+	return nil
+}
+
+// skip skips the fields of sections one by one, like those outside of any,
+// so that Validate checks them too.
+func (c *sectionCodec) skip(d *decodeState) error {
+	skip := func(f *fieldInfo) (bool, error) {
+		err := d.skipField(f)
+		if err != nil {
+			return false, d.at(err, "."+f.name)
+		}
+		return false, nil
+	}
+	for _, r := range c.runs {
+		if r.section != "" {
+			_, err := d.readSection(r.fields, skip)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := skip(&r.fields[0])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipRuns skips the given runs, sections whole by their length prefix.
+func (d *decodeState) skipRuns(runs []fieldRun) error {
+	for _, r := range runs {
+		if r.section != "" {
+			err := d.skipSection()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := d.skipField(&r.fields[0])
+		if err != nil {
+			return d.at(err, "."+r.fields[0].name)
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) skipSection() error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	err = d.checkLength(length, 1)
+	if err != nil {
+		return err
+	}
+	return d.discard(length)
+}
+
+// wireSize is unused, as computeWireSize accounts for sections itself.
+func (c *sectionCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
+
+// DecodeHeader decodes the header of the struct value at the start of b into
+// the struct a points to, the fields preceding its body, and returns the
+// length of the whole value. The body, the fields tagged body, is skipped by
+// its length prefix, as are the fields after it, so that records can be
+// scanned by their header without decoding the rest.
+func DecodeHeader(b []byte, a any) (int, error) {
+	v := reflect.ValueOf(a)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, ErrNotStruct
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	err := d.guard(func() error {
+		info := infoOf(v.Type())
+		if _, ok := info.codec.(*sectionCodec); info.codec != nil && !ok {
+			return d.decode(v)
+		}
+		runs := fieldRuns(info.fields)
+		for i, r := range runs {
+			if r.section == "body" {
+				return d.skipRuns(runs[i:])
+			}
+			var err error
+			if r.section == "" {
+				err = d.decodeStructField(&r.fields[0], v)
+			} else {
+				err = d.decodeSection(r.fields, v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return d.off, err
+}
@@ -0,0 +1,169 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type (
+	sectionOld struct {
+		A int
+		B string `gensenc:"section=meta"`
+		C int    `gensenc:"section=meta"`
+		D string
+	}
+	sectionNew struct {
+		A int
+		B string `gensenc:"section=meta"`
+		C int    `gensenc:"section=meta"`
+		E []int  `gensenc:"section=meta"`
+		D string
+	}
+)
+
+func TestSections(t *testing.T) {
+	old := sectionOld{1, "b", 3, "d"}
+	b, err := gensenc.Encode(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.LittleEndian.Uint64(b[8:]); n != 8+1+8 {
+		t.Errorf("section length is %d, want 17", n)
+	}
+	// Fields added at the end of a section decode as zero from old values,
+	// and old decoders skip them.
+	var n sectionNew
+	if err := gensenc.Decode(b, &n); err != nil {
+		t.Fatal(err)
+	}
+	if want := (sectionNew{1, "b", 3, nil, "d"}); !reflect.DeepEqual(n, want) {
+		t.Errorf("got %+v, want %+v", n, want)
+	}
+	b, err = gensenc.Encode(sectionNew{1, "b", 3, []int{7, 8}, "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o sectionOld
+	if err := gensenc.Decode(b, &o); err != nil || o != old {
+		t.Errorf("got %+v, %v, want %+v", o, err, old)
+	}
+	o = sectionOld{}
+	if err := gensenc.NewDecoder(bytes.NewReader(b)).Decode(&o); err != nil || o != old {
+		t.Errorf("decoding from a stream gave %+v, %v, want %+v", o, err, old)
+	}
+	if err := gensenc.Validate(b, reflect.TypeFor[sectionOld]()); err != nil {
+		t.Error(err)
+	}
+
+	// Strings aren't interned across sections.
+	c := gensenc.New(gensenc.WithInterning())
+	b, err = c.Encode([]sectionNew{{1, "x", 3, nil, "x"}, {1, "x", 3, nil, "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []sectionOld
+	if err := c.Decode(b, &s); err != nil || s[1] != (sectionOld{1, "x", 3, "x"}) {
+		t.Errorf("got %+v, %v", s, err)
+	}
+
+	found, err := gensenc.CheckCompatible(gensenc.DescribeType(reflect.TypeFor[sectionOld]()), gensenc.DescribeType(reflect.TypeFor[sectionNew]()))
+	if err != nil || len(found) != 0 {
+		t.Errorf("growing a section is incompatible: %v, %v", found, err)
+	}
+}
+
+func TestSectionsDamaged(t *testing.T) {
+	b, err := gensenc.Encode(sectionOld{1, "b", 3, "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := bytes.Clone(b)
+	binary.LittleEndian.PutUint64(short[8:], 2)
+	var o sectionOld
+	err = gensenc.Decode(short, &o)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrMalformed) || de.Path != "B" {
+		t.Errorf("decoding a field past its section gave %v, want ErrMalformed at B", err)
+	}
+	long := bytes.Clone(b)
+	binary.LittleEndian.PutUint64(long[8:], 1<<20)
+	if err := gensenc.Decode(long, &o); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("decoding a section longer than the input gave %v, want ErrInvalidLength", err)
+	}
+	if err := gensenc.Validate(short, reflect.TypeFor[sectionOld]()); !errors.Is(err, gensenc.ErrMalformed) {
+		t.Errorf("validating a field past its section gave %v, want ErrMalformed", err)
+	}
+}
+
+type scanRecord struct {
+	Key   string
+	TS    int64
+	Body  []byte `gensenc:"body"`
+	Meta  string `gensenc:"body"`
+	After int64
+}
+
+func TestDecodeHeader(t *testing.T) {
+	var all []byte
+	for i := range 3 {
+		b, err := gensenc.Encode(scanRecord{"k", int64(i), []byte("payload"), "m", 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, b...)
+	}
+	off := 0
+	for i := range 3 {
+		var r scanRecord
+		n, err := gensenc.DecodeHeader(all[off:], &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (scanRecord{Key: "k", TS: int64(i)}); !reflect.DeepEqual(r, want) {
+			t.Errorf("record %d: got %+v, want the header alone", i, r)
+		}
+		off += n
+	}
+	if off != len(all) {
+		t.Errorf("scanned %d of %d bytes", off, len(all))
+	}
+	var full scanRecord
+	if err := gensenc.Decode(all, &full); err != nil || full.Meta != "m" || full.After != 7 {
+		t.Errorf("got %+v, %v", full, err)
+	}
+
+	// Values without a body are decoded whole.
+	var p point
+	b, _ := gensenc.Encode(point{1, 2})
+	if n, err := gensenc.DecodeHeader(b, &p); err != nil || n != len(b) || p != (point{1, 2}) {
+		t.Errorf("got %v after %d bytes, %v", p, n, err)
+	}
+}
+
+func TestDecodeHeaderErrors(t *testing.T) {
+	b, err := gensenc.Encode(scanRecord{"k", 1, []byte("payload"), "m", 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r scanRecord
+	if _, err := gensenc.DecodeHeader(b, new(int)); !errors.Is(err, gensenc.ErrNotStruct) {
+		t.Errorf("decoding into an int gave %v, want ErrNotStruct", err)
+	}
+	if _, err := gensenc.DecodeHeader(b[:12], &r); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a truncated header gave %v, want ErrTruncated", err)
+	}
+	// The body is skipped by its length, which must fit the input.
+	long := bytes.Clone(b)
+	binary.LittleEndian.PutUint64(long[8+1+8:], 1<<20)
+	if _, err := gensenc.DecodeHeader(long, &r); !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Errorf("skipping a body longer than the input gave %v, want ErrInvalidLength", err)
+	}
+	if _, err := gensenc.DecodeHeader(b[:len(b)-1], &r); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("skipping a truncated field after the body gave %v, want ErrTruncated", err)
+	}
+}
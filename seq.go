@@ -0,0 +1,94 @@
+package gensenc
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+)
+
+var ErrInvalidMarker error = errors.New("invalid sequence marker")
+
+// EncodeSeq writes every element of seq to w, each preceded by a one byte
+// marker, followed by a terminating zero byte. Elements are written as
+// they are produced, so the length of seq need not be known, and like
+// those of a slice, so nil pointers among them decode as nil.
+func EncodeSeq[T any](w io.Writer, seq iter.Seq[T]) error {
+	e := defaultCodec.getState()
+	defer defaultCodec.putState(e)
+	for v := range seq {
+		e.buf.Reset()
+		e.buf.WriteByte(1)
+		err := e.encodeElem(reflect.ValueOf(&v).Elem())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(e.buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0})
+	return err
+}
+
+// DecodeSeq returns an iterator over the elements written by EncodeSeq.
+// Iteration stops after the first error, which is yielded with the zero
+// value.
+func DecodeSeq[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		d := defaultCodec.newDecodeState(nil, r)
+		for {
+			var v T
+			_, err := io.ReadFull(r, d.l[:1])
+			if err != nil {
+				yield(v, err)
+				return
+			}
+			switch d.l[0] {
+			case 0:
+				return
+			case 1:
+			default:
+				yield(v, ErrInvalidMarker)
+				return
+			}
+			err = d.decodeElem(reflect.ValueOf(&v).Elem())
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DecodeSlice reads a slice of elements of type elemType from r, as encoded
+// by Encode, and calls fn with each element as it is decoded instead of
+// collecting them, so that collections larger than memory can be processed
+// one element at a time. Every element is decoded into a new value, which
+// fn may keep. An error from fn stops decoding and is returned as is.
+func DecodeSlice(r io.Reader, elemType reflect.Type, fn func(reflect.Value) error) error {
+	d := defaultCodec.newDecodeState(nil, r)
+	var fnErr error
+	err := d.guard(func() error {
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		for i := range length {
+			v := reflect.New(elemType).Elem()
+			err = d.decode(v)
+			if err != nil {
+				return d.at(err, index(int(i)))
+			}
+			fnErr = fn(v)
+			if fnErr != nil {
+				return nil
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestSeqRoundTrip(t *testing.T) {
+	one, two := 1, 2
+	in := []*int{&one, nil, &two}
+	var buf bytes.Buffer
+	err := gensenc.EncodeSeq(&buf, slices.Values(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*int
+	for v, err := range gensenc.DecodeSeq[*int](&buf) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("got %v, want %v", got, in)
+	}
+}
+
+func TestSeqErrors(t *testing.T) {
+	var buf bytes.Buffer
+	err := gensenc.EncodeSeq(&buf, slices.Values([]string{"a", "b"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	for _, tt := range []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{"bad marker", append([]byte{2}, b[1:]...), gensenc.ErrInvalidMarker},
+		{"truncated element", b[:5], gensenc.ErrTruncated},
+	} {
+		var err error
+		for _, err = range gensenc.DecodeSeq[string](bytes.NewReader(tt.in)) {
+			if err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestChunkedRoundTrip(t *testing.T) {
+	one, two := 1, 2
+	in := []*int{&one, nil, &two, nil, nil}
+	var buf bytes.Buffer
+	w := gensenc.NewChunkWriter(&buf, 2)
+	for _, v := range in {
+		err := w.Append(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*int
+	err = gensenc.DecodeChunked(&buf, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("got %v, want %v", got, in)
+	}
+}
+
+func TestChunkedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := gensenc.NewChunkWriter(&buf, 10)
+	for _, s := range []string{"a", "b", "c"} {
+		err := w.Append(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	var got []string
+	err = gensenc.DecodeChunked(bytes.NewReader(b[:len(b)-12]), &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTruncated) {
+		t.Fatalf("decoding truncated segments gave %v, want ErrTruncated", err)
+	}
+	if de.Path != "[2]" {
+		t.Errorf("error at %q, want [2]", de.Path)
+	}
+	err = gensenc.DecodeChunked(bytes.NewReader(b), got)
+	if !errors.Is(err, gensenc.ErrCantSet) {
+		t.Errorf("decoding into a slice gave %v, want ErrCantSet", err)
+	}
+}
+
+func TestDecodeSlice(t *testing.T) {
+	in := []order{{ID: 1, Items: []string{"a"}}, {ID: 2}, {ID: 3, Tags: map[string]int{"x": 1}}}
+	b, err := gensenc.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Elements are new values, so keeping them is safe.
+	var got []*order
+	err = gensenc.DecodeSlice(bytes.NewReader(b), reflect.TypeFor[order](), func(v reflect.Value) error {
+		got = append(got, v.Addr().Interface().(*order))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].ID != 1 || got[0].Items[0] != "a" || got[2].Tags["x"] != 1 {
+		t.Errorf("got %+v, %+v, %+v", got[0], got[1], got[2])
+	}
+}
+
+func TestDecodeSliceErrors(t *testing.T) {
+	b, err := gensenc.Encode([]order{{ID: 1}, {ID: 2}, {ID: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	n := 0
+	err = gensenc.DecodeSlice(bytes.NewReader(b), reflect.TypeFor[order](), func(reflect.Value) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("got %v after %d elements, want the callback's error after 1", err, n)
+	}
+
+	n = 0
+	err = gensenc.DecodeSlice(bytes.NewReader(b[:len(b)-3]), reflect.TypeFor[order](), func(reflect.Value) error {
+		n++
+		return nil
+	})
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTruncated) || de.Path != "[2].Tags" || n != 2 {
+		t.Errorf("got %v after %d elements, want ErrTruncated at [2].Tags after 2", err, n)
+	}
+	b, _ = gensenc.Encode([]int{1, 300})
+	err = gensenc.DecodeSlice(bytes.NewReader(b), reflect.TypeFor[int8](), func(reflect.Value) error { return nil })
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrOverflow) || de.Path != "[1]" {
+		t.Errorf("got %v, want ErrOverflow at [1]", err)
+	}
+}
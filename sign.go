@@ -0,0 +1,36 @@
+package gensenc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrSignatureMismatch error = errors.New("signature mismatch")
+
+func sign(key, b []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// EncodeSigned encodes a and appends an HMAC-SHA256 of the encoded bytes
+// keyed with key.
+func EncodeSigned(key []byte, a any) ([]byte, error) {
+	b, err := Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, sign(key, b)...), nil
+}
+
+func DecodeSigned(key, b []byte, a any) error {
+	if len(b) < sha256.Size {
+		return ErrSignatureMismatch
+	}
+	payload := b[:len(b)-sha256.Size]
+	if !hmac.Equal(sign(key, payload), b[len(payload):]) {
+		return ErrSignatureMismatch
+	}
+	return Decode(payload, a)
+}
@@ -0,0 +1,60 @@
+package gensenc_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestSigned(t *testing.T) {
+	key := []byte("secret key")
+	v := order{ID: 3, Items: []string{"a"}, Tags: map[string]int{"k": 1}}
+	b, err := gensenc.EncodeSigned(key, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(plain)
+	if want := append(plain, mac.Sum(nil)...); string(b) != string(want) {
+		t.Errorf("encoded as %x, want the encoding followed by its HMAC-SHA256 %x", b, want)
+	}
+	var got order
+	err = gensenc.DecodeSigned(key, b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+}
+
+func TestSignedTampered(t *testing.T) {
+	key := []byte("secret key")
+	b, err := gensenc.EncodeSigned(key, order{ID: 3, Items: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b {
+		tampered := append([]byte(nil), b...)
+		tampered[i]++
+		var got order
+		if err := gensenc.DecodeSigned(key, tampered, &got); !errors.Is(err, gensenc.ErrSignatureMismatch) {
+			t.Fatalf("changing byte %d gave %v, want ErrSignatureMismatch", i, err)
+		}
+	}
+	var got order
+	if err := gensenc.DecodeSigned([]byte("other key"), b, &got); !errors.Is(err, gensenc.ErrSignatureMismatch) {
+		t.Errorf("decoding with another key gave %v, want ErrSignatureMismatch", err)
+	}
+	if err := gensenc.DecodeSigned(key, b[:sha256.Size-1], &got); !errors.Is(err, gensenc.ErrSignatureMismatch) {
+		t.Errorf("decoding a short input gave %v, want ErrSignatureMismatch", err)
+	}
+}
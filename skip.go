@@ -0,0 +1,237 @@
+package gensenc
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+)
+
+var (
+	ErrNotStruct     error = errors.New("not a struct")
+	ErrUnknownField  error = errors.New("unknown field")
+	ErrCantSkip      error = errors.New("cannot skip type")
+	ErrTrailingBytes error = errors.New("trailing bytes after value")
+)
+
+func (d *decodeState) discard(n uint64) error {
+	if d.r == nil {
+		_, err := d.take(n)
+		if err == io.EOF && n > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	m, err := io.CopyN(io.Discard, d.r, int64(n))
+	d.n += m
+	if m < int64(n) && err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (d *decodeState) skipField(f *fieldInfo) error {
+	if f.codec != nil {
+		return f.codec.skip(d)
+	}
+	return d.skip(f.typ)
+}
+
+func (d *decodeState) skipType(t reflect.Type) error {
+	info := infoOf(t)
+	if d.named && t.Kind() == reflect.Struct && namedStruct(info) {
+		return d.skipNamed()
+	}
+	if info.codec != nil {
+		return info.codec.skip(d)
+	}
+	if s := d.size(info); s >= 0 {
+		return d.discard(uint64(s))
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if d.intern {
+			_, err := d.readString()
+			return err
+		}
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		err = d.checkLength(length, 1)
+		if err != nil {
+			return err
+		}
+		return d.discard(length)
+	case reflect.Struct:
+		for _, f := range infoOf(t).fields {
+			err := d.skipField(&f)
+			if err != nil {
+				return d.at(err, "."+f.name)
+			}
+		}
+	case reflect.Slice:
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		err = d.checkLength(length, d.minSize(t.Elem()))
+		if err != nil {
+			return err
+		}
+		if s := d.size(infoOf(t.Elem())); s >= 0 {
+			return d.discard(length * uint64(s))
+		}
+		if d.columnar && columnar(t.Elem()) {
+			for _, f := range infoOf(t.Elem()).fields {
+				for i := range length {
+					err = d.skipField(&f)
+					if err != nil {
+						return d.at(err, index(int(i))+"."+f.name)
+					}
+				}
+			}
+			return nil
+		}
+		for i := range length {
+			err = d.skip(t.Elem())
+			if err != nil {
+				return d.at(err, index(int(i)))
+			}
+		}
+	case reflect.Array:
+		if d.arrayLens {
+			return d.skipArrayLen(t)
+		}
+		for i := range t.Len() {
+			err := d.skip(t.Elem())
+			if err != nil {
+				return d.at(err, index(i))
+			}
+		}
+	case reflect.Map:
+		length, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		size := d.minSize(t.Key()) + d.minSize(t.Elem())
+		err = d.checkLength(length, size)
+		if err != nil || size == 0 {
+			return err
+		}
+		for i := range length {
+			err = d.skip(t.Key())
+			if err == nil {
+				err = d.skip(t.Elem())
+			}
+			if err != nil {
+				return d.at(err, index(int(i)))
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// Only varints vary in size.
+		_, err := d.readUvarint()
+		return err
+	case reflect.Pointer:
+		present, err := d.readPresence()
+		if err != nil || !present {
+			return err
+		}
+		return d.skip(t.Elem())
+	case reflect.Interface:
+		return d.skipInterface()
+	case reflect.Uintptr, reflect.UnsafePointer:
+		return ErrAddress
+	default:
+		return ErrCantSkip
+	}
+	return nil
+}
+
+// DecodeFields decodes only the named top-level fields of the struct a
+// points to, skipping over all other fields, whole sections without any
+// requested ones, and everything after the last requested one.
+func DecodeFields(b []byte, a any, fields ...string) error {
+	v := reflect.ValueOf(a)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	want := map[string]bool{}
+	for _, name := range fields {
+		f, ok := v.Type().FieldByName(name)
+		if !ok || len(f.Index) != 1 || !encoded(f) {
+			return ErrUnknownField
+		}
+		want[name] = true
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	return d.guard(func() error {
+		visit := func(f *fieldInfo) (bool, error) {
+			var err error
+			if want[f.name] {
+				err = d.decodeField(f, v.Field(f.index))
+				delete(want, f.name)
+			} else {
+				err = d.skipField(f)
+			}
+			if err != nil {
+				return false, d.at(err, "."+f.name)
+			}
+			return len(want) == 0, nil
+		}
+		for _, r := range fieldRuns(infoOf(v.Type()).fields) {
+			if len(want) == 0 {
+				break
+			}
+			if r.section == "" {
+				_, err := visit(&r.fields[0])
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !slices.ContainsFunc(r.fields, func(f fieldInfo) bool { return want[f.name] }) {
+				err := d.skipSection()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			missing, err := d.readSection(r.fields, visit)
+			if err != nil {
+				return err
+			}
+			for _, f := range missing {
+				if want[f.name] {
+					v.Field(f.index).SetZero()
+					delete(want, f.name)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Validate checks that b holds exactly one structurally well-formed value
+// of type t, with every length prefix fitting the input, without decoding
+// it.
+func Validate(b []byte, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	d := defaultCodec.newDecodeState(b, nil)
+	return d.guard(func() error {
+		err := d.skip(t)
+		if err != nil {
+			return err
+		}
+		if d.off != len(b) {
+			return ErrTrailingBytes
+		}
+		return nil
+	})
+}
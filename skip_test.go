@@ -0,0 +1,105 @@
+package gensenc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type profile struct {
+	ID      uint64
+	Name    string
+	Friends []string
+	Scores  map[string]float64
+	Avatar  *[]byte
+	Extra   any
+	Rank    int16
+	ignored int
+}
+
+func testProfile() profile {
+	avatar := []byte{1, 2, 3}
+	return profile{
+		ID:      5,
+		Name:    "ann",
+		Friends: []string{"bob", "cy"},
+		Scores:  map[string]float64{"a": 1.5},
+		Avatar:  &avatar,
+		Extra:   []any{"x", int64(2)},
+		Rank:    -3,
+	}
+}
+
+func TestDecodeFields(t *testing.T) {
+	v := testProfile()
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := profile{Name: "kept", Rank: 9}
+	err = gensenc.DecodeFields(b, &got, "ID", "Scores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := profile{ID: 5, Name: "kept", Scores: v.Scores, Rank: 9}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// Nothing after the last requested field is read.
+	got = profile{}
+	err = gensenc.DecodeFields(b[:20], &got, "ID")
+	if err != nil || got.ID != 5 {
+		t.Errorf("decoding the first field of a prefix gave %+v, %v", got, err)
+	}
+	got = profile{}
+	err = gensenc.DecodeFields(b, &got, "Rank")
+	if err != nil || got.Rank != -3 {
+		t.Errorf("decoding the last field gave %+v, %v", got, err)
+	}
+}
+
+func TestDecodeFieldsErrors(t *testing.T) {
+	b, err := gensenc.Encode(testProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got profile
+	for _, name := range []string{"Missing", "ignored"} {
+		if err := gensenc.DecodeFields(b, &got, name); !errors.Is(err, gensenc.ErrUnknownField) {
+			t.Errorf("decoding field %s gave %v, want ErrUnknownField", name, err)
+		}
+	}
+	var n int
+	if err := gensenc.DecodeFields(b, &n, "ID"); !errors.Is(err, gensenc.ErrNotStruct) {
+		t.Errorf("decoding fields of an int gave %v, want ErrNotStruct", err)
+	}
+	err = gensenc.DecodeFields(b[:46], &got, "Rank")
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrInvalidLength) {
+		t.Fatalf("skipping a string running past the end gave %v, want ErrInvalidLength", err)
+	}
+	if de.Path != "Friends[1]" {
+		t.Errorf("error at %q, want Friends[1]", de.Path)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b, err := gensenc.Encode(testProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := reflect.TypeFor[profile]()
+	if err := gensenc.Validate(b, reflect.PointerTo(typ)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gensenc.Validate(append(b, 0), typ); !errors.Is(err, gensenc.ErrTrailingBytes) {
+		t.Errorf("validating a trailing byte gave %v, want ErrTrailingBytes", err)
+	}
+	for n := range len(b) {
+		if gensenc.Validate(b[:n], typ) == nil {
+			t.Fatalf("validated %d of %d bytes", n, len(b))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gensenc
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"sync"
+)
+
+var (
+	ErrNotSnapshot         error = errors.New("not a snapshot")
+	ErrUnsupportedVersion  error = errors.New("unsupported format version")
+	ErrFingerprintMismatch error = errors.New("type fingerprint mismatch")
+)
+
+var fingerprints sync.Map
+
+// Fingerprint returns a hash of t's schema. Types with the same wire
+// layout and type names have the same fingerprint.
+func Fingerprint(t reflect.Type) uint64 {
+	fp, ok := fingerprints.Load(t)
+	if ok {
+		return fp.(uint64)
+	}
+	b, err := DescribeType(t).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	fingerprints.Store(t, h.Sum64())
+	return h.Sum64()
+}
+
+// snapshotMagic is "GSNPSHOT" read as a little-endian integer.
+var snapshotMagic = binary.LittleEndian.Uint64([]byte("GSNPSHOT"))
+
+const snapshotVersion = 1
+
+type snapshotHeader struct {
+	Magic       uint64
+	Version     uint64
+	Fingerprint uint64
+	Count       uint64
+}
+
+// WriteSnapshot writes a header holding the format version, the
+// fingerprint of T and the number of elements, followed by the elements.
+func WriteSnapshot[T any](w io.Writer, s []T) error {
+	enc := NewEncoder(w)
+	err := enc.Encode(snapshotHeader{
+		Magic:       snapshotMagic,
+		Version:     snapshotVersion,
+		Fingerprint: Fingerprint(reflect.TypeFor[T]()),
+		Count:       uint64(len(s)),
+	})
+	if err != nil {
+		return err
+	}
+	for i := range s {
+		err = enc.Encode(&s[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot, validating its
+// header against T before decoding any element.
+func ReadSnapshot[T any](r io.Reader) ([]T, error) {
+	dec := NewDecoder(r)
+	var h snapshotHeader
+	err := dec.Decode(&h)
+	if err != nil {
+		return nil, err
+	}
+	if h.Magic != snapshotMagic {
+		return nil, ErrNotSnapshot
+	}
+	if h.Version != snapshotVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	if h.Fingerprint != Fingerprint(reflect.TypeFor[T]()) {
+		return nil, ErrFingerprintMismatch
+	}
+	s := make([]T, 0, min(h.Count, 1024))
+	for range h.Count {
+		var v T
+		err = dec.Decode(&v)
+		if err == io.EOF {
+			// The header promised more elements.
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type orderV2 struct {
+	ID    uint64
+	Items []string
+	Tags  map[string]int
+	Note  *string
+	Total int64
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := gensenc.Fingerprint(reflect.TypeFor[order]())
+	if fp != gensenc.Fingerprint(reflect.TypeFor[order]()) {
+		t.Error("fingerprint changed between calls")
+	}
+	type renamed order
+	for _, other := range []reflect.Type{
+		reflect.TypeFor[orderV2](),
+		reflect.TypeFor[renamed](),
+		reflect.TypeFor[*order](),
+		reflect.TypeFor[point](),
+	} {
+		if gensenc.Fingerprint(other) == fp {
+			t.Errorf("%v has the fingerprint of order", other)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	note := "n"
+	s := []order{
+		{ID: 1, Items: []string{"a"}, Tags: map[string]int{"x": 1}, Note: &note},
+		{ID: 2, Tags: map[string]int{}},
+	}
+	var buf bytes.Buffer
+	err := gensenc.WriteSnapshot(&buf, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("GSNPSHOT")) {
+		t.Errorf("snapshot starts with %q", buf.Bytes()[:8])
+	}
+	got, err := gensenc.ReadSnapshot[order](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("got %+v, want %+v", got, s)
+	}
+
+	buf.Reset()
+	err = gensenc.WriteSnapshot[order](&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = gensenc.ReadSnapshot[order](&buf)
+	if err != nil || len(got) != 0 {
+		t.Errorf("read %v, %v from an empty snapshot", got, err)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	var buf bytes.Buffer
+	err := gensenc.WriteSnapshot(&buf, []order{{ID: 1, Tags: map[string]int{}}, {ID: 2, Tags: map[string]int{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := buf.Bytes()
+	// The header is the magic, the version, the fingerprint and the count.
+	header := func(field int, v uint64) []byte {
+		b := bytes.Clone(snap)
+		binary.LittleEndian.PutUint64(b[8*field:], v)
+		return b
+	}
+	if _, err := gensenc.ReadSnapshot[orderV2](bytes.NewReader(snap)); !errors.Is(err, gensenc.ErrFingerprintMismatch) {
+		t.Errorf("reading as another type gave %v, want ErrFingerprintMismatch", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(header(2, 1))); !errors.Is(err, gensenc.ErrFingerprintMismatch) {
+		t.Errorf("reading a tampered fingerprint gave %v, want ErrFingerprintMismatch", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(header(0, 1))); !errors.Is(err, gensenc.ErrNotSnapshot) {
+		t.Errorf("reading without the magic gave %v, want ErrNotSnapshot", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(header(1, 2))); !errors.Is(err, gensenc.ErrUnsupportedVersion) {
+		t.Errorf("reading version 2 gave %v, want ErrUnsupportedVersion", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(header(3, 3))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading past the last element gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(snap[:len(snap)-1])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated element gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := gensenc.ReadSnapshot[order](bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("reading no input gave %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,27 @@
+package gensenc
+
+import "maps"
+
+// CollectStats makes the Encoder record the number of bytes written for
+// every field, reported by Stats. Fields are identified by their path as
+// in DecodeError, except that the elements of slices, arrays and maps are
+// counted together under the path of the container followed by "[]", as
+// in "Items[].Name". Encoding is slower while statistics are collected.
+func (enc *Encoder) CollectStats() {
+	if enc.e.stats == nil {
+		enc.e.stats = map[string]int64{}
+	}
+}
+
+// Stats returns the bytes written per field path by all values encoded
+// since CollectStats was called. The byte count of a field includes
+// those of the fields nested in it.
+func (enc *Encoder) Stats() map[string]int64 {
+	return maps.Clone(enc.e.stats)
+}
+
+// raw is options.raw, except that collecting statistics requires encoding
+// field by field.
+func (e *encodeState) raw(info *typeInfo) bool {
+	return e.stats == nil && e.options.raw(info)
+}
@@ -0,0 +1,70 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type route struct {
+	Name   string
+	Points []point
+	Meta   map[string]order
+}
+
+func TestStats(t *testing.T) {
+	var buf bytes.Buffer
+	enc := gensenc.NewEncoder(&buf)
+	enc.CollectStats()
+	v := route{
+		Name:   "abc",
+		Points: []point{{1, 2}, {3, 4}},
+		Meta:   map[string]order{"k": {ID: 1, Items: []string{"xy"}, Tags: map[string]int{}}},
+	}
+	for range 2 {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Counts are per field over both values, and the elements of
+	// containers are counted together; the points, whose memory matches
+	// the wire, are encoded field by field to count them.
+	want := map[string]int64{
+		"Name":         2 * 11,
+		"Points":       2 * (8 + 32),
+		"Points[].X":   2 * 16,
+		"Points[].Y":   2 * 16,
+		"Meta":         2 * (8 + 9 + 35),
+		"Meta[].ID":    2 * 8,
+		"Meta[].Items": 2 * 18,
+		"Meta[].Tags":  2 * 8,
+		"Meta[].Note":  2 * 1,
+	}
+	stats := enc.Stats()
+	if !maps.Equal(stats, want) {
+		t.Errorf("got %v, want %v", stats, want)
+	}
+	var total int64
+	for _, path := range []string{"Name", "Points", "Meta"} {
+		total += stats[path]
+	}
+	if total != int64(buf.Len()) {
+		t.Errorf("top-level fields took %d bytes, the output %d", total, buf.Len())
+	}
+	// The statistics returned are a copy.
+	stats["Name"] = 0
+	if enc.Stats()["Name"] != 2*11 {
+		t.Error("changing the statistics returned changed the Encoder's")
+	}
+
+	// Without CollectStats nothing is collected.
+	plain := gensenc.NewEncoder(&buf)
+	if err := plain.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if s := plain.Stats(); len(s) != 0 {
+		t.Errorf("collected %v without CollectStats", s)
+	}
+}
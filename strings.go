@@ -0,0 +1,286 @@
+package gensenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidString error = errors.New("string not representable in the encoding of the field")
+	ErrStringLength  error = errors.New("string longer than its field")
+)
+
+// A charset converts strings between UTF-8 and another encoding.
+type charset int
+
+const (
+	utf8Charset charset = iota
+	// latin1 is ISO 8859-1, which maps every byte to the code point of the
+	// same value.
+	latin1
+	// utf16LE is little-endian UTF-16 without a byte order mark.
+	utf16LE
+)
+
+func (cs charset) toBytes(s string) ([]byte, error) {
+	if !utf8.ValidString(s) {
+		return nil, ErrInvalidString
+	}
+	switch cs {
+	case latin1:
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				return nil, ErrInvalidString
+			}
+			b = append(b, byte(r))
+		}
+		return b, nil
+	case utf16LE:
+		units := utf16.Encode([]rune(s))
+		b := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			b = binary.LittleEndian.AppendUint16(b, u)
+		}
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+func (cs charset) fromBytes(b []byte) (string, error) {
+	switch cs {
+	case latin1:
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), nil
+	case utf16LE:
+		if len(b)%2 != 0 {
+			return "", ErrMalformed
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(b[2*i:])
+		}
+		for i := 0; i < len(units); i++ {
+			switch {
+			case units[i] < 0xd800 || units[i] >= 0xe000:
+			case units[i] < 0xdc00 && i+1 < len(units) && units[i+1] >= 0xdc00 && units[i+1] < 0xe000:
+				i++
+			default:
+				// Unpaired surrogate.
+				return "", ErrMalformed
+			}
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	if !utf8.Valid(b) {
+		return "", ErrMalformed
+	}
+	return string(b), nil
+}
+
+// stringCodec writes string fields tagged with latin1 or utf16 in that
+// encoding, as ISO 8859-1 or UTF-16LE, and string fields tagged with
+// fixedlen=N or cstring in a fixed number of bytes or terminated by a NUL
+// character, instead of preceded by their length in bytes. Encoding fails
+// with ErrInvalidString for strings that aren't valid UTF-8 or, for
+// latin1, contain characters beyond U+00FF, and decoding with ErrMalformed
+// for strings that aren't valid in their encoding.
+//
+// Strings of fixed length are padded with NUL characters, or with spaces
+// if the field is also tagged with pad=space, and decoded up to the first
+// NUL or without trailing spaces. Strings longer than the length fail to
+// encode with ErrStringLength unless the field is tagged with truncate,
+// which cuts them at the last complete character that fits. C strings must
+// not contain NUL characters.
+type stringCodec struct {
+	cs charset
+	// length is the length in bytes of fixed length strings, or 0.
+	length   int
+	cstring  bool
+	space    bool
+	truncate bool
+}
+
+func newStringCodec(t reflect.Type, opts tagOptions) wireCodec {
+	if t.Kind() != reflect.String {
+		return invalidTag{}
+	}
+	c := stringCodec{cstring: opts.has("cstring"), space: opts["pad"] == "space", truncate: opts.has("truncate")}
+	switch {
+	case opts.has("latin1") && opts.has("utf16"):
+		return invalidTag{}
+	case opts.has("latin1"):
+		c.cs = latin1
+	case opts.has("utf16"):
+		c.cs = utf16LE
+	}
+	if opts.has("fixedlen") {
+		n, err := strconv.Atoi(opts["fixedlen"])
+		if err != nil || n <= 0 || c.cstring || c.cs == utf16LE && n%2 != 0 {
+			return invalidTag{}
+		}
+		c.length = n
+	} else if opts.has("pad") || c.truncate {
+		return invalidTag{}
+	}
+	if opts.has("pad") && opts["pad"] != "space" && opts["pad"] != "nul" {
+		return invalidTag{}
+	}
+	return c
+}
+
+// unit returns the size of the code units of the encoding.
+func (c stringCodec) unit() int {
+	if c.cs == utf16LE {
+		return 2
+	}
+	return 1
+}
+
+func (c stringCodec) padding() []byte {
+	p := []byte{0, 0}
+	if c.space {
+		p[0] = ' '
+	}
+	return p[:c.unit()]
+}
+
+// cut returns the longest prefix of b of at most n bytes ending with a
+// complete character.
+func (c stringCodec) cut(b []byte, n int) []byte {
+	switch c.cs {
+	case utf16LE:
+		if n >= 2 && utf16.IsSurrogate(rune(binary.LittleEndian.Uint16(b[n-2:]))) && b[n-1] < 0xdc {
+			// A high surrogate whose low one doesn't fit.
+			n -= 2
+		}
+	case utf8Charset:
+		for n > 0 && n < len(b) && !utf8.RuneStart(b[n]) {
+			n--
+		}
+	}
+	return b[:n]
+}
+
+func (c stringCodec) encode(e *encodeState, v reflect.Value) error {
+	b, err := c.cs.toBytes(v.String())
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.length > 0:
+		if len(b) > c.length {
+			if !c.truncate {
+				return ErrStringLength
+			}
+			b = c.cut(b, c.length)
+		}
+		e.buf.Write(b)
+		for i := len(b); i < c.length; i += c.unit() {
+			e.buf.Write(c.padding())
+		}
+		return nil
+	case c.cstring:
+		if c.terminator(b) < len(b) {
+			return ErrInvalidString
+		}
+		e.buf.Write(b)
+		e.buf.Write(c.padding())
+		return nil
+	}
+	e.writeUint64(uint64(len(b)))
+	e.buf.Write(b)
+	return nil
+}
+
+// terminator returns the offset of the first NUL character in b, or len(b).
+func (c stringCodec) terminator(b []byte) int {
+	u := c.unit()
+	for i := 0; i+u <= len(b); i += u {
+		if b[i] == 0 && b[i+u-1] == 0 {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// read reads the bytes of a string.
+func (c stringCodec) read(d *decodeState) ([]byte, error) {
+	switch {
+	case c.length > 0:
+		b, err := d.take(uint64(c.length))
+		if err != nil {
+			return nil, err
+		}
+		if c.space {
+			u := c.unit()
+			for len(b) >= u && bytes.Equal(b[len(b)-u:], c.padding()) {
+				b = b[:len(b)-u]
+			}
+			return b, nil
+		}
+		return b[:c.terminator(b)], nil
+	case c.cstring:
+		var s []byte
+		for {
+			b, err := d.take(uint64(c.unit()))
+			if err != nil {
+				return nil, err
+			}
+			if c.terminator(b) == 0 {
+				return s, nil
+			}
+			if d.maxLength > 0 && len(s) >= d.maxLength {
+				return nil, ErrLimitExceeded
+			}
+			err = d.charge(uint64(len(b)), 1)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, b...)
+		}
+	}
+	return d.readBytes()
+}
+
+func (c stringCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	b, err := c.read(d)
+	if err != nil {
+		return err
+	}
+	s, err := c.cs.fromBytes(b)
+	if err != nil {
+		return err
+	}
+	v.SetString(s)
+	return nil
+}
+
+func (c stringCodec) skip(d *decodeState) error {
+	switch {
+	case c.length > 0:
+		return d.discard(uint64(c.length))
+	case c.cstring:
+		_, err := c.read(d)
+		return err
+	}
+	return bytesOf.skip(d)
+}
+
+func (c stringCodec) wireSize(map[reflect.Type]bool) int {
+	if c.length > 0 {
+		return c.length
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package gensenc_test
+
+import (
+	"errors"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type legacyName struct {
+	Name  string `gensenc:"latin1"`
+	Title string `gensenc:"utf16"`
+}
+
+func TestCharsets(t *testing.T) {
+	v := legacyName{Name: "café", Title: "a€𝄞"}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x04\x00\x00\x00\x00\x00\x00\x00caf\xe9" +
+		"\x08\x00\x00\x00\x00\x00\x00\x00a\x00\xac\x20\x34\xd8\x1e\xdd"
+	if string(b) != want {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	if got := roundTrip(t, gensenc.New(), v); got != v {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+}
+
+func TestCharsetsErrors(t *testing.T) {
+	for _, v := range []legacyName{{Name: "łódź"}, {Name: "\xff"}, {Title: "\xff"}} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidString) {
+			t.Errorf("encoding %q gave %v, want ErrInvalidString", v, err)
+		}
+	}
+	for _, title := range []string{
+		"\x03\x00\x00\x00\x00\x00\x00\x00a\x00b",
+		// An unpaired high and low surrogate.
+		"\x04\x00\x00\x00\x00\x00\x00\x00\x00\xd8a\x00",
+		"\x02\x00\x00\x00\x00\x00\x00\x00\x00\xdc",
+	} {
+		var got legacyName
+		err := gensenc.Decode([]byte("\x00\x00\x00\x00\x00\x00\x00\x00"+title), &got)
+		var de *gensenc.DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrMalformed) || de.Path != "Title" {
+			t.Errorf("decoding %x gave %v, want ErrMalformed at Title", title, err)
+		}
+	}
+	for _, v := range []any{
+		struct {
+			S string `gensenc:"latin1,utf16"`
+		}{},
+		struct {
+			N int `gensenc:"latin1"`
+		}{},
+	} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+	}
+}
+
+type cRecord struct {
+	Name  string `gensenc:"fixedlen=8"`
+	Label string `gensenc:"fixedlen=6,pad=space"`
+	Path  string `gensenc:"cstring"`
+	Short string `gensenc:"fixedlen=4,truncate"`
+	Wide  string `gensenc:"utf16,cstring"`
+}
+
+func TestFixedLenCString(t *testing.T) {
+	v := cRecord{Name: "abc", Label: "xy", Path: "/tmp", Short: "héllo", Wide: "ab"}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "abc\x00\x00\x00\x00\x00" + "xy    " + "/tmp\x00" + "h\xc3\xa9l" + "a\x00b\x00\x00\x00"
+	if string(b) != want {
+		t.Errorf("encoded as %q, want %q", b, want)
+	}
+	v.Short = "hél"
+	if got := roundTrip(t, gensenc.New(), v); got != v {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+
+	// Truncation keeps whole characters.
+	b, err = gensenc.Encode(cRecord{Short: "abcé"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short := string(b[8+6+1 : 8+6+1+4]); short != "abc\x00" {
+		t.Errorf("truncated to %q, want \"abc\\x00\"", short)
+	}
+}
+
+func TestFixedLenCStringErrors(t *testing.T) {
+	for _, c := range []struct {
+		v    cRecord
+		want error
+	}{
+		{cRecord{Name: "abcdefghi"}, gensenc.ErrStringLength},
+		{cRecord{Label: "abcdefg"}, gensenc.ErrStringLength},
+		{cRecord{Path: "a\x00b"}, gensenc.ErrInvalidString},
+		{cRecord{Wide: "a\x00"}, gensenc.ErrInvalidString},
+	} {
+		if _, err := gensenc.Encode(c.v); !errors.Is(err, c.want) {
+			t.Errorf("encoding %+v gave %v, want %v", c.v, err, c.want)
+		}
+	}
+
+	b, err := gensenc.Encode(cRecord{Path: "/tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got cRecord
+	err = gensenc.Decode(b[:8+6+4], &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTruncated) || de.Path != "Path" {
+		t.Errorf("decoding an unterminated string gave %v, want ErrTruncated at Path", err)
+	}
+	err = gensenc.New(gensenc.WithMaxLength(3)).Decode(b, &got)
+	if !errors.Is(err, gensenc.ErrLimitExceeded) {
+		t.Errorf("decoding a C string past the limit gave %v, want ErrLimitExceeded", err)
+	}
+
+	for _, v := range []any{
+		struct {
+			S string `gensenc:"fixedlen=0"`
+		}{},
+		struct {
+			S string `gensenc:"fixedlen=4,cstring"`
+		}{},
+		struct {
+			S string `gensenc:"utf16,fixedlen=3"`
+		}{},
+		struct {
+			S string `gensenc:"pad=space"`
+		}{},
+		struct {
+			S string `gensenc:"truncate"`
+		}{},
+		struct {
+			S string `gensenc:"fixedlen=4,pad=tab"`
+		}{},
+	} {
+		if _, err := gensenc.Encode(v); !errors.Is(err, gensenc.ErrInvalidTag) {
+			t.Errorf("encoding %T gave %v, want ErrInvalidTag", v, err)
+		}
+	}
+}
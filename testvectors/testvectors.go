@@ -0,0 +1,124 @@
+// Package testvectors generates a corpus of encoded values for checking
+// implementations of the gensenc wire format in other languages.
+//
+// Write lays the corpus out as one directory per case:
+//
+//	index.json          list of all cases with their Go type and description
+//	<case>/value.json   the value as JSON
+//	<case>/type.txt     the Go type of the value
+//	<case>/schema.bin   the gensenc.Schema of the type, encoded by its MarshalBinary
+//	<case>/encoded.bin  the gensenc encoding of the value
+//
+// All maps in the corpus have at most one entry, since the encoding of
+// larger maps depends on Go's iteration order.
+package testvectors
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type Case struct {
+	Name        string
+	Description string
+	Value       any
+}
+
+type point struct {
+	X, Y int32
+}
+
+type record struct {
+	ID      uint64
+	Name    string
+	Tags    []string
+	Point   point
+	Scores  map[string]float64
+	hidden  int
+	Enabled bool
+}
+
+// Cases returns the corpus in a stable order.
+func Cases() []Case {
+	return []Case{
+		{"int8_negative", "signed integers are widened to 8 bytes", int8(-3)},
+		{"int64_min", "", int64(math.MinInt64)},
+		{"uint16", "unsigned integers are widened to 8 bytes", uint16(65535)},
+		{"uint64_max", "", uint64(math.MaxUint64)},
+		{"bool_true", "bools are one byte", true},
+		{"bool_false", "", false},
+		{"float32", "float32 is 4 little-endian bytes", float32(1.5)},
+		{"float64", "float64 is 8 little-endian bytes", -2.25},
+		{"string_empty", "strings are a length followed by UTF-8 bytes", ""},
+		{"string_utf8", "", "héllo, 世界"},
+		{"slice_empty", "slices are a length followed by the elements", []int32{}},
+		{"slice_strings", "", []string{"a", "bc"}},
+		{"array", "arrays are their elements without a length", [3]uint8{1, 2, 3}},
+		{"map_single", "maps are a length followed by key value pairs", map[string]int{"k": 7}},
+		{"nested_slices", "", [][]int16{{1}, {}, {2, 3}}},
+		{"struct_point", "structs are their exported fields in order", point{X: -1, Y: 2}},
+		{"struct_record", "unexported fields are skipped", record{
+			ID:      42,
+			Name:    "rec",
+			Tags:    []string{"x"},
+			Point:   point{X: 1, Y: 2},
+			Scores:  map[string]float64{"s": 0.5},
+			hidden:  9,
+			Enabled: true,
+		}},
+	}
+}
+
+type indexEntry struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Write writes the corpus below dir, creating it if necessary.
+func Write(dir string) error {
+	var index []indexEntry
+	for _, c := range Cases() {
+		t := reflect.TypeOf(c.Value)
+		encoded, err := gensenc.Encode(c.Value)
+		if err != nil {
+			return err
+		}
+		value, err := json.MarshalIndent(c.Value, "", "  ")
+		if err != nil {
+			return err
+		}
+		schema, err := gensenc.DescribeType(t).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		caseDir := filepath.Join(dir, c.Name)
+		err = os.MkdirAll(caseDir, 0o755)
+		if err != nil {
+			return err
+		}
+		files := map[string][]byte{
+			"value.json":  append(value, '\n'),
+			"type.txt":    []byte(t.String() + "\n"),
+			"schema.bin":  schema,
+			"encoded.bin": encoded,
+		}
+		for name, b := range files {
+			err = os.WriteFile(filepath.Join(caseDir, name), b, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+		index = append(index, indexEntry{Name: c.Name, Type: t.String(), Description: c.Description})
+	}
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index.json"), append(b, '\n'), 0o644)
+}
@@ -0,0 +1,174 @@
+package testvectors_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/testvectors"
+)
+
+// u64 is the 8-byte little-endian form of integers up to 255.
+func u64(n byte) string {
+	return hex.EncodeToString([]byte{n}) + strings.Repeat("00", 7)
+}
+
+// golden pins the encoding of every case, written out by hand from the
+// wire format rather than taken from the encoder, so that a change to
+// either shows up here.
+var golden = map[string]string{
+	"int8_negative": "fdffffffffffffff",
+	"int64_min":     "0000000000000080",
+	"uint16":        "ffff000000000000",
+	"uint64_max":    "ffffffffffffffff",
+	"bool_true":     "01",
+	"bool_false":    "00",
+	"float32":       "0000c03f",
+	"float64":       "00000000000002c0",
+	"string_empty":  u64(0),
+	"string_utf8":   u64(14) + "68c3a96c6c6f2c20e4b896e7958c",
+	"slice_empty":   u64(0),
+	"slice_strings": u64(2) + u64(1) + "61" + u64(2) + "6263",
+	"array":         u64(1) + u64(2) + u64(3),
+	"map_single":    u64(1) + u64(1) + "6b" + u64(7),
+	"nested_slices": u64(3) + u64(1) + u64(1) + u64(0) + u64(2) + u64(2) + u64(3),
+	"struct_point":  "ffffffffffffffff" + u64(2),
+	"struct_record": u64(42) + u64(3) + "726563" + u64(1) + u64(1) + "78" + u64(1) + u64(2) +
+		u64(1) + u64(1) + "73" + "000000000000e03f" + "01",
+}
+
+func TestCases(t *testing.T) {
+	names := map[string]bool{}
+	for _, c := range testvectors.Cases() {
+		if names[c.Name] {
+			t.Errorf("%s: duplicate case", c.Name)
+		}
+		names[c.Name] = true
+		b, err := gensenc.Encode(c.Value)
+		if err != nil {
+			t.Errorf("%s: %v", c.Name, err)
+			continue
+		}
+		want, ok := golden[c.Name]
+		if !ok {
+			t.Errorf("%s: no golden encoding", c.Name)
+			continue
+		}
+		if got := hex.EncodeToString(b); got != want {
+			t.Errorf("%s: encoded as %s, want %s", c.Name, got, want)
+		}
+	}
+	for name := range golden {
+		if !names[name] {
+			t.Errorf("%s: golden encoding of a missing case", name)
+		}
+	}
+}
+
+type indexEntry struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// TestWrite checks the layout of the corpus, and that each case reads back:
+// the encoding decodes to a value encoding the same, and the schema decodes
+// to that of its type.
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "vectors")
+	err := testvectors.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index []indexEntry
+	err = json.Unmarshal(b, &index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := testvectors.Cases()
+	if len(index) != len(cases) {
+		t.Fatalf("index lists %d cases, want %d", len(index), len(cases))
+	}
+	for i, c := range cases {
+		typ := reflect.TypeOf(c.Value)
+		if index[i] != (indexEntry{c.Name, typ.String(), c.Description}) {
+			t.Errorf("index entry %d is %+v", i, index[i])
+		}
+		read := func(name string) []byte {
+			b, err := os.ReadFile(filepath.Join(dir, c.Name, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		}
+		encoded := read("encoded.bin")
+		v := reflect.New(typ)
+		err = gensenc.Decode(encoded, v.Interface())
+		if err != nil {
+			t.Errorf("%s: decoding encoded.bin: %v", c.Name, err)
+		}
+		// Empty slices decode as nil ones, so compare encodings.
+		if b, _ := gensenc.Encode(v.Elem().Interface()); !bytes.Equal(b, encoded) {
+			t.Errorf("%s: encoded.bin reencodes as %x, want %x", c.Name, b, encoded)
+		}
+		want, _ := json.MarshalIndent(c.Value, "", "  ")
+		if value := read("value.json"); !bytes.Equal(value, append(want, '\n')) {
+			t.Errorf("%s: value.json holds %s, want %s", c.Name, value, want)
+		}
+		if got := string(read("type.txt")); got != typ.String()+"\n" {
+			t.Errorf("%s: type.txt holds %q", c.Name, got)
+		}
+		var s gensenc.Schema
+		err = s.UnmarshalBinary(read("schema.bin"))
+		if err != nil {
+			t.Errorf("%s: decoding schema.bin: %v", c.Name, err)
+		} else if want := gensenc.DescribeType(typ); !reflect.DeepEqual(s, want) {
+			t.Errorf("%s: schema.bin holds %+v, want %+v", c.Name, s, want)
+		}
+	}
+}
+
+func TestWriteStable(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	for _, dir := range []string{a, b} {
+		if err := testvectors.Write(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := filepath.WalkDir(a, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(a, path)
+		x, _ := os.ReadFile(path)
+		y, err := os.ReadFile(filepath.Join(b, rel))
+		if err != nil || !bytes.Equal(x, y) {
+			t.Errorf("%s differs between runs", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testvectors.Write(file); err == nil {
+		t.Error("writing below a file succeeded")
+	}
+}
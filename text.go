@@ -0,0 +1,87 @@
+package gensenc
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+)
+
+// A TextEncoding represents payloads as text, for embedding them in JSON
+// strings, URLs, environment variables or cookies.
+type TextEncoding int
+
+const (
+	// Base64URL is the unpadded URL-safe base64 encoding of RFC 4648.
+	Base64URL TextEncoding = iota
+	// Hex is lowercase hexadecimal; decoding accepts either case.
+	Hex
+)
+
+func (t TextEncoding) encodeToString(b []byte) string {
+	if t == Hex {
+		return hex.EncodeToString(b)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (t TextEncoding) decodeString(s string) ([]byte, error) {
+	if t == Hex {
+		return hex.DecodeString(s)
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// EncodeToString encodes a as Base64URL text.
+func EncodeToString(a any) (string, error) {
+	return EncodeText(Base64URL, a)
+}
+
+// DecodeString decodes Base64URL text written by EncodeToString into the
+// value a points to.
+func DecodeString(s string, a any) error {
+	return DecodeText(Base64URL, s, a)
+}
+
+func EncodeText(t TextEncoding, a any) (string, error) {
+	b, err := Encode(a)
+	if err != nil {
+		return "", err
+	}
+	return t.encodeToString(b), nil
+}
+
+func DecodeText(t TextEncoding, s string, a any) error {
+	b, err := t.decodeString(s)
+	if err != nil {
+		return err
+	}
+	return Decode(b, a)
+}
+
+// NewTextWriter returns a writer encoding what is written to it as text in
+// encoding t and writing that to w, for use with NewEncoder. Base64URL
+// holds back partial groups of bytes until the writer is closed, which
+// doesn't close w.
+func NewTextWriter(w io.Writer, t TextEncoding) io.WriteCloser {
+	if t == Hex {
+		return nopCloser{hex.NewEncoder(w)}
+	}
+	return base64.NewEncoder(base64.RawURLEncoding, w)
+}
+
+// NewTextReader returns a reader decoding text in encoding t read from r,
+// for use with NewDecoder.
+func NewTextReader(r io.Reader, t TextEncoding) io.Reader {
+	if t == Hex {
+		return hex.NewDecoder(r)
+	}
+	return base64.NewDecoder(base64.RawURLEncoding, r)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
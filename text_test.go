@@ -0,0 +1,103 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestText(t *testing.T) {
+	v := point{X: -1, Y: 1 << 20}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := gensenc.EncodeToString(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := base64.RawURLEncoding.EncodeToString(b); s != want {
+		t.Errorf("encoded as %q, want %q", s, want)
+	}
+	var got point
+	if err := gensenc.DecodeString(s, &got); err != nil || got != v {
+		t.Errorf("decoded %+v, %v", got, err)
+	}
+	h, err := gensenc.EncodeText(gensenc.Hex, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h != "ffffffffffffffff0000100000000000" {
+		t.Errorf("encoded as %q", h)
+	}
+	got = point{}
+	if err := gensenc.DecodeText(gensenc.Hex, strings.ToUpper(h), &got); err != nil || got != v {
+		t.Errorf("decoded %+v, %v from upper case", got, err)
+	}
+}
+
+func TestTextStream(t *testing.T) {
+	var raw []byte
+	for i := range 3 {
+		b, err := gensenc.Encode(point{X: int32(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw = append(raw, b...)
+	}
+	for _, tt := range []struct {
+		te   gensenc.TextEncoding
+		want string
+	}{
+		{gensenc.Base64URL, base64.RawURLEncoding.EncodeToString(raw)},
+		{gensenc.Hex, hex.EncodeToString(raw)},
+	} {
+		var buf bytes.Buffer
+		w := gensenc.NewTextWriter(&buf, tt.te)
+		enc := gensenc.NewEncoder(w)
+		for i := range 3 {
+			if err := enc.Encode(point{X: int32(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// The stream is the text form of the values back to back.
+		if buf.String() != tt.want {
+			t.Errorf("wrote %q, want %q", buf.String(), tt.want)
+		}
+		dec := gensenc.NewDecoder(gensenc.NewTextReader(&buf, tt.te))
+		for i := range 3 {
+			var p point
+			if err := dec.Decode(&p); err != nil || p.X != int32(i) {
+				t.Errorf("value %d decoded as %+v, %v", i, p, err)
+			}
+		}
+	}
+}
+
+func TestTextErrors(t *testing.T) {
+	var p point
+	if err := gensenc.DecodeString("AA==", &p); err == nil {
+		t.Error("decoded padded base64")
+	}
+	if err := gensenc.DecodeString("a+b/", &p); err == nil {
+		t.Error("decoded standard base64")
+	}
+	if err := gensenc.DecodeText(gensenc.Hex, "0g", &p); err == nil {
+		t.Error("decoded invalid hex")
+	}
+	s, _ := gensenc.EncodeToString(p)
+	if err := gensenc.DecodeString(s[:len(s)-4], &p); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated text gave %v, want ErrTruncated", err)
+	}
+	if _, err := gensenc.EncodeToString(make(chan int)); !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("encoding a channel gave %v, want ErrUnsupportedKind", err)
+	}
+}
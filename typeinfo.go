@@ -0,0 +1,397 @@
+package gensenc
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tagOptions holds the comma separated options of a field's gensenc struct
+// tag. Options of the form key=value map key to value, all others map to
+// the empty string.
+type tagOptions map[string]string
+
+func parseTag(tag string) tagOptions {
+	if tag == "" {
+		return nil
+	}
+	opts := tagOptions{}
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		if key != "" {
+			opts[key] = value
+		}
+	}
+	return opts
+}
+
+func (o tagOptions) has(key string) bool {
+	_, ok := o[key]
+	return ok
+}
+
+// encoded reports whether the struct field f is encoded, as exported fields
+// are unless their tag is "-".
+func encoded(f reflect.StructField) bool {
+	return f.IsExported() && f.Tag.Get("gensenc") != "-"
+}
+
+// wireFields returns the encoded fields of the struct type t in wire order,
+// which is declaration order unless the fields have idx=N tag options,
+// ordering them by N. It reports false if only some fields have one, or two
+// have the same.
+func wireFields(t reflect.Type) ([]reflect.StructField, bool) {
+	var fields []reflect.StructField
+	positions := map[string]uint64{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !encoded(f) {
+			continue
+		}
+		fields = append(fields, f)
+		s, ok := parseTag(f.Tag.Get("gensenc"))["idx"]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fields, false
+		}
+		positions[f.Name] = n
+	}
+	if len(positions) == 0 {
+		return fields, true
+	}
+	if len(positions) != len(fields) {
+		return fields, false
+	}
+	slices.SortFunc(fields, func(a, b reflect.StructField) int {
+		return cmp.Compare(positions[a.Name], positions[b.Name])
+	})
+	for i := 1; i < len(fields); i++ {
+		if positions[fields[i-1].Name] == positions[fields[i].Name] {
+			return fields, false
+		}
+	}
+	return fields, true
+}
+
+type fieldInfo struct {
+	index int
+	name  string
+	typ   reflect.Type
+	opts  tagOptions
+	// codec, if not nil, replaces the default encoding of the field.
+	codec wireCodec
+}
+
+// A wireCodec encodes values in a wire form other than the default one of
+// their kind, for types that need one and for struct fields whose tag
+// options ask for one.
+type wireCodec interface {
+	encode(e *encodeState, v reflect.Value) error
+	decode(d *decodeState, v reflect.Value) error
+	skip(d *decodeState) error
+	// wireSize is like computeWireSize for the encoded field.
+	wireSize(visiting map[reflect.Type]bool) int
+}
+
+// newFieldCodec returns the codec for a field of type t with the given tag
+// options, or nil if the field uses the default encoding.
+func newFieldCodec(t reflect.Type, opts tagOptions) wireCodec {
+	switch {
+	case opts.has("redact"):
+		return newRedactCodec(t, opts)
+	case opts.has("arraylen"):
+		return newArrayLenCodec(t)
+	case opts.has("enum"):
+		return newEnumCodec(t)
+	case opts.has("oneof"):
+		return newOneofCodec(t)
+	case opts.has("delta"):
+		return newDeltaCodec(t, opts.has("varint"))
+	case opts.has("bits"):
+		return newBitsCodec(t, opts["bits"])
+	case opts.has("rle"):
+		return newRLECodec(t)
+	case opts.has("float16"), opts.has("fixed"):
+		return newFloatCodec(t, opts)
+	case opts.has("compress"):
+		return newCompressCodec(t, opts["compress"])
+	case opts.has("uuid"):
+		return newUUIDCodec(t)
+	case opts.has("latin1"), opts.has("utf16"), opts.has("fixedlen"), opts.has("cstring"),
+		opts.has("pad"), opts.has("truncate"):
+		return newStringCodec(t, opts)
+	}
+	return nil
+}
+
+// hasFieldCodec reports whether opts give fields a codec of their own,
+// whatever their type, as newFieldCodec returns invalidTag rather than nil
+// for options that don't apply to a type.
+func hasFieldCodec(opts tagOptions) bool {
+	return newFieldCodec(reflect.TypeFor[struct{}](), opts) != nil
+}
+
+func fieldCodecOf(f reflect.StructField) wireCodec {
+	return newFieldCodec(f.Type, parseTag(f.Tag.Get("gensenc")))
+}
+
+// invalidTag is the codec of fields with tag options that don't apply to
+// their type. It fails every operation, reporting the misuse on first use.
+type invalidTag struct{}
+
+func (invalidTag) encode(*encodeState, reflect.Value) error { return ErrInvalidTag }
+func (invalidTag) decode(*decodeState, reflect.Value) error { return ErrInvalidTag }
+func (invalidTag) skip(*decodeState) error                  { return ErrInvalidTag }
+func (invalidTag) wireSize(map[reflect.Type]bool) int       { return -1 }
+
+func (invalidTag) writeFormat(*encodeState, FormatWriter, reflect.Value) error {
+	return ErrInvalidTag
+}
+
+func (invalidTag) readFormat(*decodeState, FormatReader, reflect.Value) error {
+	return ErrInvalidTag
+}
+
+// typeInfo holds what the encoder and decoder need to know about a type,
+// computed once per type.
+type typeInfo struct {
+	// fields lists the exported fields of a struct type in declaration
+	// order.
+	fields []fieldInfo
+	// size is the wire size of every value of the type, or -1 if it
+	// depends on the value.
+	size int
+	// minSize is a lower bound for the wire size.
+	minSize     int
+	hasPointers bool
+	// memLayout reports whether values can be copied to and from the wire
+	// as raw memory.
+	memLayout bool
+	// arrays reports whether values of a fixed size contain arrays, which
+	// invalidates size and memLayout when arrays carry their length.
+	arrays bool
+	// integers reports whether values of a fixed size contain integers,
+	// which invalidates size and memLayout when integers are varints.
+	integers bool
+	// structs reports whether values of a fixed size contain structs, which
+	// invalidates size and memLayout when fields are named.
+	structs bool
+	// codec, if not nil, replaces the default encoding of the type.
+	codec wireCodec
+	// invalidKeys reports whether a map type has keys of a type that is
+	// never valid, and checkKeys whether each key needs checking.
+	invalidKeys bool
+	checkKeys   bool
+}
+
+var typeInfos sync.Map
+
+func infoOf(t reflect.Type) *typeInfo {
+	ti, ok := typeInfos.Load(t)
+	if ok {
+		return ti.(*typeInfo)
+	}
+	info := &typeInfo{
+		codec:       typeCodecs[t],
+		size:        computeWireSize(t, map[reflect.Type]bool{}),
+		minSize:     computeMinWireSize(t),
+		hasPointers: computeHasPointers(t),
+		memLayout:   computeMemLayout(t),
+	}
+	if info.size >= 0 {
+		info.arrays = computeHasArrays(t)
+		info.integers = computeHasIntegers(t)
+		info.structs = computeHasStructs(t)
+	}
+	if t.Kind() == reflect.Map {
+		valid, check := computeKeyType(t.Key(), map[reflect.Type]bool{})
+		info.invalidKeys, info.checkKeys = !valid, check
+	}
+	if t.Kind() == reflect.Struct {
+		fields, ok := wireFields(t)
+		if !ok {
+			info.codec = invalidTag{}
+		}
+		for _, f := range fields {
+			opts := parseTag(f.Tag.Get("gensenc"))
+			info.fields = append(info.fields, fieldInfo{
+				index: f.Index[0],
+				name:  f.Name,
+				typ:   f.Type,
+				opts:  opts,
+				codec: newFieldCodec(f.Type, opts),
+			})
+		}
+		if info.codec == nil {
+			info.codec = newSectionCodec(info.fields)
+		}
+	}
+	ti, _ = typeInfos.LoadOrStore(t, info)
+	return ti.(*typeInfo)
+}
+
+// wireSize returns the number of bytes every value of t occupies on the
+// wire, or -1 if the size depends on the value.
+func wireSize(t reflect.Type) int {
+	return infoOf(t).size
+}
+
+// minWireSize returns a lower bound for the wire size of values of t.
+func minWireSize(t reflect.Type) int {
+	return infoOf(t).minSize
+}
+
+// computeMinWireSize computes minWireSize. Variable-size codecs write at
+// least one byte.
+func computeMinWireSize(t reflect.Type) int {
+	if s := computeWireSize(t, map[reflect.Type]bool{}); s >= 0 {
+		return s
+	}
+	if typeCodecs[t] != nil {
+		return 1
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Interface:
+		return 8
+	case reflect.Struct:
+		n := 8 * sectionCount(t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !encoded(f) || parseTag(f.Tag.Get("gensenc")).section() != "" {
+				continue
+			}
+			if c := fieldCodecOf(f); c != nil {
+				n += max(c.wireSize(map[reflect.Type]bool{}), 1)
+			} else {
+				n += computeMinWireSize(f.Type)
+			}
+		}
+		return n
+	case reflect.Array:
+		return t.Len() * computeMinWireSize(t.Elem())
+	}
+	return 1
+}
+
+func computeWireSize(t reflect.Type, visiting map[reflect.Type]bool) int {
+	if visiting[t] {
+		return -1
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	if c := typeCodecs[t]; c != nil {
+		return c.wireSize(visiting)
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return -1
+	case reflect.Struct:
+		// Sections vary in size as they may hold fewer or more fields.
+		if sectionCount(t) > 0 {
+			return -1
+		}
+		n := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !encoded(f) {
+				continue
+			}
+			var s int
+			if c := fieldCodecOf(f); c != nil {
+				s = c.wireSize(visiting)
+			} else {
+				s = computeWireSize(f.Type, visiting)
+			}
+			if s < 0 {
+				return -1
+			}
+			n += s
+		}
+		return n
+	case reflect.Array:
+		s := computeWireSize(t.Elem(), visiting)
+		if s < 0 {
+			return -1
+		}
+		return s * t.Len()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 8
+	case reflect.Bool:
+		return 1
+	case reflect.Float32:
+		return 4
+	case reflect.Float64, reflect.Complex64:
+		return 8
+	case reflect.Complex128:
+		return 16
+	}
+	return -1
+}
+
+// computeHasArrays reports whether t contains an array. t must have a fixed
+// wire size, which rules out cycles.
+func computeHasArrays(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if encoded(f) && computeHasArrays(f.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// computeHasIntegers reports whether t contains integers encoded as such,
+// outside of fields with their own codec. Like computeHasArrays, it
+// requires t to have a fixed wire size.
+func computeHasIntegers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Array:
+		return computeHasIntegers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if encoded(f) && fieldCodecOf(f) == nil && computeHasIntegers(f.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasPointers(t reflect.Type) bool {
+	return infoOf(t).hasPointers
+}
+
+func computeHasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return t.Len() > 0 && computeHasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if computeHasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+type withSkipped struct {
+	A       int
+	hidden  string
+	Skipped []string `gensenc:"-"`
+	B       string
+}
+
+type withoutSkipped struct {
+	A int
+	B string
+}
+
+// TestTypeInfoFields checks that unexported fields and those tagged "-" are
+// left out and the others written in declaration order.
+func TestTypeInfoFields(t *testing.T) {
+	got, err := gensenc.Encode(withSkipped{A: 1, hidden: "h", Skipped: []string{"s"}, B: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := gensenc.Encode(withoutSkipped{A: 1, B: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encoded as %x, want %x", got, want)
+	}
+	v := withSkipped{hidden: "kept", Skipped: []string{"kept"}}
+	err = gensenc.Decode(want, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := (withSkipped{A: 1, hidden: "kept", Skipped: []string{"kept"}, B: "b"}); !reflect.DeepEqual(v, w) {
+		t.Errorf("decoded as %+v, want %+v", v, w)
+	}
+}
+
+// TestTypeInfoMinSize checks that slice lengths are checked against the
+// smallest encoding of their elements before anything is allocated.
+func TestTypeInfoMinSize(t *testing.T) {
+	type pair struct{ A, B int64 }
+	for _, tt := range []struct {
+		name string
+		dst  any
+		// fits is the largest length the 64 bytes following it can hold.
+		fits uint64
+	}{
+		{"fixed size", new([]pair), 4},
+		{"strings", new([]string), 8},
+		{"pointers", new([]*pair), 64},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			b := binary.LittleEndian.AppendUint64(nil, tt.fits+1)
+			b = append(b, make([]byte, 64)...)
+			err := gensenc.Decode(b, tt.dst)
+			if !errors.Is(err, gensenc.ErrInvalidLength) {
+				t.Errorf("length %d gave %v, want ErrInvalidLength", tt.fits+1, err)
+			}
+			binary.LittleEndian.PutUint64(b, tt.fits)
+			err = gensenc.Decode(b, tt.dst)
+			if errors.Is(err, gensenc.ErrInvalidLength) {
+				t.Errorf("length %d gave %v", tt.fits, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package gensenc
+
+import (
+	"database/sql"
+	"encoding"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"time"
+)
+
+// typeCodecs holds the codecs of types from other packages whose default
+// encoding as structs of exported fields would lose their value, and of
+// Envelope.
+var typeCodecs = map[reflect.Type]wireCodec{
+	reflect.TypeFor[time.Time](): binaryCodec[time.Time](),
+	reflect.TypeFor[Envelope]():  envelopeCodec{},
+	reflect.TypeFor[Raw]():       bytesOf,
+	reflect.TypeFor[UUID]():      uuidCodec{},
+
+	reflect.TypeFor[big.Int]():   gobCodec[big.Int](),
+	reflect.TypeFor[big.Float](): gobCodec[big.Float](),
+	reflect.TypeFor[big.Rat]():   gobCodec[big.Rat](),
+
+	reflect.TypeFor[netip.Addr]():     binaryCodec[netip.Addr](),
+	reflect.TypeFor[netip.AddrPort](): binaryCodec[netip.AddrPort](),
+	reflect.TypeFor[netip.Prefix]():   binaryCodec[netip.Prefix](),
+	reflect.TypeFor[net.IP]():         ipCodec,
+	reflect.TypeFor[net.IPMask]():     bytesOf,
+
+	reflect.TypeFor[sql.NullBool]():    nullOf[sql.NullBool](),
+	reflect.TypeFor[sql.NullByte]():    nullOf[sql.NullByte](),
+	reflect.TypeFor[sql.NullFloat64](): nullOf[sql.NullFloat64](),
+	reflect.TypeFor[sql.NullInt16]():   nullOf[sql.NullInt16](),
+	reflect.TypeFor[sql.NullInt32]():   nullOf[sql.NullInt32](),
+	reflect.TypeFor[sql.NullInt64]():   nullOf[sql.NullInt64](),
+	reflect.TypeFor[sql.NullString]():  nullOf[sql.NullString](),
+	reflect.TypeFor[sql.NullTime]():    nullOf[sql.NullTime](),
+}
+
+// pointer returns a pointer to v, or to a copy of v if v isn't
+// addressable.
+func pointer(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+func (d *decodeState) readBytes() ([]byte, error) {
+	length, err := d.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	err = d.checkLength(length, 1)
+	if err == nil {
+		err = d.charge(length, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.take(length)
+}
+
+// bytesCodec encodes values as a length-prefixed byte string, like a
+// string. The bytes passed to unmarshal are only valid during the call.
+type bytesCodec struct {
+	marshal   func(v reflect.Value) ([]byte, error)
+	unmarshal func(v reflect.Value, b []byte) error
+}
+
+func (c bytesCodec) encode(e *encodeState, v reflect.Value) error {
+	b, err := c.marshal(v)
+	if err != nil {
+		return err
+	}
+	e.writeUint64(uint64(len(b)))
+	e.buf.Write(b)
+	return nil
+}
+
+func (c bytesCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	b, err := d.readBytes()
+	if err != nil {
+		return err
+	}
+	return c.unmarshal(v, b)
+}
+
+func (c bytesCodec) skip(d *decodeState) error {
+	length, err := d.readUint64()
+	if err != nil {
+		return err
+	}
+	err = d.checkLength(length, 1)
+	if err != nil {
+		return err
+	}
+	return d.discard(length)
+}
+
+func (bytesCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
+
+// writeFormat writes values with a text form, such as time.Time, big.Int
+// and netip.Addr, as strings in that form and others as bytes.
+func (c bytesCodec) writeFormat(_ *encodeState, w FormatWriter, v reflect.Value) error {
+	if m, ok := pointer(v).Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err != nil {
+			return err
+		}
+		return w.WriteString(string(b))
+	}
+	b, err := c.marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(b)
+}
+
+func (c bytesCodec) readFormat(_ *decodeState, r FormatReader, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		s, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+		return u.UnmarshalText([]byte(s))
+	}
+	b, err := r.ReadBytes()
+	if err != nil {
+		return err
+	}
+	return c.unmarshal(v, b)
+}
+
+type gobber interface {
+	GobEncode() ([]byte, error)
+	GobDecode([]byte) error
+}
+
+// gobCodec encodes values of T with their GobEncode and GobDecode methods.
+func gobCodec[T any, P interface {
+	*T
+	gobber
+}]() wireCodec {
+	return bytesCodec{
+		marshal: func(v reflect.Value) ([]byte, error) {
+			return pointer(v).Interface().(P).GobEncode()
+		},
+		unmarshal: func(v reflect.Value, b []byte) error {
+			return v.Addr().Interface().(P).GobDecode(b)
+		},
+	}
+}
+
+// binaryCodec encodes values of T with their MarshalBinary and
+// UnmarshalBinary methods.
+func binaryCodec[T any, P interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}]() wireCodec {
+	return bytesCodec{
+		marshal: func(v reflect.Value) ([]byte, error) {
+			return pointer(v).Interface().(P).MarshalBinary()
+		},
+		unmarshal: func(v reflect.Value, b []byte) error {
+			return v.Addr().Interface().(P).UnmarshalBinary(b)
+		},
+	}
+}
+
+// bytesOf encodes byte slices with one byte per element instead of the
+// eight of the default encoding.
+var bytesOf = bytesCodec{
+	marshal: func(v reflect.Value) ([]byte, error) {
+		return v.Bytes(), nil
+	},
+	unmarshal: func(v reflect.Value, b []byte) error {
+		if len(b) == 0 {
+			v.SetZero()
+		} else {
+			v.SetBytes(append([]byte(nil), b...))
+		}
+		return nil
+	},
+}
+
+// ipCodec is bytesOf for net.IP, writing IPv4 addresses held in their
+// 16-byte form as 4 bytes.
+var ipCodec = bytesCodec{
+	marshal: func(v reflect.Value) ([]byte, error) {
+		ip := net.IP(v.Bytes())
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		return ip, nil
+	},
+	unmarshal: bytesOf.unmarshal,
+}
+
+// nullCodec encodes the sql.Null types like a pointer to their value: a
+// presence byte that is zero for NULL, followed by the value only if it is
+// valid. The types all hold the value in their first field and the
+// validity in the second.
+type nullCodec struct {
+	value reflect.Type
+}
+
+func nullOf[T any]() wireCodec {
+	return nullCodec{reflect.TypeFor[T]().Field(0).Type}
+}
+
+func (nullCodec) encode(e *encodeState, v reflect.Value) error {
+	if !v.Field(1).Bool() {
+		e.buf.WriteByte(0)
+		return nil
+	}
+	e.buf.WriteByte(1)
+	return e.encode(v.Field(0))
+}
+
+func (nullCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	valid, err := d.readPresence()
+	if err != nil {
+		return err
+	}
+	v.SetZero()
+	v.Field(1).SetBool(valid)
+	if !valid {
+		return nil
+	}
+	return d.decode(v.Field(0))
+}
+
+func (c nullCodec) skip(d *decodeState) error {
+	valid, err := d.readPresence()
+	if err != nil || !valid {
+		return err
+	}
+	return d.skip(c.value)
+}
+
+func (nullCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
+
+// writeFormat writes NULL as nil and valid values as their value.
+func (nullCodec) writeFormat(e *encodeState, w FormatWriter, v reflect.Value) error {
+	if !v.Field(1).Bool() {
+		return w.WriteNil()
+	}
+	return e.encodeFormat(w, v.Field(0))
+}
+
+func (nullCodec) readFormat(d *decodeState, r FormatReader, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	null, err := r.ReadNil()
+	if err != nil {
+		return err
+	}
+	v.SetZero()
+	if null {
+		return nil
+	}
+	v.Field(1).SetBool(true)
+	return d.decodeFormat(r, v.Field(0))
+}
@@ -0,0 +1,222 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/cbor"
+)
+
+type ledger struct {
+	Balance big.Int
+	Rate    *big.Rat
+	Price   *big.Float
+	Unset   *big.Int
+}
+
+func TestBigTypes(t *testing.T) {
+	var v ledger
+	v.Balance.SetString("-123456789012345678901234567890", 10)
+	v.Rate = big.NewRat(-7, 3)
+	v.Price, _ = new(big.Float).SetPrec(200).SetString("3.14159265358979323846264338327950288")
+	got := roundTrip(t, gensenc.New(), v)
+	if got.Balance.Cmp(&v.Balance) != 0 || got.Rate.Cmp(v.Rate) != 0 || got.Unset != nil {
+		t.Errorf("got %v, %v, %v", &got.Balance, got.Rate, got.Unset)
+	}
+	if got.Price.Prec() != 200 || got.Price.Cmp(v.Price) != 0 {
+		t.Errorf("got price %v with precision %d, want %v with 200", got.Price, got.Price.Prec(), v.Price)
+	}
+
+	// Formats get the text form.
+	b, err := gensenc.EncodeFormat(cbor.Format, v.Rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\x64-7/3" {
+		t.Errorf("encoded as %q, want the text -7/3", b)
+	}
+	var r big.Rat
+	if err := gensenc.DecodeFormat(cbor.Format, b, &r); err != nil || r.Cmp(v.Rate) != 0 {
+		t.Errorf("decoded %v, %v from the text form", &r, err)
+	}
+}
+
+func TestBigTypesErrors(t *testing.T) {
+	b, err := gensenc.Encode(big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first byte after the length is the version of the gob encoding.
+	b[8] = 0xfe
+	var i big.Int
+	err = gensenc.Decode(b, &i)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("decoding an unknown gob version gave %v, want a *DecodeError", err)
+	}
+	b, err = gensenc.Encode(ledger{Rate: big.NewRat(1, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l ledger
+	err = gensenc.Decode(b[:len(b)-3], &l)
+	if !errors.Is(err, gensenc.ErrInvalidLength) && !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated input gave %v", err)
+	}
+}
+
+type flow struct {
+	Src    netip.AddrPort
+	Dst    netip.Addr
+	Net    netip.Prefix
+	Router net.IP
+	Mask   net.IPMask
+	Zero   netip.Addr
+}
+
+func TestNetTypes(t *testing.T) {
+	v := flow{
+		Src:    netip.MustParseAddrPort("[2001:db8::1%eth0]:443"),
+		Dst:    netip.MustParseAddr("10.0.0.1"),
+		Net:    netip.MustParsePrefix("192.168.0.0/16"),
+		Router: net.ParseIP("192.168.1.1"),
+		Mask:   net.CIDRMask(24, 32),
+	}
+	// net.ParseIP returns IPv4 addresses in their 16-byte form, which is
+	// written as 4 bytes.
+	want := v
+	want.Router = v.Router.To4()
+	if got := roundTrip(t, gensenc.New(), v); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	b, err := gensenc.Encode(v.Router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{4, 0, 0, 0, 0, 0, 0, 0}, 192, 168, 1, 1); !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	b, err = gensenc.EncodeFormat(cbor.Format, v.Net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p netip.Prefix
+	if err := gensenc.DecodeFormat(cbor.Format, b, &p); err != nil || p != v.Net {
+		t.Errorf("decoded %v, %v from %q", p, err, b)
+	}
+}
+
+func TestNetTypesErrors(t *testing.T) {
+	// Five bytes are no address.
+	b := append([]byte{5, 0, 0, 0, 0, 0, 0, 0}, 1, 2, 3, 4, 5)
+	var a netip.Addr
+	err := gensenc.Decode(b, &a)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("decoding a 5-byte address gave %v, want a *DecodeError", err)
+	}
+	var f flow
+	b, _ = gensenc.Encode(flow{Dst: netip.MustParseAddr("::1")})
+	err = gensenc.Decode(b[:20], &f)
+	if !errors.Is(err, gensenc.ErrInvalidLength) && !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated input gave %v", err)
+	}
+}
+
+type row struct {
+	Active  sql.NullBool
+	Flag    sql.NullByte
+	Score   sql.NullFloat64
+	Small   sql.NullInt16
+	Medium  sql.NullInt32
+	Count   sql.NullInt64
+	Name    sql.NullString
+	Updated sql.NullTime
+}
+
+func TestSQLNull(t *testing.T) {
+	valid := row{
+		Active:  sql.NullBool{Bool: true, Valid: true},
+		Flag:    sql.NullByte{Byte: 7, Valid: true},
+		Score:   sql.NullFloat64{Float64: 0.5, Valid: true},
+		Small:   sql.NullInt16{Int16: -2, Valid: true},
+		Medium:  sql.NullInt32{Int32: 3, Valid: true},
+		Count:   sql.NullInt64{Int64: 1 << 40, Valid: true},
+		Name:    sql.NullString{String: "", Valid: true},
+		Updated: sql.NullTime{Time: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), Valid: true},
+	}
+	if got := roundTrip(t, gensenc.New(), valid); !reflect.DeepEqual(got, valid) {
+		t.Errorf("got %+v, want %+v", got, valid)
+	}
+	// NULL is a single zero byte per field, and decoding it clears what
+	// the field held.
+	b, err := gensenc.Encode(row{Name: sql.NullString{String: "ignored"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, make([]byte, 8)) {
+		t.Errorf("encoded NULLs as %x", b)
+	}
+	got := valid
+	if err := gensenc.Decode(b, &got); err != nil || got != (row{}) {
+		t.Errorf("decoding NULLs gave %+v, %v", got, err)
+	}
+}
+
+// TestSQLNullPointers checks that a sql.Null type and a pointer to its
+// value encode alike.
+func TestSQLNullPointers(t *testing.T) {
+	n := int64(-5)
+	for _, tt := range []struct {
+		null sql.NullInt64
+		ptr  *int64
+	}{
+		{sql.NullInt64{Int64: n, Valid: true}, &n},
+		{sql.NullInt64{}, nil},
+	} {
+		a, err := gensenc.Encode(struct{ V sql.NullInt64 }{tt.null})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := gensenc.Encode(struct{ V *int64 }{tt.ptr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a, b) {
+			t.Errorf("%+v encoded as %x, the pointer as %x", tt.null, a, b)
+		}
+		var back struct{ V sql.NullInt64 }
+		if err := gensenc.Decode(b, &back); err != nil || back.V != tt.null {
+			t.Errorf("decoding the pointer form gave %+v, %v, want %+v", back.V, err, tt.null)
+		}
+	}
+	b, err := gensenc.EncodeFormat(cbor.Format, row{Count: sql.NullInt64{Int64: 1, Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r row
+	if err := gensenc.DecodeFormat(cbor.Format, b, &r); err != nil || r != (row{Count: sql.NullInt64{Int64: 1, Valid: true}}) {
+		t.Errorf("decoding from CBOR gave %+v, %v", r, err)
+	}
+}
+
+func TestSQLNullErrors(t *testing.T) {
+	var r row
+	err := gensenc.Decode([]byte{0, 0, 2}, &r)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrMalformed) || de.Path != "Score" {
+		t.Errorf("decoding presence byte 2 gave %v, want ErrMalformed at Score", err)
+	}
+	err = gensenc.Decode([]byte{0, 0, 0, 1, 0}, &r)
+	if !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding a truncated value gave %v, want ErrTruncated", err)
+	}
+}
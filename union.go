@@ -0,0 +1,162 @@
+package gensenc
+
+import (
+	"errors"
+	"reflect"
+)
+
+var ErrOneOf error = errors.New("more than one oneof member set")
+
+// oneofCodec encodes struct fields tagged with oneof as a sum type. The
+// exported fields of the struct are its members, of which at most one may
+// be non-zero. Only a discriminator byte is written, zero if no member is
+// set and one plus the member's position otherwise, followed by the set
+// member. Pointer members can mark zero values as set.
+type oneofCodec struct {
+	t reflect.Type
+}
+
+func newOneofCodec(t reflect.Type) wireCodec {
+	if t.Kind() != reflect.Struct || len(infoOf(t).fields) > 255 {
+		return invalidTag{}
+	}
+	return oneofCodec{t}
+}
+
+// set returns the position of the member of v that is set, or -1 if none
+// is.
+func (c oneofCodec) set(v reflect.Value) (int, error) {
+	set := -1
+	for i, f := range infoOf(c.t).fields {
+		if v.Field(f.index).IsZero() {
+			continue
+		}
+		if set >= 0 {
+			return -1, ErrOneOf
+		}
+		set = i
+	}
+	return set, nil
+}
+
+func (c oneofCodec) encode(e *encodeState, v reflect.Value) error {
+	fields := infoOf(c.t).fields
+	set, err := c.set(v)
+	if err != nil {
+		return err
+	}
+	e.buf.WriteByte(byte(set + 1))
+	if set < 0 {
+		return nil
+	}
+	f := &fields[set]
+	return e.encodeField(f, v.Field(f.index))
+}
+
+// member reads the discriminator and returns the member it selects, or nil
+// if none is set.
+func (c oneofCodec) member(d *decodeState) (*fieldInfo, error) {
+	b, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	fields := infoOf(c.t).fields
+	n := int(b[0])
+	if n > len(fields) {
+		return nil, ErrMalformed
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return &fields[n-1], nil
+}
+
+func (c oneofCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	f, err := c.member(d)
+	if err != nil {
+		return err
+	}
+	v.SetZero()
+	if f == nil {
+		return nil
+	}
+	err = d.decodeField(f, v.Field(f.index))
+	if err != nil {
+		return d.at(err, "."+f.name)
+	}
+	return nil
+}
+
+func (c oneofCodec) skip(d *decodeState) error {
+	f, err := c.member(d)
+	if err != nil || f == nil {
+		return err
+	}
+	err = d.skipField(f)
+	if err != nil {
+		return d.at(err, "."+f.name)
+	}
+	return nil
+}
+
+func (oneofCodec) wireSize(map[reflect.Type]bool) int {
+	return -1
+}
+
+// writeFormat writes a map holding the member that is set, if any, under
+// its name.
+func (c oneofCodec) writeFormat(e *encodeState, w FormatWriter, v reflect.Value) error {
+	set, err := c.set(v)
+	if err != nil {
+		return err
+	}
+	if set < 0 {
+		return w.WriteMapHeader(0)
+	}
+	f := &infoOf(c.t).fields[set]
+	err = w.WriteMapHeader(1)
+	if err == nil {
+		err = w.WriteString(f.name)
+	}
+	if err == nil {
+		err = e.encodeFormatField(w, f, v.Field(f.index))
+	}
+	if err != nil {
+		return e.at(err, "."+f.name)
+	}
+	return nil
+}
+
+func (c oneofCodec) readFormat(d *decodeState, r FormatReader, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	n, err := r.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return ErrOneOf
+	}
+	v.SetZero()
+	if n == 0 {
+		return nil
+	}
+	name, err := r.ReadString()
+	if err != nil {
+		return err
+	}
+	for _, f := range infoOf(c.t).fields {
+		if f.name == name {
+			err = d.decodeFormatField(r, &f, v.Field(f.index))
+			if err != nil {
+				return d.at(err, "."+f.name)
+			}
+			return nil
+		}
+	}
+	return r.Skip()
+}
@@ -0,0 +1,95 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/msgpack"
+)
+
+type event struct {
+	Body struct {
+		Click  *point
+		Key    string
+		Scroll int
+	} `gensenc:"oneof"`
+	Seq uint8
+}
+
+func TestOneof(t *testing.T) {
+	var e event
+	e.Body.Key = "k"
+	e.Seq = 9
+	b, err := gensenc.Encode(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The discriminator is one plus the member's position.
+	want := append([]byte{2, 1, 0, 0, 0, 0, 0, 0, 0}, 'k', 9, 0, 0, 0, 0, 0, 0, 0)
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded as %x, want %x", b, want)
+	}
+	if got := roundTrip(t, gensenc.New(), e); !reflect.DeepEqual(got, e) {
+		t.Errorf("got %+v, want %+v", got, e)
+	}
+	// Pointer members mark zero values as set.
+	var click event
+	click.Body.Click = &point{}
+	if got := roundTrip(t, gensenc.New(), click); got.Body.Click == nil || *got.Body.Click != (point{}) {
+		t.Errorf("got %+v, want a zero click", got.Body)
+	}
+	// Decoding replaces the member that was set.
+	var empty event
+	b, err = gensenc.Encode(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0 {
+		t.Errorf("no member set encoded as %x", b)
+	}
+	got := e
+	if err := gensenc.Decode(b, &got); err != nil || !reflect.DeepEqual(got, empty) {
+		t.Errorf("decoding no member over one gave %+v, %v", got, err)
+	}
+
+	b, err = gensenc.EncodeFormat(msgpack.Format, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back event
+	if err := gensenc.DecodeFormat(msgpack.Format, b, &back); err != nil || !reflect.DeepEqual(back, e) {
+		t.Errorf("decoding from MessagePack gave %+v, %v", back, err)
+	}
+}
+
+func TestOneofErrors(t *testing.T) {
+	var e event
+	e.Body.Key, e.Body.Scroll = "k", 1
+	_, err := gensenc.Encode(e)
+	var ee *gensenc.EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, gensenc.ErrOneOf) || ee.Path != "Body" {
+		t.Errorf("encoding two members gave %v, want ErrOneOf at Body", err)
+	}
+	if _, err := gensenc.EncodeFormat(msgpack.Format, e); !errors.Is(err, gensenc.ErrOneOf) {
+		t.Errorf("encoding two members to MessagePack gave %v, want ErrOneOf", err)
+	}
+	var got event
+	err = gensenc.Decode([]byte{4, 0}, &got)
+	if !errors.Is(err, gensenc.ErrMalformed) {
+		t.Errorf("decoding discriminator 4 of 3 members gave %v, want ErrMalformed", err)
+	}
+	err = gensenc.Decode([]byte{1, 1, 5}, &got)
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTruncated) || de.Path != "Body.Click.X" {
+		t.Errorf("decoding a truncated member gave %v, want ErrTruncated at Body.Click.X", err)
+	}
+	_, err = gensenc.Encode(struct {
+		N int `gensenc:"oneof"`
+	}{})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("tagging an int gave %v, want ErrInvalidTag", err)
+	}
+}
@@ -0,0 +1,36 @@
+package gensenc
+
+// An UnknownPolicy says what DecodeTagged and ReadTagged do with values
+// tagged with a type name that isn't registered.
+type UnknownPolicy int
+
+const (
+	// FailUnknown fails with ErrUnknownType. ReadTagged skips the value
+	// first, so that reading can go on with the next one.
+	FailUnknown UnknownPolicy = iota
+	// SkipUnknown skips the value. DecodeTagged leaves the variable
+	// unchanged and ReadTagged returns the next value instead.
+	SkipUnknown
+	// CaptureUnknown returns the value as an Unknown, which DecodeTagged
+	// stores in variables of an interface type or of type Unknown.
+	CaptureUnknown
+)
+
+// WithUnknownTypes sets the UnknownPolicy of a Codec, which is FailUnknown
+// by default. Interface values within a value have no length to skip
+// them by, so their type must always be registered.
+func WithUnknownTypes(p UnknownPolicy) Option {
+	return func(o *options) { o.unknown = p }
+}
+
+// An Unknown holds a tagged value of a type that isn't registered, as
+// captured by CaptureUnknown, so that services can forward messages they
+// don't understand. EncodeTagged and WriteTagged write it back as it was
+// read.
+type Unknown struct {
+	// Name is the name the type of the value is registered under by its
+	// writer.
+	Name string
+	// Data is the encoding of the value.
+	Data Raw
+}
@@ -0,0 +1,87 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestUnknownPolicies(t *testing.T) {
+	foreign := taggedAs("ext.Thing", []byte{1, 2, 3})
+	known, err := gensenc.EncodeTagged(ping{7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := append(bytes.Clone(foreign), known...)
+	read := func(c *gensenc.Codec) []any {
+		t.Helper()
+		var got []any
+		r := bytes.NewReader(stream)
+		for {
+			v, err := c.ReadTagged(r)
+			if err == io.EOF {
+				return got
+			}
+			if err != nil {
+				got = append(got, err)
+				continue
+			}
+			got = append(got, v)
+		}
+	}
+
+	fail := gensenc.New()
+	if got := read(fail); len(got) != 2 || !errors.Is(got[0].(error), gensenc.ErrUnknownType) || got[1] != (ping{7}) {
+		t.Errorf("failing read %v", got)
+	}
+	var v any = ping{1}
+	if err := fail.DecodeTagged(foreign, &v); !errors.Is(err, gensenc.ErrUnknownType) {
+		t.Errorf("failing decode gave %v, want ErrUnknownType", err)
+	}
+
+	skip := gensenc.New(gensenc.WithUnknownTypes(gensenc.SkipUnknown))
+	if got := read(skip); !reflect.DeepEqual(got, []any{ping{7}}) {
+		t.Errorf("skipping read %v", got)
+	}
+	if err := skip.DecodeTagged(foreign, &v); err != nil || v != (ping{1}) {
+		t.Errorf("skipping decode gave %v, %v, want the variable unchanged", v, err)
+	}
+
+	capture := gensenc.New(gensenc.WithUnknownTypes(gensenc.CaptureUnknown))
+	got := read(capture)
+	want := []any{gensenc.Unknown{Name: "ext.Thing", Data: gensenc.Raw{1, 2, 3}}, ping{7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("capturing read %v, want %v", got, want)
+	}
+	// Captured values are forwarded as they were read.
+	back, err := capture.EncodeTagged(got[0])
+	if err != nil || !bytes.Equal(back, foreign) {
+		t.Errorf("forwarded as %x, %v, want %x", back, err, foreign)
+	}
+	var u gensenc.Unknown
+	if err := capture.DecodeTagged(foreign, &u); err != nil || !reflect.DeepEqual(u, want[0]) {
+		t.Errorf("capturing decode gave %v, %v", u, err)
+	}
+	var p ping
+	if err := capture.DecodeTagged(foreign, &p); !errors.Is(err, gensenc.ErrNotAssignable) {
+		t.Errorf("capturing into a ping gave %v, want ErrNotAssignable", err)
+	}
+}
+
+func TestUnknownTruncated(t *testing.T) {
+	foreign := taggedAs("ext.Thing", []byte{1, 2, 3})
+	for _, p := range []gensenc.UnknownPolicy{gensenc.FailUnknown, gensenc.SkipUnknown, gensenc.CaptureUnknown} {
+		c := gensenc.New(gensenc.WithUnknownTypes(p))
+		if _, err := c.ReadTagged(bytes.NewReader(foreign[:len(foreign)-1])); !errors.Is(err, gensenc.ErrTruncated) {
+			t.Errorf("policy %d: reading a truncated value gave %v, want ErrTruncated", p, err)
+		}
+		var v any
+		if err := c.DecodeTagged(foreign[:len(foreign)-1], &v); !errors.Is(err, gensenc.ErrInvalidLength) {
+			t.Errorf("policy %d: decoding a truncated value gave %v, want ErrInvalidLength", p, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !tinygo && !purego
+
+package gensenc
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// rawMemory reports whether values may be copied to and from the wire as
+// raw memory. Builds for TinyGo or with the purego tag do without unsafe
+// memory access.
+const rawMemory = true
+
+// rawBytes returns the memory of the addressable value v.
+func rawBytes(v reflect.Value) []byte {
+	if v.Type().Size() == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(v.Addr().UnsafePointer()), v.Type().Size())
+}
+
+// rawSliceBytes returns the memory backing the elements of the slice v.
+func rawSliceBytes(v reflect.Value) []byte {
+	n := v.Len() * int(v.Type().Elem().Size())
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(v.UnsafePointer()), n)
+}
+
+// unsafeString returns a string sharing b's memory; b must not be
+// modified afterwards.
+func unsafeString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
+// alignPad returns the number of bytes to skip at the start of the
+// non-empty b to reach an address aligned to align.
+func alignPad(b []byte, align int) int {
+	return int(-uintptr(unsafe.Pointer(&b[0])) & uintptr(align-1))
+}
+
+// sliceAt returns a slice of type t and length n backed by b.
+func sliceAt(t reflect.Type, b []byte, n int) reflect.Value {
+	return reflect.SliceAt(t.Elem(), unsafe.Pointer(&b[0]), n).Convert(t)
+}
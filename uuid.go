@@ -0,0 +1,179 @@
+package gensenc
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+)
+
+var ErrInvalidUUID error = errors.New("invalid UUID")
+
+// A UUID is written as its 16 bytes, like fields tagged uuid, and formats
+// as its canonical string, also in JSON through ToJSON and FromJSON.
+type UUID [16]byte
+
+// ParseUUID parses the canonical form of a UUID, as in
+// "6ba7b810-9dad-11d1-80b4-00c04fd430c8", in either case.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+	b := make([]byte, 0, 32)
+	b = append(b, s[:8]...)
+	b = append(b, s[9:13]...)
+	b = append(b, s[14:18]...)
+	b = append(b, s[19:23]...)
+	b = append(b, s[24:]...)
+	_, err := hex.Decode(u[:], b)
+	if err != nil {
+		return u, ErrInvalidUUID
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:8], u[:4])
+	hex.Encode(b[9:13], u[4:6])
+	hex.Encode(b[14:18], u[6:8])
+	hex.Encode(b[19:23], u[8:10])
+	hex.Encode(b[24:], u[10:])
+	b[8], b[13], b[18], b[23] = '-', '-', '-', '-'
+	return string(b[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) error {
+	var err error
+	*u, err = ParseUUID(string(b))
+	return err
+}
+
+// isUUID reports whether t is a [16]byte array type, such as UUID or
+// uuid.UUID.
+func isUUID(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// uuidCodec writes UUIDs as their 16 bytes instead of 16 integers. Fields
+// tagged uuid hold them in [16]byte arrays or, in their canonical form, in
+// strings, of which the empty string stands for the zero UUID. The
+// self-describing modes, EncodeFormat, DecodeFormat and DecodeDynamic,
+// write and read such fields as strings in canonical form.
+type uuidCodec struct {
+	// str is set for string fields.
+	str bool
+}
+
+func newUUIDCodec(t reflect.Type) wireCodec {
+	switch {
+	case isUUID(t):
+		return uuidCodec{}
+	case t.Kind() == reflect.String:
+		return uuidCodec{str: true}
+	}
+	return invalidTag{}
+}
+
+// uuidOf returns the UUID the array v holds.
+func uuidOf(v reflect.Value) UUID {
+	var u UUID
+	for i := range u {
+		u[i] = byte(v.Index(i).Uint())
+	}
+	return u
+}
+
+// setUUID stores u in the array v.
+func setUUID(v reflect.Value, u UUID) {
+	for i := range u {
+		v.Index(i).SetUint(uint64(u[i]))
+	}
+}
+
+func (c uuidCodec) encode(e *encodeState, v reflect.Value) error {
+	var u UUID
+	if !c.str {
+		u = uuidOf(v)
+	} else if s := v.String(); s != "" {
+		var err error
+		u, err = ParseUUID(s)
+		if err != nil {
+			return err
+		}
+	}
+	e.buf.Write(u[:])
+	return nil
+}
+
+func (c uuidCodec) decode(d *decodeState, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	b, err := d.take(16)
+	if err != nil {
+		return err
+	}
+	u := UUID(b)
+	switch {
+	case !c.str:
+		setUUID(v, u)
+	case u == UUID{}:
+		v.SetString("")
+	default:
+		v.SetString(u.String())
+	}
+	return nil
+}
+
+func (uuidCodec) skip(d *decodeState) error {
+	return d.discard(16)
+}
+
+func (uuidCodec) wireSize(map[reflect.Type]bool) int {
+	return 16
+}
+
+// writeFormat writes the UUID as a string in canonical form.
+func (c uuidCodec) writeFormat(_ *encodeState, w FormatWriter, v reflect.Value) error {
+	if !c.str {
+		return w.WriteString(uuidOf(v).String())
+	}
+	s := v.String()
+	if s == "" {
+		return w.WriteString(s)
+	}
+	u, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	return w.WriteString(u.String())
+}
+
+func (c uuidCodec) readFormat(_ *decodeState, r FormatReader, v reflect.Value) error {
+	if !v.CanSet() {
+		return ErrCantSet
+	}
+	s, err := r.ReadString()
+	if err != nil {
+		return err
+	}
+	if c.str && s == "" {
+		v.SetString("")
+		return nil
+	}
+	u, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	if c.str {
+		v.SetString(u.String())
+	} else {
+		setUUID(v, u)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+// foreignUUID stands for UUID types of other packages, such as uuid.UUID.
+type foreignUUID [16]byte
+
+type identified struct {
+	ID      gensenc.UUID
+	Foreign foreignUUID `gensenc:"uuid"`
+	Text    string      `gensenc:"uuid"`
+	Empty   string      `gensenc:"uuid"`
+	N       int
+}
+
+const (
+	uuidText = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	uuidNext = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+)
+
+func TestUUID(t *testing.T) {
+	id, err := gensenc.ParseUUID("6BA7B810-9dad-11d1-80b4-00C04FD430C8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != uuidText {
+		t.Errorf("formatted as %s, want %s", id, uuidText)
+	}
+	v := identified{ID: id, Foreign: foreignUUID(id), Text: uuidNext, N: 3}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each UUID takes its 16 bytes, and the empty string the zero UUID.
+	if len(b) != 4*16+8 {
+		t.Errorf("encoded in %d bytes, want %d", len(b), 4*16+8)
+	}
+	if !bytes.Equal(b[:16], id[:]) {
+		t.Errorf("encoded ID as %x, want %x", b[:16], id[:])
+	}
+	if got := roundTrip(t, gensenc.New(), v); got != v {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	// UUIDs take their canonical form in JSON, as UUID is a TextMarshaler.
+	typ := reflect.TypeFor[[]gensenc.UUID]()
+	b, err = gensenc.FromJSON([]byte(`["`+uuidText+`"]`), typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := gensenc.ToJSON(b, typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `["` + uuidText + `"]`; string(j) != want {
+		t.Errorf("got %s, want %s", j, want)
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			if got := roundTripFormat(t, f.format, v); got != v {
+				t.Errorf("got %+v, want %+v", got, v)
+			}
+		})
+	}
+}
+
+func TestUUIDErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"6ba7b8109dad11d180b400c04fd430c8",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cx",
+		"6ba7b810-9dad-11d1-80b4_00c04fd430c8",
+	} {
+		if _, err := gensenc.ParseUUID(s); !errors.Is(err, gensenc.ErrInvalidUUID) {
+			t.Errorf("parsing %q gave %v, want ErrInvalidUUID", s, err)
+		}
+	}
+	_, err := gensenc.Encode(identified{Text: "nope"})
+	if !errors.Is(err, gensenc.ErrInvalidUUID) {
+		t.Errorf("encoding a string that isn't a UUID gave %v, want ErrInvalidUUID", err)
+	}
+	_, err = gensenc.Encode(struct {
+		N int `gensenc:"uuid"`
+	}{1})
+	if !errors.Is(err, gensenc.ErrInvalidTag) {
+		t.Errorf("encoding an int tagged uuid gave %v, want ErrInvalidTag", err)
+	}
+	b, err := gensenc.Encode(gensenc.UUID{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got identified
+	if err := gensenc.Decode(b[:15], &got); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("decoding truncated input gave %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,69 @@
+package gensenc
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"reflect"
+)
+
+// A ValueWriter is an io.WriterTo writing the encoding of V, for APIs that
+// take one.
+type ValueWriter struct {
+	V any
+}
+
+func (vw ValueWriter) WriteTo(w io.Writer) (int64, error) {
+	n, err := EncodeValueTo(w, addressable(reflect.ValueOf(vw.V)))
+	return int64(n), err
+}
+
+// A ValuePlaceholder is an io.ReaderFrom decoding a value into what V
+// points to. As io.ReaderFrom requires, it reads until EOF, failing with
+// ErrTrailingBytes if anything follows the value.
+type ValuePlaceholder struct {
+	V any
+}
+
+func (vp ValuePlaceholder) ReadFrom(r io.Reader) (int64, error) {
+	n, err := DecodeValueN(r, reflect.ValueOf(vp.V))
+	if err != nil {
+		return n, err
+	}
+	var b [1]byte
+	m, err := io.ReadFull(r, b[:])
+	n += int64(m)
+	switch err {
+	case io.EOF:
+		return n, nil
+	case nil:
+		return n, &DecodeError{Err: ErrTrailingBytes, Offset: n - 1}
+	}
+	return n, err
+}
+
+// EncodeToWriterAt writes the encoding of a to w at offset off with a single
+// WriteAt and returns the number of bytes written, for files of records at
+// fixed offsets. Nothing is written if encoding fails.
+func EncodeToWriterAt(w io.WriterAt, off int64, a any) (int64, error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	b, err := Encode(a)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.WriteAt(b, off)
+	return int64(n), err
+}
+
+// DecodeFromReaderAt decodes the value at offset off of r into the value a
+// points to and returns its size, so that the record following it starts at
+// off plus that size. r may be read past the end of the value.
+func DecodeFromReaderAt(r io.ReaderAt, off int64, a any) (int64, error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, off, math.MaxInt64-off))
+	return DecodeValueN(br, reflect.ValueOf(a))
+}
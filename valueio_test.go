@@ -0,0 +1,122 @@
+package gensenc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+)
+
+func TestValueWriterPlaceholder(t *testing.T) {
+	v := order{ID: 7, Items: []string{"a"}, Tags: map[string]int{"x": 1}}
+	var buf bytes.Buffer
+	var wt io.WriterTo = gensenc.ValueWriter{V: v}
+	n, err := wt.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := gensenc.Encode(v)
+	if n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("wrote %d bytes %x, want %x", n, buf.Bytes(), want)
+	}
+
+	var got order
+	var rf io.ReaderFrom = gensenc.ValuePlaceholder{V: &got}
+	n, err = rf.ReadFrom(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(want)) || !reflect.DeepEqual(got, v) {
+		t.Errorf("read %d bytes into %+v, want %d into %+v", n, got, len(want), v)
+	}
+}
+
+func TestValueWriterPlaceholderErrors(t *testing.T) {
+	_, err := gensenc.ValueWriter{V: make(chan int)}.WriteTo(io.Discard)
+	if !errors.Is(err, gensenc.ErrUnsupportedKind) {
+		t.Errorf("writing a channel gave %v, want ErrUnsupportedKind", err)
+	}
+
+	b, _ := gensenc.Encode(uint64(1))
+	var got uint64
+	n, err := gensenc.ValuePlaceholder{V: &got}.ReadFrom(bytes.NewReader(append(b, 0)))
+	var de *gensenc.DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, gensenc.ErrTrailingBytes) || de.Offset != 8 || n != 9 {
+		t.Errorf("reading a trailing byte gave %d bytes and %v, want 9 and ErrTrailingBytes at 8", n, err)
+	}
+	_, err = gensenc.ValuePlaceholder{V: &got}.ReadFrom(bytes.NewReader(b[:5]))
+	if !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("reading a truncated value gave %v, want ErrTruncated", err)
+	}
+	if _, err = (gensenc.ValuePlaceholder{V: got}).ReadFrom(bytes.NewReader(b)); err == nil {
+		t.Error("reading into a non-pointer succeeded")
+	}
+}
+
+func TestWriterAtReaderAt(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "records"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records := []order{{ID: 1, Items: []string{"one"}}, {ID: 2, Tags: map[string]int{"x": 2}}}
+	off := int64(100)
+	for _, o := range records {
+		n, err := gensenc.EncodeToWriterAt(f, off, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		off += n
+	}
+	off = 100
+	for i, want := range records {
+		var o order
+		n, err := gensenc.DecodeFromReaderAt(f, off, &o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want.Tags == nil {
+			want.Tags = map[string]int{}
+		}
+		if !reflect.DeepEqual(o, want) {
+			t.Errorf("record %d: got %+v, want %+v", i, o, want)
+		}
+		off += n
+	}
+	var o order
+	if _, err := gensenc.DecodeFromReaderAt(f, off, &o); err != io.EOF {
+		t.Errorf("decoding at the end gave %v, want io.EOF", err)
+	}
+}
+
+func TestWriterAtReaderAtErrors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "records"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := gensenc.EncodeToWriterAt(f, -1, 1); !errors.Is(err, gensenc.ErrInvalidOffset) {
+		t.Errorf("writing at -1 gave %v, want ErrInvalidOffset", err)
+	}
+	if _, err := gensenc.DecodeFromReaderAt(f, -1, new(int)); !errors.Is(err, gensenc.ErrInvalidOffset) {
+		t.Errorf("reading at -1 gave %v, want ErrInvalidOffset", err)
+	}
+	n, err := gensenc.EncodeToWriterAt(f, 0, make(chan int))
+	if !errors.Is(err, gensenc.ErrUnsupportedKind) || n != 0 {
+		t.Errorf("writing a channel gave %d bytes and %v, want none and ErrUnsupportedKind", n, err)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 0 {
+		t.Errorf("wrote %d bytes for a failing value", fi.Size())
+	}
+	if _, err := gensenc.EncodeToWriterAt(f, 0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gensenc.DecodeFromReaderAt(io.NewSectionReader(f, 0, 10), 0, new(string)); !errors.Is(err, gensenc.ErrTruncated) {
+		t.Errorf("reading a truncated record gave %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,203 @@
+// Package wire reads and writes the primitives of the gensenc wire format in
+// its default configuration, so that generated code and hand-written codecs
+// produce and consume exactly what the reflective encoder does:
+//
+//   - integers of any size are 8 bytes little-endian, floats and complex
+//     numbers their IEEE 754 bits in as many bytes as in memory, booleans
+//     one byte
+//   - strings are a length followed by their bytes
+//   - slices and maps are a length followed by their elements, or their
+//     keys and values in turn
+//   - structs are their exported fields in order
+//   - pointers within a value are a presence byte followed by the value
+//     if it is 1
+//
+// Byte strings, as gensenc.Raw and net.IP are encoded, are written like
+// strings, while a plain []byte is a slice of 8-byte integers like any
+// other.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	ErrInvalidLength error = errors.New("wire: invalid length")
+	ErrMalformed     error = errors.New("wire: malformed input")
+)
+
+// A Writer appends primitives to a byte slice.
+type Writer struct {
+	buf []byte
+}
+
+// NewWriter returns a Writer appending to buf.
+func NewWriter(buf []byte) *Writer {
+	return &Writer{buf: buf}
+}
+
+// Bytes returns what has been written.
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+func (w *Writer) Reset() {
+	w.buf = w.buf[:0]
+}
+
+func (w *Writer) PutUint64(x uint64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, x)
+}
+
+func (w *Writer) PutInt64(x int64) {
+	w.PutUint64(uint64(x))
+}
+
+func (w *Writer) PutBool(b bool) {
+	if b {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *Writer) PutFloat32(x float32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, math.Float32bits(x))
+}
+
+func (w *Writer) PutFloat64(x float64) {
+	w.PutUint64(math.Float64bits(x))
+}
+
+// PutLen writes the length preceding the elements of a slice or map.
+func (w *Writer) PutLen(n int) {
+	w.PutUint64(uint64(n))
+}
+
+func (w *Writer) PutString(s string) {
+	w.PutLen(len(s))
+	w.buf = append(w.buf, s...)
+}
+
+// PutBytes writes b as a byte string.
+func (w *Writer) PutBytes(b []byte) {
+	w.PutLen(len(b))
+	w.buf = append(w.buf, b...)
+}
+
+// PutPresence writes the presence byte of a pointer, to be followed by the
+// value it points to if present.
+func (w *Writer) PutPresence(present bool) {
+	w.PutBool(present)
+}
+
+// A Reader reads primitives from a byte slice. Reading past its end fails
+// with io.ErrUnexpectedEOF.
+type Reader struct {
+	b   []byte
+	off int
+}
+
+func NewReader(b []byte) *Reader {
+	return &Reader{b: b}
+}
+
+// Offset returns the number of bytes read.
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Remaining returns the number of bytes left to read.
+func (r *Reader) Remaining() int {
+	return len(r.b) - r.off
+}
+
+func (r *Reader) take(n int) ([]byte, error) {
+	if n > len(r.b)-r.off {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *Reader) ReadUint64() (uint64, error) {
+	b, err := r.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *Reader) ReadInt64() (int64, error) {
+	x, err := r.ReadUint64()
+	return int64(x), err
+}
+
+// ReadBool reads a boolean, taking any non-zero byte as true like Decode
+// does without gensenc.WithStrict.
+func (r *Reader) ReadBool() (bool, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return false, err
+	}
+	return b[0] != 0, nil
+}
+
+func (r *Reader) ReadFloat32() (float32, error) {
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (r *Reader) ReadFloat64() (float64, error) {
+	x, err := r.ReadUint64()
+	return math.Float64frombits(x), err
+}
+
+// ReadLen reads the length preceding the elements of a slice or map, each
+// of which takes at least minSize bytes. It fails with ErrInvalidLength if
+// the remaining input can't hold that many, so that corrupt lengths are
+// caught before allocating for them.
+func (r *Reader) ReadLen(minSize int) (int, error) {
+	n, err := r.ReadUint64()
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt || minSize > 0 && n > uint64(r.Remaining()/minSize) {
+		return 0, ErrInvalidLength
+	}
+	return int(n), nil
+}
+
+func (r *Reader) ReadString() (string, error) {
+	b, err := r.ReadBytes()
+	return string(b), err
+}
+
+// ReadBytes reads a byte string. The result aliases the Reader's input.
+func (r *Reader) ReadBytes() ([]byte, error) {
+	n, err := r.ReadLen(1)
+	if err != nil {
+		return nil, err
+	}
+	return r.take(n)
+}
+
+// ReadPresence reads the presence byte of a pointer, failing with
+// ErrMalformed for bytes other than 0 and 1.
+func (r *Reader) ReadPresence() (bool, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return false, err
+	}
+	if b[0] > 1 {
+		return false, ErrMalformed
+	}
+	return b[0] == 1, nil
+}
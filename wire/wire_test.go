@@ -0,0 +1,128 @@
+package wire_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	gensenc "github.com/CodeSpoof/gogenericencoder"
+	"github.com/CodeSpoof/gogenericencoder/wire"
+)
+
+type record struct {
+	A int32
+	B bool
+	C float32
+	D string
+	E []uint16
+	P *float64
+	N *float64
+	R gensenc.Raw
+}
+
+func TestWriter(t *testing.T) {
+	f := 2.5
+	v := record{-3, true, 1.5, "hi", []uint16{1, 2}, &f, nil, gensenc.Raw("xy")}
+	want, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := wire.NewWriter(nil)
+	w.PutInt64(-3)
+	w.PutBool(true)
+	w.PutFloat32(1.5)
+	w.PutString("hi")
+	w.PutLen(2)
+	w.PutUint64(1)
+	w.PutUint64(2)
+	w.PutPresence(true)
+	w.PutFloat64(2.5)
+	w.PutPresence(false)
+	w.PutBytes([]byte("xy"))
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Errorf("wrote %x, want %x", w.Bytes(), want)
+	}
+	w.Reset()
+	if len(w.Bytes()) != 0 {
+		t.Errorf("%d bytes left after Reset", len(w.Bytes()))
+	}
+}
+
+func TestReader(t *testing.T) {
+	f := 2.5
+	v := record{-3, true, 1.5, "hi", []uint16{1, 2}, &f, nil, gensenc.Raw("xy")}
+	b, err := gensenc.Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := wire.NewReader(b)
+	var got record
+	var errs []error
+	read := func(err error) { errs = append(errs, err) }
+	var a int64
+	a, err = r.ReadInt64()
+	read(err)
+	got.A = int32(a)
+	got.B, err = r.ReadBool()
+	read(err)
+	got.C, err = r.ReadFloat32()
+	read(err)
+	got.D, err = r.ReadString()
+	read(err)
+	n, err := r.ReadLen(8)
+	read(err)
+	for range n {
+		x, err := r.ReadUint64()
+		read(err)
+		got.E = append(got.E, uint16(x))
+	}
+	for _, p := range []**float64{&got.P, &got.N} {
+		present, err := r.ReadPresence()
+		read(err)
+		if present {
+			x, err := r.ReadFloat64()
+			read(err)
+			*p = &x
+		}
+	}
+	got.R, err = r.ReadBytes()
+	read(err)
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+	if r.Offset() != len(b) || r.Remaining() != 0 {
+		t.Errorf("read %d bytes with %d left, want %d with none", r.Offset(), r.Remaining(), len(b))
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	if _, err := wire.NewReader(make([]byte, 7)).ReadUint64(); err != io.ErrUnexpectedEOF {
+		t.Errorf("reading 8 of 7 bytes gave %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := wire.NewReader([]byte{2}).ReadPresence(); !errors.Is(err, wire.ErrMalformed) {
+		t.Errorf("reading a presence byte of 2 gave %v, want ErrMalformed", err)
+	}
+	w := wire.NewWriter(nil)
+	w.PutLen(3)
+	w.PutUint64(1)
+	w.PutUint64(2)
+	r := wire.NewReader(w.Bytes())
+	if _, err := r.ReadLen(8); !errors.Is(err, wire.ErrInvalidLength) {
+		t.Errorf("reading 3 elements of 8 bytes from 16 gave %v, want ErrInvalidLength", err)
+	}
+	w.Reset()
+	w.PutUint64(1 << 63)
+	if _, err := wire.NewReader(w.Bytes()).ReadLen(0); !errors.Is(err, wire.ErrInvalidLength) {
+		t.Errorf("reading a length of 2^63 gave %v, want ErrInvalidLength", err)
+	}
+	w.Reset()
+	w.PutString("hello")
+	if _, err := wire.NewReader(w.Bytes()[:12]).ReadString(); !errors.Is(err, wire.ErrInvalidLength) {
+		t.Errorf("reading a truncated string gave %v, want ErrInvalidLength", err)
+	}
+}